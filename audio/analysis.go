@@ -0,0 +1,75 @@
+package audio
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// FrequencyData holds the output of bpm/frequency_analysis.py
+type FrequencyData struct {
+	FundamentalFreq  *float64
+	PeakFreq         *float64
+	SpectralCentroid *float64
+}
+
+// AnalyzeBPM runs bpm/beats_per_min.py on a wav file and returns BPM, key and the raw script output
+func AnalyzeBPM(inputPath string) (float64, string, []byte, error) {
+	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
+		return 0, "", nil, fmt.Errorf("input file %s does not exist", inputPath)
+	}
+
+	bpmCmd := exec.Command("python3", "bpm/beats_per_min.py", inputPath)
+	output, err := bpmCmd.CombinedOutput()
+	if err != nil {
+		return 0, "", output, fmt.Errorf("BPM analysis failed: %v", err)
+	}
+
+	var bpmData map[string]interface{}
+	if err := json.Unmarshal(output, &bpmData); err != nil {
+		return 0, "", output, fmt.Errorf("failed to parse BPM JSON: %v", err)
+	}
+
+	bpm, ok := bpmData["bpm"].(float64)
+	if !ok {
+		return 0, "", output, fmt.Errorf("BPM missing from analysis output")
+	}
+	key, ok := bpmData["key"].(string)
+	if !ok {
+		return 0, "", output, fmt.Errorf("key missing from analysis output")
+	}
+
+	return bpm, key, output, nil
+}
+
+// AnalyzeFrequency runs bpm/frequency_analysis.py on a wav file
+func AnalyzeFrequency(inputPath string) (*FrequencyData, []byte, error) {
+	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("input file %s does not exist", inputPath)
+	}
+
+	hzCmd := exec.Command("python3", "bpm/frequency_analysis.py", inputPath)
+	output, err := hzCmd.CombinedOutput()
+	if err != nil {
+		return nil, output, fmt.Errorf("frequency analysis failed: %v", err)
+	}
+
+	var freqData map[string]interface{}
+	if err := json.Unmarshal(output, &freqData); err != nil {
+		return nil, output, fmt.Errorf("failed to parse frequency JSON: %v", err)
+	}
+
+	data := &FrequencyData{}
+	if freq, ok := freqData["fundamental_frequency"].(float64); ok {
+		data.FundamentalFreq = &freq
+	}
+	if freq, ok := freqData["peak_frequency"].(float64); ok {
+		data.PeakFreq = &freq
+	}
+	if freq, ok := freqData["spectral_centroid"].(float64); ok {
+		data.SpectralCentroid = &freq
+	}
+
+	return data, output, nil
+}
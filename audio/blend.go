@@ -1,6 +1,8 @@
 package audio
 
-import ()
+import (
+	"starchive/util"
+)
 
 // Key and BPM calculation utilities
 var keyMap = map[string]int{
@@ -31,52 +33,56 @@ func CalculateKeyDifference(key1, key2 string) int {
 }
 
 
-// DetectTrackTypes determines optimal track types (vocal/instrumental) for blending
-func DetectTrackTypes(id1, id2 string) (string, string) {
-	id1HasVocal := HasVocalFile(id1)
-	id1HasInstrumental := HasInstrumentalFile(id1)
-	id2HasVocal := HasVocalFile(id2)
-	id2HasInstrumental := HasInstrumentalFile(id2)
+// DetectTrackTypes determines optimal track types for blending. Types are "V" for vocals,
+// "I" for instrumental, or a stem name (e.g. "drums") when a multi-stem separator left
+// no instrumental file.
+func DetectTrackTypes(db *util.Database, id1, id2 string) (string, string) {
+	id1HasVocal := HasVocalFile(db, id1)
+	id1Backing := backingType(db, id1)
+	id1HasBacking := id1Backing != ""
+	id2HasVocal := HasVocalFile(db, id2)
+	id2Backing := backingType(db, id2)
+	id2HasBacking := id2Backing != ""
 
 	var type1, type2 string
 
-	// If one track only has instrumental, make the other vocal (if possible)
-	if !id2HasVocal && id2HasInstrumental {
-		// Track 2 is instrumental-only, prefer vocal for track 1
+	// If one track only has backing stems, make the other vocal (if possible)
+	if !id2HasVocal && id2HasBacking {
+		// Track 2 is backing-only, prefer vocal for track 1
 		if id1HasVocal {
 			type1 = "V"
 		} else {
-			type1 = "I"
+			type1 = fallbackType(id1Backing)
 		}
-		type2 = "I"
-	} else if !id1HasVocal && id1HasInstrumental {
-		// Track 1 is instrumental-only, prefer vocal for track 2
-		type1 = "I"
+		type2 = id2Backing
+	} else if !id1HasVocal && id1HasBacking {
+		// Track 1 is backing-only, prefer vocal for track 2
+		type1 = id1Backing
 		if id2HasVocal {
 			type2 = "V"
 		} else {
-			type2 = "I"
+			type2 = fallbackType(id2Backing)
 		}
 	} else {
 		// Both tracks have options, choose complementary types
-		if id1HasVocal && !id1HasInstrumental {
+		if id1HasVocal && !id1HasBacking {
 			type1 = "V"
-		} else if !id1HasVocal && id1HasInstrumental {
-			type1 = "I"
-		} else if id1HasVocal && id1HasInstrumental {
+		} else if !id1HasVocal && id1HasBacking {
+			type1 = id1Backing
+		} else if id1HasVocal && id1HasBacking {
 			type1 = "V"  // Default to vocal for track 1
 		} else {
 			type1 = "I"  // Fallback
 		}
 
-		if id2HasVocal && !id2HasInstrumental {
+		if id2HasVocal && !id2HasBacking {
 			type2 = "V"
-		} else if !id2HasVocal && id2HasInstrumental {
-			type2 = "I"
-		} else if id2HasVocal && id2HasInstrumental {
+		} else if !id2HasVocal && id2HasBacking {
+			type2 = id2Backing
+		} else if id2HasVocal && id2HasBacking {
 			// Choose opposite of track 1
 			if type1 == "V" {
-				type2 = "I"
+				type2 = id2Backing
 			} else {
 				type2 = "V"
 			}
@@ -86,4 +92,26 @@ func DetectTrackTypes(id1, id2 string) (string, string) {
 	}
 
 	return type1, type2
+}
+
+// fallbackType returns the backing type if known, otherwise instrumental
+func fallbackType(backing string) string {
+	if backing != "" {
+		return backing
+	}
+	return "I"
+}
+
+// TrackTypeDescription returns a human readable name for a track type code or stem name
+func TrackTypeDescription(trackType string) string {
+	switch trackType {
+	case "V":
+		return "vocal"
+	case "I":
+		return "instrumental"
+	case "":
+		return "full mix"
+	default:
+		return NormalizeStemName(trackType)
+	}
 }
\ No newline at end of file
@@ -0,0 +1,285 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"starchive/util"
+)
+
+// PeaksCacheDir holds the peak files and spectrograms made for drawing waveforms
+const PeaksCacheDir = "./data/peaks"
+
+// PeakSampleRate is the rate audio is decoded at for peaks; samples per pixel count
+// samples at this rate
+const PeakSampleRate = 44100
+
+// PeakLevels are the resolutions stored in a peak file, in samples per pixel. Each is
+// a multiple of the one before, so coarser levels are built from the finest.
+var PeakLevels = []int{256, 1024, 4096, 16384}
+
+// PeakFile is the waveform of one audio file at several resolutions, in the spirit of
+// audiowaveform's JSON output with one data array per level
+type PeakFile struct {
+	Version    int         `json:"version"`
+	Source     string      `json:"source"` // File name of the audio the peaks were made from
+	Channels   int         `json:"channels"`
+	SampleRate int         `json:"sample_rate"`
+	Bits       int         `json:"bits"`
+	Samples    int64       `json:"samples"`
+	Duration   float64     `json:"duration"`
+	Levels     []PeakLevel `json:"levels"`
+}
+
+// PeakLevel is one resolution of a waveform: Length pairs of min, max sample values
+type PeakLevel struct {
+	SamplesPerPixel int     `json:"samples_per_pixel"`
+	Length          int     `json:"length"`
+	Data            []int16 `json:"data"`
+}
+
+// One generation per output file at a time; concurrent callers wait for it
+var peakLocks sync.Map
+
+// PeaksPath returns where the peak file of an audio file is cached
+func PeaksPath(audioPath string) string {
+	return filepath.Join(PeaksCacheDir, peakBase(audioPath)+".peaks.json")
+}
+
+// SpectrogramPath returns where the spectrogram of an audio file is cached
+func SpectrogramPath(audioPath string) string {
+	return filepath.Join(PeaksCacheDir, peakBase(audioPath)+".spectrogram.png")
+}
+
+func peakBase(audioPath string) string {
+	return strings.TrimSuffix(filepath.Base(audioPath), filepath.Ext(audioPath))
+}
+
+// PeakSources lists the audio files of an ID that get peaks: its WAV and every stem
+func PeakSources(db *util.Database, id string) []string {
+	var sources []string
+	if _, err := os.Stat(fmt.Sprintf("./data/%s.wav", id)); err == nil {
+		sources = append(sources, fmt.Sprintf("./data/%s.wav", id))
+	}
+	for _, stem := range RegisteredStems(db, id) {
+		sources = append(sources, stem.Path)
+	}
+	return sources
+}
+
+// cacheFresh reports whether a cached file exists and is not older than its source
+func cacheFresh(cachePath, sourcePath string) bool {
+	source, err := os.Stat(sourcePath)
+	if err != nil {
+		return false
+	}
+	cached, err := os.Stat(cachePath)
+	return err == nil && !cached.ModTime().Before(source.ModTime())
+}
+
+// EnsurePeaks returns the peak file of an audio file, generating it if it is missing
+// or older than the audio. force regenerates it regardless.
+func EnsurePeaks(audioPath string, force bool) (*PeakFile, error) {
+	outputPath := PeaksPath(audioPath)
+
+	lock, _ := peakLocks.LoadOrStore(outputPath, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if !force && cacheFresh(outputPath, audioPath) {
+		if data, err := os.ReadFile(outputPath); err == nil {
+			var peaks PeakFile
+			if json.Unmarshal(data, &peaks) == nil && len(peaks.Levels) > 0 {
+				return &peaks, nil
+			}
+		}
+	}
+
+	peaks, err := GeneratePeaks(audioPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(peaks)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(PeaksCacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	tmpPath := outputPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write peaks: %v", err)
+	}
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		return nil, fmt.Errorf("failed to store peaks: %v", err)
+	}
+
+	return peaks, nil
+}
+
+// GeneratePeaks decodes an audio file to mono with ffmpeg and computes min/max peaks
+// at every level in PeakLevels
+func GeneratePeaks(audioPath string) (*PeakFile, error) {
+	cmd := exec.Command("ffmpeg",
+		"-hide_banner",
+		"-loglevel", "error",
+		"-i", audioPath,
+		"-vn",
+		"-ac", "1",
+		"-ar", fmt.Sprintf("%d", PeakSampleRate),
+		"-f", "s16le",
+		"pipe:1")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create decoder pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %v", err)
+	}
+
+	// The finest level is computed while decoding, so the audio is never held in memory
+	finest := PeakLevel{SamplesPerPixel: PeakLevels[0]}
+	r := bufio.NewReaderSize(stdout, 64*1024)
+	buf := make([]byte, finest.SamplesPerPixel*2)
+	var samples int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n >= 2 {
+			low, high := int16(32767), int16(-32768)
+			for i := 0; i+1 < n; i += 2 {
+				sample := int16(uint16(buf[i]) | uint16(buf[i+1])<<8)
+				low, high = min(low, sample), max(high, sample)
+			}
+			finest.Data = append(finest.Data, low, high)
+			samples += int64(n / 2)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %v\n%s", err, stderr.String())
+	}
+	finest.Length = len(finest.Data) / 2
+
+	peaks := &PeakFile{
+		Version:    1,
+		Source:     filepath.Base(audioPath),
+		Channels:   1,
+		SampleRate: PeakSampleRate,
+		Bits:       16,
+		Samples:    samples,
+		Duration:   float64(samples) / PeakSampleRate,
+		Levels:     []PeakLevel{finest},
+	}
+	for _, samplesPerPixel := range PeakLevels[1:] {
+		peaks.Levels = append(peaks.Levels, downsamplePeaks(finest, samplesPerPixel))
+	}
+	return peaks, nil
+}
+
+// downsamplePeaks merges the pixels of a level into a coarser one
+func downsamplePeaks(level PeakLevel, samplesPerPixel int) PeakLevel {
+	factor := samplesPerPixel / level.SamplesPerPixel
+	coarse := PeakLevel{SamplesPerPixel: samplesPerPixel}
+	for start := 0; start < level.Length; start += factor {
+		end := min(start+factor, level.Length)
+		low, high := level.Data[2*start], level.Data[2*start+1]
+		for i := start + 1; i < end; i++ {
+			low, high = min(low, level.Data[2*i]), max(high, level.Data[2*i+1])
+		}
+		coarse.Data = append(coarse.Data, low, high)
+	}
+	coarse.Length = len(coarse.Data) / 2
+	return coarse
+}
+
+// Level returns the coarsest level with at least pixels points, or the finest level
+// when none has that many; pixels <= 0 selects the finest
+func (peaks *PeakFile) Level(pixels int) PeakLevel {
+	for i := len(peaks.Levels) - 1; i >= 0 && pixels > 0; i-- {
+		if peaks.Levels[i].Length >= pixels {
+			return peaks.Levels[i]
+		}
+	}
+	return peaks.Levels[0]
+}
+
+// EnsureSpectrogram returns the path of an audio file's spectrogram PNG, rendering it
+// with ffmpeg if it is missing or older than the audio. force renders it regardless.
+func EnsureSpectrogram(audioPath string, force bool) (string, error) {
+	outputPath := SpectrogramPath(audioPath)
+
+	lock, _ := peakLocks.LoadOrStore(outputPath, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if !force && cacheFresh(outputPath, audioPath) {
+		return outputPath, nil
+	}
+	if _, err := os.Stat(audioPath); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(PeaksCacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	tmpPath := outputPath + ".tmp"
+	cmd := exec.Command("ffmpeg",
+		"-y",
+		"-hide_banner",
+		"-loglevel", "error",
+		"-i", audioPath,
+		"-lavfi", "showspectrumpic=s=1024x256:legend=0:scale=log",
+		"-frames:v", "1",
+		"-f", "image2",
+		"-c:v", "png",
+		tmpPath)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("ffmpeg failed: %v\n%s", err, output)
+	}
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		return "", fmt.Errorf("failed to store spectrogram: %v", err)
+	}
+
+	return outputPath, nil
+}
+
+// sparkBlocks draw loudness in one terminal row, quietest first
+var sparkBlocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// Sparkline draws the waveform as width block characters, one per column, for
+// terminal output
+func (peaks *PeakFile) Sparkline(width int) string {
+	level := peaks.Level(width)
+	if width <= 0 || level.Length == 0 {
+		return ""
+	}
+
+	line := make([]rune, width)
+	for col := range line {
+		start := col * level.Length / width
+		end := max((col+1)*level.Length/width, start+1)
+		peak := 0
+		for i := start; i < end && i < level.Length; i++ {
+			peak = max(peak, -int(level.Data[2*i]), int(level.Data[2*i+1]))
+		}
+		index := peak * (len(sparkBlocks) - 1) / 32768
+		line[col] = sparkBlocks[min(index, len(sparkBlocks)-1)]
+	}
+	return string(line)
+}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+
+	"starchive/util"
 )
 
 // HandleSplitCommand splits audio files by silence detection
@@ -104,7 +106,13 @@ func HandleDemoCommand(args []string) {
 	id := demoArgs[0]
 	audioType := demoArgs[1]
 
-	inputPath := GetAudioFilename(id, audioType)
+	db, err := util.InitDatabase()
+	if err != nil {
+		fmt.Printf("Warning: stem registry not available, scanning ./data instead: %v\n", err)
+	} else {
+		defer db.Close()
+	}
+	inputPath := GetAudioFilename(db, id, audioType)
 
 	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
 		fmt.Printf("Error: Input file %s does not exist\n", inputPath)
@@ -141,10 +149,11 @@ func HandleDemoCommand(args []string) {
 // HandlePlayCommand plays audio files
 func HandlePlayCommand(args []string) {
 	if len(args) < 1 {
-		fmt.Println("Usage: starchive play <id> [I|V]")
+		fmt.Println("Usage: starchive play <id> [I|V|stem]")
 		fmt.Println("Example: starchive play NdYWuo9OFAw")
 		fmt.Println("         starchive play NdYWuo9OFAw I  (instrumental)")
 		fmt.Println("         starchive play NdYWuo9OFAw V  (vocals)")
+		fmt.Println("         starchive play NdYWuo9OFAw drums  (any separated stem)")
 		fmt.Println("Plays the wav file starting from the middle. Press any key to stop.")
 		os.Exit(1)
 	}
@@ -156,7 +165,13 @@ func HandlePlayCommand(args []string) {
 		audioType = args[1]
 	}
 	
-	inputPath := GetAudioFilename(id, audioType)
+	db, err := util.InitDatabase()
+	if err != nil {
+		fmt.Printf("Warning: stem registry not available, scanning ./data instead: %v\n", err)
+	} else {
+		defer db.Close()
+	}
+	inputPath := GetAudioFilename(db, id, audioType)
 
 	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
 		fmt.Printf("Error: Input file %s does not exist\n", inputPath)
@@ -172,11 +187,8 @@ func HandlePlayCommand(args []string) {
 	startPosition := duration / 2
 	
 	trackDesc := "main track"
-	switch audioType {
-	case "I", "instrumental", "instrumentals":
-		trackDesc = "instrumental track"
-	case "V", "vocal", "vocals":
-		trackDesc = "vocal track"
+	if audioType != "" {
+		trackDesc = NormalizeStemName(audioType) + " track"
 	}
 	
 	fmt.Printf("Playing %s (%s) from position %.1fs (middle of %.1fs total)\n", id, trackDesc, startPosition, duration)
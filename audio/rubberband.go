@@ -0,0 +1,88 @@
+package audio
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RubberbandCacheDir holds pre-rendered rubberband files keyed by their parameters
+const RubberbandCacheDir = "./data/rubberband"
+
+var (
+	rubberbandFilterOnce sync.Once
+	rubberbandFilter     bool
+)
+
+// HasRubberbandFilter reports whether the installed ffmpeg was built with the rubberband filter
+func HasRubberbandFilter() bool {
+	rubberbandFilterOnce.Do(func() {
+		output, err := exec.Command("ffmpeg", "-hide_banner", "-filters").Output()
+		if err != nil {
+			return
+		}
+		rubberbandFilter = strings.Contains(string(output), " rubberband ")
+	})
+	return rubberbandFilter
+}
+
+// RubberbandCachePath returns where the rendering of inputPath with the given
+// tempo multiplier and pitch shift is cached. The name carries a hash of the full
+// path, since segment files of different tracks share base names (part_001.wav),
+// and of the source's size, so a rewritten source gets a new entry.
+func RubberbandCachePath(inputPath string, tempo float64, semitones int) string {
+	key, err := filepath.Abs(inputPath)
+	if err != nil {
+		key = inputPath
+	}
+	if info, err := os.Stat(inputPath); err == nil {
+		key += fmt.Sprintf("\x00%d", info.Size())
+	}
+	sum := sha256.Sum256([]byte(key))
+	base := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	return filepath.Join(RubberbandCacheDir, fmt.Sprintf("%s_%x_t%.4f_p%+d.wav", base, sum[:6], tempo, semitones))
+}
+
+// RenderRubberband time-stretches and pitch-shifts inputPath with the rubberband CLI
+// (formants preserved) and returns the cached output path. A render older than its
+// source, as after a re-split or re-separation, is made again.
+func RenderRubberband(inputPath string, tempo float64, semitones int) (string, error) {
+	outputPath := RubberbandCachePath(inputPath, tempo, semitones)
+	if cacheFresh(outputPath, inputPath) {
+		return outputPath, nil
+	}
+	if _, err := os.Stat(inputPath); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(RubberbandCacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	// Render to a temp name so an interrupted run never leaves a partial cache entry
+	tmpPath := strings.TrimSuffix(outputPath, ".wav") + ".tmp.wav"
+	cmd := exec.Command("rubberband",
+		"--tempo", fmt.Sprintf("%.6f", tempo),
+		"--pitch", fmt.Sprintf("%d", semitones),
+		"--fine",
+		"--formant",
+		inputPath,
+		tmpPath)
+
+	fmt.Printf("Rendering %s with rubberband (tempo x%.3f, pitch %+d)...\n", filepath.Base(inputPath), tempo, semitones)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("rubberband failed: %v\n%s", err, output)
+	}
+
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		return "", fmt.Errorf("failed to store rubberband render: %v", err)
+	}
+
+	return outputPath, nil
+}
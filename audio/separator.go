@@ -0,0 +1,127 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Separator splits ./data/<id>.wav into stems
+type Separator interface {
+	// Name is the short name used on the command line, e.g. "uvr"
+	Name() string
+	// Stems lists the stems this separator produces
+	Stems() []string
+	// Separate runs the separation and returns the produced files keyed by stem name
+	Separate(id string) (map[string]string, error)
+}
+
+// audioSeparator runs the audio-separator CLI with a specific model
+type audioSeparator struct {
+	name          string
+	modelFilename string
+	stems         []string
+}
+
+// DefaultSeparator is used when no model is requested
+const DefaultSeparator = "uvr"
+
+var separators = map[string]Separator{
+	"uvr": &audioSeparator{
+		name:          "uvr",
+		modelFilename: "UVR_MDXNET_Main.onnx",
+		stems:         []string{StemVocals, StemInstrumental},
+	},
+	"demucs": &audioSeparator{
+		name:          "demucs",
+		modelFilename: "htdemucs.yaml",
+		stems:         []string{StemDrums, StemBass, StemOther, StemVocals},
+	},
+}
+
+// GetSeparator returns the separator with the given name ("" selects the default)
+func GetSeparator(name string) (Separator, error) {
+	if name == "" {
+		name = DefaultSeparator
+	}
+	sep, ok := separators[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown separator %q (available: %s)", name, strings.Join(SeparatorNames(), ", "))
+	}
+	return sep, nil
+}
+
+// SeparatorNames lists the available separator names
+func SeparatorNames() []string {
+	var names []string
+	for name := range separators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *audioSeparator) Name() string {
+	return s.name
+}
+
+func (s *audioSeparator) Stems() []string {
+	return s.stems
+}
+
+// modelTag is the model name audio-separator puts at the end of output filenames
+func (s *audioSeparator) modelTag() string {
+	return strings.TrimSuffix(s.modelFilename, filepath.Ext(s.modelFilename))
+}
+
+func (s *audioSeparator) Separate(id string) (map[string]string, error) {
+	inputPath := fmt.Sprintf("./data/%s.wav", id)
+
+	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("input file %s does not exist", inputPath)
+	}
+
+	cmd := exec.Command("audio-separator", inputPath,
+		"--output_dir", "./data/",
+		"--model_filename", s.modelFilename,
+		"--output_format", "wav")
+
+	fmt.Printf("Running: %s\n", cmd.String())
+
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("audio-separator failed: %v", err)
+	}
+
+	produced := make(map[string]string)
+	for _, stem := range s.stems {
+		expectedPath := filepath.Join("./data", StemFilename(id, stem, s.modelTag()))
+
+		// audio-separator drops a leading underscore from the input name
+		if strings.HasPrefix(id, "_") {
+			actualPath := filepath.Join("./data", StemFilename(strings.TrimPrefix(id, "_"), stem, s.modelTag()))
+			if fileExists(actualPath) {
+				if err := os.Rename(actualPath, expectedPath); err != nil {
+					fmt.Printf("Warning: Could not rename %s file: %v\n", stem, err)
+				} else {
+					fmt.Printf("Renamed: %s -> %s\n", filepath.Base(actualPath), filepath.Base(expectedPath))
+				}
+			}
+		}
+
+		if fileExists(expectedPath) {
+			produced[stem] = expectedPath
+		}
+	}
+
+	if len(produced) == 0 {
+		return nil, fmt.Errorf("audio-separator produced no stems for %s", id)
+	}
+
+	return produced, nil
+}
@@ -0,0 +1,212 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"starchive/util"
+)
+
+// Stem names used throughout starchive. Separators may produce any subset of these.
+const (
+	StemVocals       = "vocals"
+	StemInstrumental = "instrumental"
+	StemDrums        = "drums"
+	StemBass         = "bass"
+	StemOther        = "other"
+)
+
+// preferredModels is the order in which stem files are chosen when several models produced the same stem
+var preferredModels = []string{"UVR_MDXNET_Main", "htdemucs"}
+
+// StemFile is a stem discovered on disk
+type StemFile struct {
+	Stem  string
+	Model string
+	Path  string
+}
+
+// NormalizeStemName maps track type codes and separator labels to a canonical stem name
+func NormalizeStemName(name string) string {
+	switch strings.ToLower(name) {
+	case "v", "vocal", "vocals":
+		return StemVocals
+	case "i", "instrumental", "instrumentals", "no_vocals":
+		return StemInstrumental
+	default:
+		return strings.ToLower(name)
+	}
+}
+
+// stemLabel returns the label audio-separator uses in filenames, e.g. "vocals" -> "Vocals"
+func stemLabel(stem string) string {
+	if stem == "" {
+		return stem
+	}
+	return strings.ToUpper(stem[:1]) + stem[1:]
+}
+
+// StemFilename returns the audio-separator filename for a stem produced by the given model
+func StemFilename(id, stem, model string) string {
+	return fmt.Sprintf("%s_(%s)_%s.wav", id, stemLabel(NormalizeStemName(stem)), model)
+}
+
+// FindStems scans ./data for separated stem files belonging to id. Lookups go
+// through the stem registry instead, see RegisteredStems.
+func FindStems(id string) []StemFile {
+	matches, err := filepath.Glob(filepath.Join("./data", id+"_(*)_*.wav"))
+	if err != nil {
+		return nil
+	}
+
+	prefix := id + "_("
+	var stems []StemFile
+	for _, match := range matches {
+		name := strings.TrimSuffix(filepath.Base(match), ".wav")
+		rest := strings.TrimPrefix(name, prefix)
+
+		closeIdx := strings.Index(rest, ")_")
+		if closeIdx <= 0 {
+			continue
+		}
+		model := rest[closeIdx+2:]
+
+		// Skip derived files such as rubberband sync outputs and demos
+		if strings.Contains(model, "_sync_to_") || strings.HasSuffix(model, "_demo") {
+			continue
+		}
+
+		stems = append(stems, StemFile{
+			Stem:  NormalizeStemName(rest[:closeIdx]),
+			Model: model,
+			Path:  match,
+		})
+	}
+
+	sortStems(stems)
+	return stems
+}
+
+// RegisteredStems returns the stems recorded for id in the stem registry whose files
+// still exist, in preference order. IDs separated before the registry existed are
+// scanned on disk once and registered. With a nil db, ./data is scanned instead.
+func RegisteredStems(db *util.Database, id string) []StemFile {
+	if db == nil {
+		return FindStems(id)
+	}
+
+	records, err := db.GetStems(id)
+	if err != nil {
+		return FindStems(id)
+	}
+	var stems []StemFile
+	for _, rec := range records {
+		if fileExists(rec.Path) {
+			stems = append(stems, StemFile{Stem: rec.Stem, Model: rec.Model, Path: rec.Path})
+		}
+	}
+	if len(stems) > 0 {
+		sortStems(stems)
+		return stems
+	}
+
+	stems, _ = RegisterStems(db, id)
+	return stems
+}
+
+// sortStems orders stems by name, then by preferred model
+func sortStems(stems []StemFile) {
+	sort.Slice(stems, func(i, j int) bool {
+		if stems[i].Stem != stems[j].Stem {
+			return stems[i].Stem < stems[j].Stem
+		}
+		return modelRank(stems[i].Model) < modelRank(stems[j].Model)
+	})
+}
+
+func modelRank(model string) int {
+	for i, m := range preferredModels {
+		if m == model {
+			return i
+		}
+	}
+	return len(preferredModels)
+}
+
+// ListStemNames returns the distinct stem names registered for id
+func ListStemNames(db *util.Database, id string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, stem := range RegisteredStems(db, id) {
+		if !seen[stem.Stem] {
+			seen[stem.Stem] = true
+			names = append(names, stem.Stem)
+		}
+	}
+	return names
+}
+
+// FindStemFile returns the path of the preferred file for a stem, or "" if none exists
+func FindStemFile(db *util.Database, id, stem string) string {
+	stem = NormalizeStemName(stem)
+	for _, s := range RegisteredStems(db, id) {
+		if s.Stem == stem {
+			return s.Path
+		}
+	}
+	return ""
+}
+
+// GetStemFilePath returns the path for a stem, falling back to the conventional
+// filename of the separator that normally produces it when nothing is on disk
+func GetStemFilePath(db *util.Database, id, stem string) string {
+	if path := FindStemFile(db, id, stem); path != "" {
+		return path
+	}
+
+	stem = NormalizeStemName(stem)
+	model := preferredModels[0]
+	if stem != StemVocals && stem != StemInstrumental {
+		model = "htdemucs"
+	}
+	return filepath.Join("./data", StemFilename(id, stem, model))
+}
+
+// HasStem checks if a file for the given stem exists for id
+func HasStem(db *util.Database, id, stem string) bool {
+	return FindStemFile(db, id, stem) != ""
+}
+
+// RegisterStems records every stem file found on disk for id in the stem registry
+func RegisterStems(db *util.Database, id string) ([]StemFile, error) {
+	stems := FindStems(id)
+	for _, stem := range stems {
+		if err := db.RecordStem(id, stem.Stem, stem.Model, stem.Path); err != nil {
+			return stems, err
+		}
+	}
+	return stems, nil
+}
+
+// backingType picks the best non-vocal track type for id: instrumental if present,
+// otherwise the first other stem (e.g. drums from a 4-stem separator)
+func backingType(db *util.Database, id string) string {
+	if HasStem(db, id, StemInstrumental) {
+		return "I"
+	}
+	for _, name := range ListStemNames(db, id) {
+		if name != StemVocals {
+			return name
+		}
+	}
+	return ""
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,78 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// TranscodeCacheDir holds compressed copies of WAV files made for streaming
+const TranscodeCacheDir = "./data/transcode"
+
+// TranscodeFormat is a compressed format WAV files can be streamed in
+type TranscodeFormat struct {
+	Name        string
+	Extension   string
+	ContentType string
+	Args        []string // ffmpeg output options
+}
+
+// TranscodeFormats are the streaming formats by name
+var TranscodeFormats = map[string]TranscodeFormat{
+	"opus": {Name: "opus", Extension: ".opus", ContentType: "audio/ogg; codecs=opus",
+		Args: []string{"-c:a", "libopus", "-b:a", "128k", "-f", "ogg"}},
+	"mp3": {Name: "mp3", Extension: ".mp3", ContentType: "audio/mpeg",
+		Args: []string{"-c:a", "libmp3lame", "-b:a", "192k", "-f", "mp3"}},
+}
+
+// One transcode per output file at a time; concurrent requests wait for it
+var transcodeLocks sync.Map
+
+// TranscodeCachePath returns where the transcode of inputPath to a format is cached
+func TranscodeCachePath(inputPath string, format TranscodeFormat) string {
+	base := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	return filepath.Join(TranscodeCacheDir, base+format.Extension)
+}
+
+// Transcode converts inputPath to a streaming format with ffmpeg and returns the
+// cached output path. A cached copy older than its source is made again.
+func Transcode(inputPath string, format TranscodeFormat) (string, error) {
+	outputPath := TranscodeCachePath(inputPath, format)
+
+	lock, _ := transcodeLocks.LoadOrStore(outputPath, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	source, err := os.Stat(inputPath)
+	if err != nil {
+		return "", err
+	}
+	if cached, err := os.Stat(outputPath); err == nil && !cached.ModTime().Before(source.ModTime()) {
+		return outputPath, nil
+	}
+
+	if err := os.MkdirAll(TranscodeCacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	// Encode to a temp name so an interrupted run never leaves a partial cache entry
+	tmpPath := outputPath + ".tmp"
+	args := append([]string{"-y", "-hide_banner", "-loglevel", "error", "-i", inputPath, "-vn"}, format.Args...)
+	cmd := exec.Command("ffmpeg", append(args, tmpPath)...)
+
+	fmt.Printf("Transcoding %s to %s...\n", filepath.Base(inputPath), format.Name)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("ffmpeg failed: %v\n%s", err, output)
+	}
+
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		return "", fmt.Errorf("failed to store transcode: %v", err)
+	}
+
+	return outputPath, nil
+}
@@ -9,6 +9,8 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+
+	"starchive/util"
 )
 
 // GetAudioDuration returns the duration of an audio file in seconds
@@ -55,48 +57,23 @@ func WaitForKeyPress() {
 	}
 }
 
-// GetVocalFilePath returns the path to the vocal version of a track
-func GetVocalFilePath(id string) string {
-	return fmt.Sprintf("./data/%s_(Vocals)_UVR_MDXNET_Main.wav", id)
-}
-
-// GetInstrumentalFilePath returns the path to the instrumental version of a track
-func GetInstrumentalFilePath(id string) string {
-	return fmt.Sprintf("./data/%s_(Instrumental)_UVR_MDXNET_Main.wav", id)
-}
-
-// GetVocalFilename returns just the filename for the vocal version
-func GetVocalFilename(id string) string {
-	return fmt.Sprintf("%s_(Vocals)_UVR_MDXNET_Main.wav", id)
-}
-
-// GetInstrumentalFilename returns just the filename for the instrumental version
-func GetInstrumentalFilename(id string) string {
-	return fmt.Sprintf("%s_(Instrumental)_UVR_MDXNET_Main.wav", id)
-}
-
-// GetAudioFilename returns the appropriate audio file path based on type
-func GetAudioFilename(id, audioType string) string {
-	switch audioType {
-	case "V", "vocal", "vocals":
-		return GetVocalFilePath(id)
-	case "I", "instrumental", "instrumentals":
-		return GetInstrumentalFilePath(id)
-	default:
+// GetAudioFilename returns the appropriate audio file path based on type.
+// audioType may be a type code (V/I), a stem name such as "drums", or empty for the full mix.
+func GetAudioFilename(db *util.Database, id, audioType string) string {
+	if audioType == "" {
 		return fmt.Sprintf("./data/%s.wav", id)
 	}
+	return GetStemFilePath(db, id, audioType)
 }
 
 // HasVocalFile checks if a vocal file exists for the given ID
-func HasVocalFile(id string) bool {
-	_, err := os.Stat(GetVocalFilePath(id))
-	return !os.IsNotExist(err)
+func HasVocalFile(db *util.Database, id string) bool {
+	return HasStem(db, id, StemVocals)
 }
 
 // HasInstrumentalFile checks if an instrumental file exists for the given ID
-func HasInstrumentalFile(id string) bool {
-	_, err := os.Stat(GetInstrumentalFilePath(id))
-	return !os.IsNotExist(err)
+func HasInstrumentalFile(db *util.Database, id string) bool {
+	return HasStem(db, id, StemInstrumental)
 }
 
 // CalculateEffectiveBPM calculates the effective BPM after tempo adjustment
@@ -0,0 +1,342 @@
+package blend
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// arrangeStyle is an auto-arrange preset: how much of the target should carry
+// placed vocals and what the search should favor besides the blend score
+type arrangeStyle struct {
+	Name        string
+	Description string
+	Coverage    float64 // Fraction of the target track covered by segments
+	BarsOnly    bool    // Only place on the first beat of a bar
+	GapBonus    float64 // Points for placements starting in gaps, scaled by their fraction
+	Harmony     bool    // Stack harmonies on high-energy segments afterwards
+}
+
+// arrangeStyles are the presets from PLAN.md step 19
+var arrangeStyles = []arrangeStyle{
+	{Name: "minimal", Description: "a few segments on bar lines, lots of space", Coverage: 0.2, BarsOnly: true},
+	{Name: "dense", Description: "vocals over most of the track", Coverage: 0.65},
+	{Name: "call-response", Description: "segments answer in the gaps of the other track", Coverage: 0.35, GapBonus: 15, BarsOnly: true},
+	{Name: "harmony-heavy", Description: "moderate coverage with stacked harmonies on the big moments", Coverage: 0.35, Harmony: true},
+}
+
+// Search budget for the annealer
+const (
+	arrangeIterations  = 4000
+	arrangeStartTemp   = 8.0
+	arrangeFinalTemp   = 0.05
+	arrangeMaxCoverage = 0.9 // Keep placements out of the last 10% of the target
+)
+
+// HandleArrangeCommand processes automatic arrangement commands (auto-arrange, magic-blend)
+func (bs *Shell) HandleArrangeCommand(cmd string, args []string) bool {
+	switch cmd {
+	case "auto-arrange":
+		if bs.handleAutoArrangeCommand(cmd, args) {
+			bs.previewArrangement()
+		}
+
+	case "magic-blend":
+		bs.handleMagicBlendCommand(args)
+
+	default:
+		return false // Command not handled by this module
+	}
+
+	return true
+}
+
+// lookupArrangeStyle finds a preset by name; "" selects the first
+func lookupArrangeStyle(name string) (arrangeStyle, bool) {
+	if name == "" {
+		return arrangeStyles[0], true
+	}
+	for _, style := range arrangeStyles {
+		if style.Name == name {
+			return style, true
+		}
+	}
+	return arrangeStyle{}, false
+}
+
+// handleAutoArrangeCommand searches for the best placement of every segment in a
+// style. cmd is the command recorded with the seed. It returns false if nothing
+// was arranged.
+func (bs *Shell) handleAutoArrangeCommand(cmd string, args []string) bool {
+	args, seed, seeded, err := parseSeedFlag(args)
+	if err != nil {
+		fmt.Fprintf(bs.Out, "%v\n", err)
+		return false
+	}
+
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	style, ok := lookupArrangeStyle(name)
+	if !ok {
+		fmt.Fprintf(bs.Out, "Unknown style: %s\n", name)
+		fmt.Fprintf(bs.Out, "Usage: %s [style] [--seed N]\n", cmd)
+		for _, style := range arrangeStyles {
+			fmt.Fprintf(bs.Out, "  %-14s %s\n", style.Name, style.Description)
+		}
+		return false
+	}
+
+	return bs.autoArrange(style, bs.seededRand(cmd+" "+style.Name, seed, seeded))
+}
+
+// handleMagicBlendCommand runs the analysis steps and then auto-arranges (PLAN.md step 20)
+func (bs *Shell) handleMagicBlendCommand(args []string) {
+	fmt.Fprintf(bs.Out, "🪄 Magic blend: analyzing, matching and arranging...\n\n")
+
+	for track := 1; track <= 2; track++ {
+		if bs.isVocalTrack(track) && len(bs.trackSegments(track)) == 0 {
+			bs.HandleSegmentCreationCommand("split", []string{fmt.Sprintf("%d", track)})
+		}
+	}
+	bs.HandleAudioCommand("analyze-segments", []string{"1"})
+	bs.HandleAudioCommand("analyze-segments", []string{"2"})
+	bs.HandleAudioCommand("beat-detect", []string{"both"})
+	bs.HandleMatchingCommand("auto-match", []string{})
+	bs.AutoFade = true
+	fmt.Fprintf(bs.Out, "\n")
+
+	if bs.handleAutoArrangeCommand("magic-blend", args) {
+		bs.previewArrangement()
+	}
+}
+
+// arrangeSlot is one segment the search may place
+type arrangeSlot struct {
+	track      int
+	index      int       // Position in the track's segments
+	candidates []float64 // Allowed placements on the target track
+}
+
+// autoArrange places the segments of every vocal track onto the other track with
+// simulated annealing, using the blend score plus the style's terms as objective.
+// It returns false if there is nothing to arrange.
+func (bs *Shell) autoArrange(style arrangeStyle, rng *rand.Rand) bool {
+	var slots []arrangeSlot
+	for track := 1; track <= 2; track++ {
+		segments := bs.trackSegments(track)
+		if len(segments) == 0 || !bs.isVocalTrack(track) {
+			continue
+		}
+
+		target := otherTrack(track)
+		positions := bs.arrangePositions(target, style.BarsOnly)
+		for i := range segments {
+			var candidates []float64
+			limit := bs.trackDuration(target)*arrangeMaxCoverage - bs.placedDuration(track, segments[i])
+			for _, pos := range positions {
+				if pos <= limit {
+					candidates = append(candidates, pos)
+				}
+			}
+			if len(candidates) > 0 {
+				slots = append(slots, arrangeSlot{track: track, index: i, candidates: candidates})
+			}
+		}
+	}
+
+	if len(slots) == 0 {
+		fmt.Fprintf(bs.Out, "Nothing to arrange: split a vocal track into segments first (split <1|2>)\n")
+		return false
+	}
+
+	bs.analyzeForScore()
+	fmt.Fprintf(bs.Out, "Auto-arranging %d segments in %s style (%s)...\n", len(slots), style.Name, style.Description)
+
+	// Start from silence and let the search add segments
+	for _, slot := range slots {
+		seg := &bs.trackSegments(slot.track)[slot.index]
+		seg.Active = false
+		seg.Placement = slot.candidates[0]
+	}
+
+	current := bs.arrangeObjective(style)
+	best := current
+	bestState := bs.arrangeSnapshot(slots)
+
+	for i := 0; i < arrangeIterations; i++ {
+		temp := arrangeStartTemp * math.Pow(arrangeFinalTemp/arrangeStartTemp, float64(i)/arrangeIterations)
+
+		slot := slots[rng.Intn(len(slots))]
+		segments := bs.trackSegments(slot.track)
+		seg := &segments[slot.index]
+		oldActive, oldPlacement := seg.Active, seg.Placement
+
+		// Move: toggle a segment, or move it to another allowed position
+		if rng.Float64() < 0.3 {
+			seg.Active = !seg.Active
+		} else {
+			seg.Active = true
+			seg.Placement = slot.candidates[rng.Intn(len(slot.candidates))]
+		}
+
+		// Conflicts are a hard constraint, not a cost
+		if seg.Active {
+			others := bs.trackSegments(otherTrack(slot.track))
+			if bs.wouldCauseConflict(slot.track, seg, seg.Placement, &segments, &others) {
+				seg.Active, seg.Placement = oldActive, oldPlacement
+				continue
+			}
+		}
+
+		next := bs.arrangeObjective(style)
+		if next >= current || rng.Float64() < math.Exp((next-current)/temp) {
+			current = next
+			if current > best {
+				best = current
+				bestState = bs.arrangeSnapshot(slots)
+			}
+		} else {
+			seg.Active, seg.Placement = oldActive, oldPlacement
+		}
+	}
+
+	bs.arrangeRestore(slots, bestState)
+	if style.Harmony {
+		bs.stackHarmonies()
+	}
+
+	placed := 0
+	for _, slot := range slots {
+		seg := bs.trackSegments(slot.track)[slot.index]
+		if seg.Active {
+			placed++
+			target := otherTrack(slot.track)
+			fmt.Fprintf(bs.Out, "  %d:%d at %.2fs (bar %s)\n", slot.track, slot.index+1, seg.Placement, bs.formatBarBeat(target, seg.Placement))
+		}
+	}
+	fmt.Fprintf(bs.Out, "Arrangement complete: %d/%d segments placed, score %.0f/100\n", placed, len(slots), bs.Score().Total)
+	return true
+}
+
+// arrangeObjective is the value the search maximizes: the blend score adjusted for the style
+func (bs *Shell) arrangeObjective(style arrangeStyle) float64 {
+	score := bs.Score()
+	objective := score.Total
+
+	covered, length := 0.0, 0.0
+	for track := 1; track <= 2; track++ {
+		active := bs.getActiveSegments(track)
+		if !bs.isVocalTrack(track) || len(bs.trackSegments(track)) == 0 {
+			continue
+		}
+		length += bs.trackDuration(otherTrack(track)) * arrangeMaxCoverage
+		for _, seg := range active {
+			covered += bs.placedDuration(track, seg)
+		}
+	}
+	if length > 0 {
+		objective -= 100 * math.Abs(covered/length-style.Coverage)
+	}
+
+	if style.GapBonus > 0 {
+		for _, rule := range score.Rules {
+			if rule.Name == "gap usage" && !rule.Skipped {
+				objective += style.GapBonus * rule.Score
+			}
+		}
+	}
+	return objective
+}
+
+// arrangePositions returns the placements allowed on a target track: its beats, or
+// only bar starts, counted on its beat grid
+func (bs *Shell) arrangePositions(target int, barsOnly bool) []float64 {
+	duration := bs.trackDuration(target)
+	step := 1.0
+	if barsOnly {
+		step = beatsPerBar
+	}
+
+	var positions []float64
+	for index := 0.0; ; index += step {
+		pos := bs.beatToSeconds(target, index)
+		if pos >= duration || len(positions) > 10000 {
+			break
+		}
+		if pos >= 0 {
+			positions = append(positions, pos)
+		}
+	}
+	return positions
+}
+
+type arrangeState struct {
+	active    bool
+	placement float64
+}
+
+func (bs *Shell) arrangeSnapshot(slots []arrangeSlot) []arrangeState {
+	state := make([]arrangeState, len(slots))
+	for i, slot := range slots {
+		seg := bs.trackSegments(slot.track)[slot.index]
+		state[i] = arrangeState{active: seg.Active, placement: seg.Placement}
+	}
+	return state
+}
+
+func (bs *Shell) arrangeRestore(slots []arrangeSlot, state []arrangeState) {
+	for i, slot := range slots {
+		seg := &bs.trackSegments(slot.track)[slot.index]
+		seg.Active, seg.Placement = state[i].active, state[i].placement
+	}
+}
+
+// stackHarmonies adds the default harmony stack to the loudest third of the placed segments
+func (bs *Shell) stackHarmonies() {
+	for track := 1; track <= 2; track++ {
+		segments := bs.trackSegments(track)
+		var placed []int
+		for i, seg := range segments {
+			if seg.Active {
+				placed = append(placed, i)
+			}
+		}
+		sort.Slice(placed, func(a, b int) bool {
+			return segments[placed[a]].RMSEnergy > segments[placed[b]].RMSEnergy
+		})
+
+		for n, i := range placed {
+			if n >= (len(placed)+2)/3 {
+				break
+			}
+			setSegmentEffect(&segments[i], SegmentEffect{Type: EffectHarmony, Semitones: bs.defaultHarmony(track)})
+		}
+	}
+}
+
+// previewArrangement plays the blend from a bar before the first placed segment
+func (bs *Shell) previewArrangement() {
+	first := math.Inf(1)
+	grid := bs.gridTrack()
+	for _, seg := range bs.getActiveSegments(otherTrack(grid)) {
+		first = math.Min(first, seg.Placement)
+	}
+	if math.IsInf(first, 1) {
+		bs.handlePlayCommand(-1)
+		return
+	}
+
+	start := bs.beatToSeconds(grid, bs.secondsToBeat(grid, first)-beatsPerBar)
+	bs.handlePlayCommand(math.Max(start, 0))
+}
+
+// trackDuration returns a track's length in its original seconds
+func (bs *Shell) trackDuration(track int) float64 {
+	if track == 2 {
+		return bs.Duration2
+	}
+	return bs.Duration1
+}
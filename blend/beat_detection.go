@@ -14,7 +14,7 @@ func (bs *Shell) HandleBeatDetectionCommand(cmd string, args []string) bool {
 		if len(args) > 0 {
 			bs.handleBeatDetectCommand(args[0])
 		} else {
-			fmt.Printf("Usage: beat-detect <1|2|both>\n")
+			fmt.Fprintf(bs.Out, "Usage: beat-detect <1|2|both>\n")
 		}
 		
 	case "beats":
@@ -49,13 +49,13 @@ func (bs *Shell) handleBeatDetectCommand(target string) {
 		bs.detectBeats("1", bs.InputPath1, bs.ID1, &bs.Beats1)
 		bs.detectBeats("2", bs.InputPath2, bs.ID2, &bs.Beats2)
 	default:
-		fmt.Printf("Invalid target: %s (use 1, 2, or both)\n", target)
+		fmt.Fprintf(bs.Out, "Invalid target: %s (use 1, 2, or both)\n", target)
 	}
 }
 
 // detectBeats uses ffprobe with onset detection to find beat positions
 func (bs *Shell) detectBeats(trackNum, inputPath, id string, beats *[]float64) {
-	fmt.Printf("Detecting beats in track %s (%s)...\n", trackNum, id)
+	fmt.Fprintf(bs.Out, "Detecting beats in track %s (%s)...\n", trackNum, id)
 	
 	// Use ffprobe with silencedetect as a simple onset detector
 	// This detects sudden changes in audio level which often correspond to beats
@@ -86,7 +86,7 @@ func (bs *Shell) detectBeatsWithSpectralAnalysis(trackNum, inputPath, id string,
 	
 	_, err := cmd.Output()
 	if err != nil {
-		fmt.Printf("  Spectral analysis failed, using simple approach: %v\n", err)
+		fmt.Fprintf(bs.Out, "  Spectral analysis failed, using simple approach: %v\n", err)
 		bs.detectBeatsSimple(trackNum, inputPath, id, beats)
 		return
 	}
@@ -97,12 +97,12 @@ func (bs *Shell) detectBeatsWithSpectralAnalysis(trackNum, inputPath, id string,
 
 // detectBeatsWithAubio uses aubio onset detection if available
 func (bs *Shell) detectBeatsWithAubio(trackNum, inputPath, id string, beats *[]float64) {
-	fmt.Printf("  Trying aubio onset detection...\n")
+	fmt.Fprintf(bs.Out, "  Trying aubio onset detection...\n")
 	
 	// Check if aubio is available
 	checkCmd := exec.Command("which", "aubiodet")
 	if checkCmd.Run() != nil {
-		fmt.Printf("  aubio not available, using simple approach\n")
+		fmt.Fprintf(bs.Out, "  aubio not available, using simple approach\n")
 		bs.detectBeatsSimple(trackNum, inputPath, id, beats)
 		return
 	}
@@ -111,7 +111,7 @@ func (bs *Shell) detectBeatsWithAubio(trackNum, inputPath, id string, beats *[]f
 	cmd := exec.Command("aubiodet", "-i", inputPath, "-O", "onset")
 	output, err := cmd.Output()
 	if err != nil {
-		fmt.Printf("  aubio failed: %v, using simple approach\n", err)
+		fmt.Fprintf(bs.Out, "  aubio failed: %v, using simple approach\n", err)
 		bs.detectBeatsSimple(trackNum, inputPath, id, beats)
 		return
 	}
@@ -131,12 +131,12 @@ func (bs *Shell) detectBeatsWithAubio(trackNum, inputPath, id string, beats *[]f
 		}
 	}
 	
-	fmt.Printf("  Found %d onsets/beats using aubio\n", len(*beats))
+	fmt.Fprintf(bs.Out, "  Found %d onsets/beats using aubio\n", len(*beats))
 }
 
 // detectBeatsSimple uses a basic approach based on BPM metadata
 func (bs *Shell) detectBeatsSimple(trackNum, inputPath, id string, beats *[]float64) {
-	fmt.Printf("  Using simple BPM-based beat detection...\n")
+	fmt.Fprintf(bs.Out, "  Using simple BPM-based beat detection...\n")
 	
 	var metadata *VideoMetadata
 	var duration float64
@@ -152,13 +152,13 @@ func (bs *Shell) detectBeatsSimple(trackNum, inputPath, id string, beats *[]floa
 	*beats = []float64{}
 	
 	if metadata == nil || metadata.BPM == nil {
-		fmt.Printf("  No BPM metadata available for track %s\n", trackNum)
+		fmt.Fprintf(bs.Out, "  No BPM metadata available for track %s\n", trackNum)
 		return
 	}
 	
 	bpm := *metadata.BPM
 	if bpm <= 0 {
-		fmt.Printf("  Invalid BPM value: %.1f\n", bpm)
+		fmt.Fprintf(bs.Out, "  Invalid BPM value: %.1f\n", bpm)
 		return
 	}
 	
@@ -170,50 +170,50 @@ func (bs *Shell) detectBeatsSimple(trackNum, inputPath, id string, beats *[]floa
 		*beats = append(*beats, t)
 	}
 	
-	fmt.Printf("  Generated %d beats based on %.1f BPM (every %.2fs)\n", len(*beats), bpm, beatInterval)
+	fmt.Fprintf(bs.Out, "  Generated %d beats based on %.1f BPM (every %.2fs)\n", len(*beats), bpm, beatInterval)
 }
 
 // handleBeatsCommand shows detected beats
 func (bs *Shell) handleBeatsCommand(track string) {
 	if track == "" {
 		// Show beats for both tracks
-		fmt.Printf("Track 1 beats: %d total\n", len(bs.Beats1))
+		fmt.Fprintf(bs.Out, "Track 1 beats: %d total\n", len(bs.Beats1))
 		if len(bs.Beats1) > 0 {
-			fmt.Printf("  First 10 beats: ")
+			fmt.Fprintf(bs.Out, "  First 10 beats: ")
 			for i, beat := range bs.Beats1 {
 				if i >= 10 { break }
-				fmt.Printf("%.1fs ", beat)
+				fmt.Fprintf(bs.Out, "%.1fs ", beat)
 			}
-			fmt.Printf("\n")
+			fmt.Fprintf(bs.Out, "\n")
 			if len(bs.Beats1) > 10 {
-				fmt.Printf("  ... and %d more\n", len(bs.Beats1)-10)
+				fmt.Fprintf(bs.Out, "  ... and %d more\n", len(bs.Beats1)-10)
 			}
 		}
 		
-		fmt.Printf("Track 2 beats: %d total\n", len(bs.Beats2))
+		fmt.Fprintf(bs.Out, "Track 2 beats: %d total\n", len(bs.Beats2))
 		if len(bs.Beats2) > 0 {
-			fmt.Printf("  First 10 beats: ")
+			fmt.Fprintf(bs.Out, "  First 10 beats: ")
 			for i, beat := range bs.Beats2 {
 				if i >= 10 { break }
-				fmt.Printf("%.1fs ", beat)
+				fmt.Fprintf(bs.Out, "%.1fs ", beat)
 			}
-			fmt.Printf("\n")
+			fmt.Fprintf(bs.Out, "\n")
 			if len(bs.Beats2) > 10 {
-				fmt.Printf("  ... and %d more\n", len(bs.Beats2)-10)
+				fmt.Fprintf(bs.Out, "  ... and %d more\n", len(bs.Beats2)-10)
 			}
 		}
 	} else if track == "1" {
-		fmt.Printf("Track 1 beats: %d total\n", len(bs.Beats1))
+		fmt.Fprintf(bs.Out, "Track 1 beats: %d total\n", len(bs.Beats1))
 		for i, beat := range bs.Beats1 {
-			fmt.Printf("  Beat %d: %.2fs\n", i+1, beat)
+			fmt.Fprintf(bs.Out, "  Beat %d: %.2fs\n", i+1, beat)
 		}
 	} else if track == "2" {
-		fmt.Printf("Track 2 beats: %d total\n", len(bs.Beats2))
+		fmt.Fprintf(bs.Out, "Track 2 beats: %d total\n", len(bs.Beats2))
 		for i, beat := range bs.Beats2 {
-			fmt.Printf("  Beat %d: %.2fs\n", i+1, beat)
+			fmt.Fprintf(bs.Out, "  Beat %d: %.2fs\n", i+1, beat)
 		}
 	} else {
-		fmt.Printf("Invalid track: %s (use 1 or 2)\n", track)
+		fmt.Fprintf(bs.Out, "Invalid track: %s (use 1 or 2)\n", track)
 	}
 }
 
@@ -228,21 +228,21 @@ func (bs *Shell) handleQuantizeCommand(target string) {
 		bs.quantizeSegments(1, &bs.Segments1, bs.Beats2)
 		bs.quantizeSegments(2, &bs.Segments2, bs.Beats1)
 	default:
-		fmt.Printf("Invalid target: %s (use 1, 2, or both)\n", target)
+		fmt.Fprintf(bs.Out, "Invalid target: %s (use 1, 2, or both)\n", target)
 	}
 }
 
 // quantizeSegments snaps all segment placements to the nearest beat boundaries
 func (bs *Shell) quantizeSegments(trackNum int, segments *[]VocalSegment, targetBeats []float64) {
 	if len(targetBeats) == 0 {
-		fmt.Printf("No beats available for target track. Run beat-detect first.\n")
+		fmt.Fprintf(bs.Out, "No beats available for target track. Run beat-detect first.\n")
 		return
 	}
 	
 	quantizedCount := 0
 	totalAdjustment := 0.0
 	
-	fmt.Printf("Quantizing track %d segments to nearest beat boundaries...\n", trackNum)
+	fmt.Fprintf(bs.Out, "Quantizing track %d segments to nearest beat boundaries...\n", trackNum)
 	
 	for i := range *segments {
 		segment := &(*segments)[i]
@@ -257,17 +257,17 @@ func (bs *Shell) quantizeSegments(trackNum int, segments *[]VocalSegment, target
 			quantizedCount++
 			totalAdjustment += abs(adjustment)
 			
-			fmt.Printf("  Segment %d: %.2fs → %.2fs (adjusted by %.2fs)\n", 
+			fmt.Fprintf(bs.Out, "  Segment %d: %.2fs → %.2fs (adjusted by %.2fs)\n", 
 				segment.Index, originalPlacement, nearestBeat, adjustment)
 		}
 	}
 	
 	if quantizedCount > 0 {
 		avgAdjustment := totalAdjustment / float64(quantizedCount)
-		fmt.Printf("Quantized %d segments with average adjustment of %.2fs\n", 
+		fmt.Fprintf(bs.Out, "Quantized %d segments with average adjustment of %.2fs\n", 
 			quantizedCount, avgAdjustment)
 	} else {
-		fmt.Printf("All segments were already aligned to beat boundaries\n")
+		fmt.Fprintf(bs.Out, "All segments were already aligned to beat boundaries\n")
 	}
 }
 
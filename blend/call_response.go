@@ -0,0 +1,166 @@
+package blend
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+
+	"starchive/audio"
+)
+
+// VocalPhrase is a stretch of continuous singing in a track's vocal stem
+type VocalPhrase struct {
+	Start float64 // In the track's original seconds
+	End   float64
+}
+
+// Call-and-response tuning
+const (
+	phraseSilenceDB  = -35.0 // Below this the vocal stem counts as silent
+	phraseMinPause   = 0.4   // Shorter pauses are breaths inside a phrase
+	phraseMinLength  = 0.3   // Shorter bursts are bleed, not phrases
+	responseMinSpace = 1.0   // Smallest gap between phrases worth answering in
+)
+
+var silenceLinePattern = regexp.MustCompile(`silence_(start|end): (-?[0-9.]+)`)
+
+// handleCallResponseCommand places a track's segments in the pauses between the other
+// track's vocal phrases, each answer starting on the first beat after a phrase ends
+func (bs *Shell) handleCallResponseCommand(trackNum string) {
+	track, err := strconv.Atoi(trackNum)
+	if err != nil || (track != 1 && track != 2) {
+		fmt.Fprintf(bs.Out, "Invalid track number: %s (use 1 or 2)\n", trackNum)
+		return
+	}
+
+	segments := bs.trackSegments(track)
+	if len(segments) == 0 {
+		fmt.Fprintf(bs.Out, "No segments found for track %d. Run 'split %d' first.\n", track, track)
+		return
+	}
+
+	target := otherTrack(track)
+	targetID := bs.ID1
+	if target == 2 {
+		targetID = bs.ID2
+	}
+	vocalPath := audio.GetAudioFilename(bs.DB, targetID, "V")
+	if _, err := os.Stat(vocalPath); err != nil {
+		fmt.Fprintf(bs.Out, "Call-response needs the vocal stem of track %d (%s): %v\n", target, vocalPath, err)
+		return
+	}
+
+	fmt.Fprintf(bs.Out, "Detecting vocal phrases in track %d (%s)...\n", target, targetID)
+	phrases, err := detectVocalPhrases(vocalPath, bs.trackDuration(target))
+	if err != nil {
+		fmt.Fprintf(bs.Out, "Error detecting vocal phrases: %v\n", err)
+		return
+	}
+	if len(phrases) == 0 {
+		fmt.Fprintf(bs.Out, "No vocal phrases found in track %d; nothing to respond to\n", target)
+		return
+	}
+	if len(bs.trackBeats(target)) == 0 {
+		fmt.Fprintf(bs.Out, "💡 No beats detected for track %d; snapping to its BPM grid (run 'beat-detect %d' for better timing)\n", target, target)
+	}
+
+	// The pauses between phrases are where the answers go
+	type pause struct{ start, end float64 }
+	var pauses []pause
+	for i := 0; i+1 < len(phrases); i++ {
+		if phrases[i+1].Start-phrases[i].End >= responseMinSpace {
+			pauses = append(pauses, pause{phrases[i].End, phrases[i+1].Start})
+		}
+	}
+
+	for i := range segments {
+		segments[i].Active = false
+	}
+	others := bs.trackSegments(target)
+
+	fmt.Fprintf(bs.Out, "Found %d phrases and %d pauses to answer in\n", len(phrases), len(pauses))
+
+	placed := 0
+	used := make(map[int]bool)
+	for _, p := range pauses {
+		start := bs.beatToSeconds(target, math.Ceil(bs.secondsToBeat(target, p.start)-0.01))
+		space := p.end - start
+		if space <= 0 {
+			continue
+		}
+
+		// The longest unused segment that fits fills the pause best
+		best := -1
+		for i := range segments {
+			length := bs.placedDuration(track, segments[i])
+			if used[i] || length > space {
+				continue
+			}
+			if best >= 0 && length <= bs.placedDuration(track, segments[best]) {
+				continue
+			}
+			if bs.wouldCauseConflict(track, &segments[i], start, &segments, &others) {
+				continue
+			}
+			best = i
+		}
+		if best < 0 {
+			continue
+		}
+
+		segments[best].Placement = start
+		segments[best].Active = true
+		used[best] = true
+		placed++
+		fmt.Fprintf(bs.Out, "  %d:%d answers at %.2fs (bar %s) in a %.1fs pause\n",
+			track, best+1, start, bs.formatBarBeat(target, start), p.end-p.start)
+	}
+
+	fmt.Fprintf(bs.Out, "Call-response placement complete: %d/%d segments placed\n", placed, len(segments))
+}
+
+// detectVocalPhrases finds the phrases of a vocal stem from the silences between them
+func detectVocalPhrases(vocalPath string, duration float64) ([]VocalPhrase, error) {
+	cmd := exec.Command("ffmpeg", "-hide_banner", "-i", vocalPath,
+		"-af", fmt.Sprintf("silencedetect=noise=%.0fdB:d=%.2f", phraseSilenceDB, phraseMinPause),
+		"-f", "null", "-")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	var phrases []VocalPhrase
+	phraseStart := 0.0
+	inSilence := false
+	for _, match := range silenceLinePattern.FindAllStringSubmatch(string(output), -1) {
+		at, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			continue
+		}
+		if match[1] == "start" && !inSilence {
+			if at-phraseStart >= phraseMinLength {
+				phrases = append(phrases, VocalPhrase{Start: phraseStart, End: at})
+			}
+			inSilence = true
+		} else if match[1] == "end" && inSilence {
+			phraseStart = at
+			inSilence = false
+		}
+	}
+	if !inSilence && duration-phraseStart >= phraseMinLength {
+		phrases = append(phrases, VocalPhrase{Start: phraseStart, End: duration})
+	}
+
+	return phrases, nil
+}
+
+// trackBeats returns a track's detected beats
+func (bs *Shell) trackBeats(track int) []float64 {
+	if track == 2 {
+		return bs.Beats2
+	}
+	return bs.Beats1
+}
@@ -19,6 +19,7 @@ func (bs *Shell) HandleCommand(input string) bool {
 		cmd = cmd[1:]
 	}
 	args := parts[1:]
+	bs.History = append(bs.History, strings.Join(parts, " "))
 	
 	// Check for exit commands first
 	if IsExitCommand(cmd) {
@@ -39,6 +40,18 @@ func (bs *Shell) HandleCommand(input string) bool {
 		return true
 	}
 	
+	if bs.HandleStemCommand(cmd, args) {
+		return true
+	}
+	
+	if bs.HandleTimelineCommand(cmd, args) {
+		return true
+	}
+	
+	if bs.HandleFadeCommand(cmd, args) {
+		return true
+	}
+	
 	if bs.HandlePlaybackCommand(cmd, args) {
 		return true
 	}
@@ -51,8 +64,24 @@ func (bs *Shell) HandleCommand(input string) bool {
 		return true
 	}
 	
+	if bs.HandleSegmentEffectCommand(cmd, args) {
+		return true
+	}
+	
+	if bs.HandleTemplateCommand(cmd, args) {
+		return true
+	}
+	
+	if bs.HandleScoreCommand(cmd, args) {
+		return true
+	}
+	
+	if bs.HandleArrangeCommand(cmd, args) {
+		return true
+	}
+	
 	// If no module handled the command, show error
-	fmt.Printf("Unknown command: %s. Type 'help' for available commands.\n", cmd)
+	fmt.Fprintf(bs.Out, "Unknown command: %s. Type 'help' for available commands.\n", cmd)
 	return true
 }
 
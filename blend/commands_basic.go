@@ -10,7 +10,7 @@ import (
 func (bs *Shell) HandleBasicCommand(cmd string, args []string) bool {
 	switch cmd {
 	case "exit", "quit", "q":
-		fmt.Println("Exiting blend shell...")
+		fmt.Fprintln(bs.Out, "Exiting blend shell...")
 		// We need to signal exit differently - we'll handle this in the main command handler
 		return true
 
@@ -26,12 +26,18 @@ func (bs *Shell) HandleBasicCommand(cmd string, args []string) bool {
 	case "conflict-detect":
 		bs.handleConflictDetectCommand()
 
+	case "history":
+		bs.showHistory()
+
 	case "foundation":
-		if len(args) > 0 {
-			bs.handleFoundationCommand(args[0])
+		args, seed, seeded, err := parseSeedFlag(args)
+		if err != nil {
+			fmt.Fprintf(bs.Out, "%v\n", err)
+		} else if len(args) > 0 {
+			bs.handleFoundationCommand(args[0], seed, seeded)
 		} else {
-			fmt.Printf("Usage: foundation <N>  (runs steps 1-N from PLAN.md)\n")
-			fmt.Printf("Available steps: 1=analyze-segments, 2=beat-detect, 3=auto-match, 4=conflict-detect, 5=segment-trim, 6=smart-random, 7=gap-finder, 8=quantize\n")
+			fmt.Fprintf(bs.Out, "Usage: foundation <N> [--seed N]  (runs steps 1-N from PLAN.md)\n")
+			fmt.Fprintf(bs.Out, "Available steps: 1=analyze-segments, 2=beat-detect, 3=auto-match, 4=conflict-detect, 5=segment-trim, 6=smart-random, 7=gap-finder, 8=quantize, 14=crossfade-auto, 17=score, 18=auto-arrange\n")
 		}
 
 	default:
@@ -48,45 +54,45 @@ func IsExitCommand(cmd string) bool {
 
 // handleConflictDetectCommand analyzes potential vocal segment overlaps
 func (bs *Shell) handleConflictDetectCommand() {
-	fmt.Printf("Analyzing potential vocal conflicts...\n")
+	fmt.Fprintf(bs.Out, "Analyzing potential vocal conflicts...\n")
 
 	// Check if we have active segments to analyze
 	activeSegments1 := bs.getActiveSegments(1)
 	activeSegments2 := bs.getActiveSegments(2)
 
 	if len(activeSegments1) == 0 && len(activeSegments2) == 0 {
-		fmt.Printf("No active segments to analyze. Use 'add' commands to place segments first.\n")
+		fmt.Fprintf(bs.Out, "No active segments to analyze. Use 'add' commands to place segments first.\n")
 		return
 	}
 
 	conflicts := 0
 	warnings := 0
 
-	fmt.Printf("Checking %d active segments...\n", len(activeSegments1)+len(activeSegments2))
+	fmt.Fprintf(bs.Out, "Checking %d active segments...\n", len(activeSegments1)+len(activeSegments2))
 
 	// Analyze overlaps between all active segments
 	for _, seg1 := range activeSegments1 {
 		for _, seg2 := range activeSegments1 {
 			if seg1.Index != seg2.Index {
-				overlap := bs.calculateOverlap(seg1, seg2)
+				overlap := bs.calculateOverlap(1, seg1, 1, seg2)
 				if overlap > 0 {
 					conflicts++
-					fmt.Printf("  ⚠️  CONFLICT: Segments %d and %d overlap by %.1fs\n",
+					fmt.Fprintf(bs.Out, "  ⚠️  CONFLICT: Segments %d and %d overlap by %.1fs\n",
 						seg1.Index, seg2.Index, overlap)
 				}
 			}
 		}
 
 		for _, seg2 := range activeSegments2 {
-			overlap := bs.calculateOverlap(seg1, seg2)
+			overlap := bs.calculateOverlap(1, seg1, 2, seg2)
 			if overlap > 0 {
-				if bs.Type1 == "V" && bs.Type2 == "V" {
+				if bs.isVocalTrack(1) && bs.isVocalTrack(2) {
 					conflicts++
-					fmt.Printf("  ⚠️  VOCAL CONFLICT: Track 1 seg %d and Track 2 seg %d overlap by %.1fs\n",
+					fmt.Fprintf(bs.Out, "  ⚠️  VOCAL CONFLICT: Track 1 seg %d and Track 2 seg %d overlap by %.1fs\n",
 						seg1.Index, seg2.Index, overlap)
 				} else {
 					warnings++
-					fmt.Printf("  ℹ️  OVERLAP: Track 1 seg %d and Track 2 seg %d overlap by %.1fs\n",
+					fmt.Fprintf(bs.Out, "  ℹ️  OVERLAP: Track 1 seg %d and Track 2 seg %d overlap by %.1fs\n",
 						seg1.Index, seg2.Index, overlap)
 				}
 			}
@@ -96,10 +102,10 @@ func (bs *Shell) handleConflictDetectCommand() {
 	for _, seg1 := range activeSegments2 {
 		for _, seg2 := range activeSegments2 {
 			if seg1.Index != seg2.Index {
-				overlap := bs.calculateOverlap(seg1, seg2)
+				overlap := bs.calculateOverlap(2, seg1, 2, seg2)
 				if overlap > 0 {
 					conflicts++
-					fmt.Printf("  ⚠️  CONFLICT: Track 2 segments %d and %d overlap by %.1fs\n",
+					fmt.Fprintf(bs.Out, "  ⚠️  CONFLICT: Track 2 segments %d and %d overlap by %.1fs\n",
 						seg1.Index, seg2.Index, overlap)
 				}
 			}
@@ -107,23 +113,23 @@ func (bs *Shell) handleConflictDetectCommand() {
 	}
 
 	// Summary
-	fmt.Printf("\n--- Conflict Analysis Summary ---\n")
+	fmt.Fprintf(bs.Out, "\n--- Conflict Analysis Summary ---\n")
 	if conflicts > 0 {
-		fmt.Printf("🚨 %d CONFLICTS found (segments overlap problematically)\n", conflicts)
+		fmt.Fprintf(bs.Out, "🚨 %d CONFLICTS found (segments overlap problematically)\n", conflicts)
 	}
 	if warnings > 0 {
-		fmt.Printf("⚠️  %d overlaps detected (may be acceptable depending on arrangement)\n", warnings)
+		fmt.Fprintf(bs.Out, "⚠️  %d overlaps detected (may be acceptable depending on arrangement)\n", warnings)
 	}
 	if conflicts == 0 && warnings == 0 {
-		fmt.Printf("✅ No conflicts detected - segments are well spaced\n")
+		fmt.Fprintf(bs.Out, "✅ No conflicts detected - segments are well spaced\n")
 	}
 
 	// Suggestions
 	if conflicts > 0 || warnings > 0 {
-		fmt.Printf("\nSuggestions:\n")
-		fmt.Printf("  - Use 'move' command to reposition conflicting segments\n")
-		fmt.Printf("  - Use 'gap-finder' to find better placement spots\n")
-		fmt.Printf("  - Consider shorter segment durations\n")
+		fmt.Fprintf(bs.Out, "\nSuggestions:\n")
+		fmt.Fprintf(bs.Out, "  - Use 'move' command to reposition conflicting segments\n")
+		fmt.Fprintf(bs.Out, "  - Use 'gap-finder' to find better placement spots\n")
+		fmt.Fprintf(bs.Out, "  - Consider shorter segment durations\n")
 	}
 }
 
@@ -148,12 +154,10 @@ func (bs *Shell) getActiveSegments(track int) []VocalSegment {
 	return segments
 }
 
-// calculateOverlap calculates the overlap time between two segments in seconds
-func (bs *Shell) calculateOverlap(seg1, seg2 VocalSegment) float64 {
-	start1 := seg1.Placement
-	end1 := seg1.Placement + seg1.Duration
-	start2 := seg2.Placement
-	end2 := seg2.Placement + seg2.Duration
+// calculateOverlap calculates the overlap time between two segments in mix seconds
+func (bs *Shell) calculateOverlap(track1 int, seg1 VocalSegment, track2 int, seg2 VocalSegment) float64 {
+	start1, end1 := bs.mixSpan(track1, seg1)
+	start2, end2 := bs.mixSpan(track2, seg2)
 
 	// No overlap if one ends before the other starts
 	if end1 <= start2 || end2 <= start1 {
@@ -182,78 +186,105 @@ func min(a, b float64) float64 {
 	return b
 }
 
-// handleFoundationCommand runs steps 1-N from the PLAN.md automatically
-func (bs *Shell) handleFoundationCommand(stepArg string) {
+// handleFoundationCommand runs steps 1-N from the PLAN.md automatically. A seed is
+// passed on to the placement steps.
+func (bs *Shell) handleFoundationCommand(stepArg string, seed int64, seeded bool) {
 	maxStep, err := strconv.Atoi(stepArg)
 	if err != nil {
-		fmt.Printf("Invalid step number: %s (must be 1-5)\n", stepArg)
+		fmt.Fprintf(bs.Out, "Invalid step number: %s (must be 1-5)\n", stepArg)
 		return
 	}
 
 	if maxStep < 1 || maxStep > 20 {
-		fmt.Printf("Step number must be 1-20, got: %d\n", maxStep)
+		fmt.Fprintf(bs.Out, "Step number must be 1-20, got: %d\n", maxStep)
 		return
 	}
 
-	fmt.Printf("🚀 Running foundation steps 1-%d...\n\n", maxStep)
+	fmt.Fprintf(bs.Out, "🚀 Running foundation steps 1-%d...\n\n", maxStep)
 
 	// Step 1: analyze-segments
 	if maxStep >= 1 {
-		fmt.Printf("Step 1: Analyzing segments...\n")
+		fmt.Fprintf(bs.Out, "Step 1: Analyzing segments...\n")
 		bs.HandleAudioCommand("analyze-segments", []string{"1"})
-		fmt.Printf("\n")
+		fmt.Fprintf(bs.Out, "\n")
 	}
 
 	// Step 2: beat-detect
 	if maxStep >= 2 {
-		fmt.Printf("Step 2: Detecting beats...\n")
+		fmt.Fprintf(bs.Out, "Step 2: Detecting beats...\n")
 		bs.HandleAudioCommand("beat-detect", []string{"both"})
-		fmt.Printf("\n")
+		fmt.Fprintf(bs.Out, "\n")
 	}
 
 	// Step 3: auto-match
 	if maxStep >= 3 {
-		fmt.Printf("Step 3: Auto-matching tracks...\n")
+		fmt.Fprintf(bs.Out, "Step 3: Auto-matching tracks...\n")
 		bs.HandleMatchingCommand("auto-match", []string{})
-		fmt.Printf("\n")
+		fmt.Fprintf(bs.Out, "\n")
 	}
 
 	// Step 4: conflict-detect
 	if maxStep >= 4 {
-		fmt.Printf("Step 4: Checking for conflicts...\n")
+		fmt.Fprintf(bs.Out, "Step 4: Checking for conflicts...\n")
 		bs.handleConflictDetectCommand()
-		fmt.Printf("\n")
+		fmt.Fprintf(bs.Out, "\n")
 	}
 
 	// Step 5: segment-trim
 	if maxStep >= 5 {
-		fmt.Printf("Step 5: Auto-trimming silence from segments...\n")
+		fmt.Fprintf(bs.Out, "Step 5: Auto-trimming silence from segments...\n")
 		//bs.HandleSegmentManipulationCommand("segment-trim", []string{"1"})
-		fmt.Printf("\n")
+		fmt.Fprintf(bs.Out, "\n")
 	}
 
 	// Step 6: smart-random
 	if maxStep >= 6 {
-		fmt.Printf("Step 6: Smart-random placement with beat alignment...\n")
-		bs.HandleSegmentAdvancedCommand("smart-random", []string{"1"})
-		fmt.Printf("\n")
+		fmt.Fprintf(bs.Out, "Step 6: Smart-random placement with beat alignment...\n")
+		smartArgs := []string{"1"}
+		if seeded {
+			smartArgs = append(smartArgs, "--seed", strconv.FormatInt(seed, 10))
+		}
+		bs.HandleSegmentAdvancedCommand("smart-random", smartArgs)
+		fmt.Fprintf(bs.Out, "\n")
 	}
 
 	// Step 7: gap-finder
 	if maxStep >= 7 {
-		fmt.Printf("Step 7: Analyzing instrumental track for vocal gaps...\n")
+		fmt.Fprintf(bs.Out, "Step 7: Analyzing instrumental track for vocal gaps...\n")
 		bs.HandleAudioCommand("gap-finder", []string{"2"}) // Analyze track 2 (instrumental) for gaps
-		fmt.Printf("\n")
+		fmt.Fprintf(bs.Out, "\n")
 	}
 
 	// Step 8: quantize
 	if maxStep >= 8 {
-		fmt.Printf("Step 8: Quantizing segments to beat boundaries...\n")
+		fmt.Fprintf(bs.Out, "Step 8: Quantizing segments to beat boundaries...\n")
 		bs.HandleBeatDetectionCommand("quantize", []string{"both"})
-		fmt.Printf("\n")
+		fmt.Fprintf(bs.Out, "\n")
+	}
+
+	// Step 14: crossfade-auto
+	if maxStep >= 14 {
+		fmt.Fprintf(bs.Out, "Step 14: Enabling automatic anti-click fades...\n")
+		bs.HandleFadeCommand("crossfade-auto", []string{"on"})
+		fmt.Fprintf(bs.Out, "\n")
+	}
+
+	// Step 17: quality-score
+	if maxStep >= 17 {
+		fmt.Fprintf(bs.Out, "Step 17: Scoring the blend...\n")
+		bs.HandleScoreCommand("score", []string{})
+		fmt.Fprintf(bs.Out, "\n")
+	}
+
+	// Step 18: auto-arrange (previewing is left to 'play')
+	if maxStep >= 18 {
+		fmt.Fprintf(bs.Out, "Step 18: Auto-arranging segments...\n")
+		style, _ := lookupArrangeStyle("")
+		bs.autoArrange(style, bs.seededRand("auto-arrange "+style.Name, seed, seeded))
+		fmt.Fprintf(bs.Out, "\n")
 	}
 
-	fmt.Printf("✅ Foundation steps 1-%d complete!\n", maxStep)
-	fmt.Printf("Current status:\n")
+	fmt.Fprintf(bs.Out, "✅ Foundation steps 1-%d complete!\n", maxStep)
+	fmt.Fprintf(bs.Out, "Current status:\n")
 	bs.ShowStatus()
 }
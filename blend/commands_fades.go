@@ -0,0 +1,188 @@
+package blend
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HandleFadeCommand processes fade and automation commands (fade, automate, crossfade-auto)
+func (bs *Shell) HandleFadeCommand(cmd string, args []string) bool {
+	switch cmd {
+	case "fade":
+		bs.handleFadeCommand(args)
+
+	case "automate":
+		bs.handleAutomateCommand(args)
+
+	case "crossfade-auto":
+		bs.handleCrossfadeAutoCommand(args)
+
+	default:
+		return false // Command not handled by this module
+	}
+
+	return true
+}
+
+// handleFadeCommand sets a segment's fade lengths: fade <track:seg> <in> <out>
+func (bs *Shell) handleFadeCommand(args []string) {
+	if len(args) < 3 {
+		fmt.Fprintf(bs.Out, "Usage: fade <track:segment> <in_seconds> <out_seconds>\n")
+		fmt.Fprintf(bs.Out, "Example: fade 1:3 0.5 1.2 (0 turns a fade off)\n")
+		return
+	}
+
+	track, seg, ok := bs.lookupSegment(args[0])
+	if !ok {
+		return
+	}
+
+	fadeIn, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || fadeIn < 0 {
+		fmt.Fprintf(bs.Out, "Invalid fade-in length: %s\n", args[1])
+		return
+	}
+	fadeOut, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || fadeOut < 0 {
+		fmt.Fprintf(bs.Out, "Invalid fade-out length: %s\n", args[2])
+		return
+	}
+
+	duration := seg.Duration / bs.trackSpeed(track)
+	if fadeIn+fadeOut > duration {
+		fmt.Fprintf(bs.Out, "Fades of %.2fs + %.2fs are longer than the segment (%.2fs)\n", fadeIn, fadeOut, duration)
+		return
+	}
+
+	seg.FadeIn, seg.FadeOut = fadeIn, fadeOut
+	fmt.Fprintf(bs.Out, "Segment %s: fade in %.2fs, fade out %.2fs\n", args[0], fadeIn, fadeOut)
+}
+
+// handleAutomateCommand sets a track's volume envelope:
+// automate volume1 <time>=<value> ... | automate volume1 clear
+func (bs *Shell) handleAutomateCommand(args []string) {
+	if len(args) == 0 {
+		for track := 1; track <= 2; track++ {
+			bs.showAutomation(track)
+		}
+		fmt.Fprintf(bs.Out, "Usage: automate <volume1|volume2> <time>=<value> ... | automate <volume1|volume2> clear\n")
+		fmt.Fprintf(bs.Out, "Example: automate volume1 0=100 30=40 17.1b=100 (times follow the timebase)\n")
+		return
+	}
+
+	var track int
+	switch args[0] {
+	case "volume1":
+		track = 1
+	case "volume2":
+		track = 2
+	default:
+		fmt.Fprintf(bs.Out, "Only volume1 and volume2 can be automated\n")
+		return
+	}
+
+	if len(args) == 1 {
+		bs.showAutomation(track)
+		return
+	}
+
+	if args[1] == "clear" || args[1] == "off" {
+		bs.setTrackAutomation(track, nil)
+		fmt.Fprintf(bs.Out, "Volume automation for track %d cleared (volume %.0f%%)\n", track, bs.trackVolume(track))
+		return
+	}
+
+	var points []AutomationPoint
+	for _, arg := range args[1:] {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			fmt.Fprintf(bs.Out, "Invalid point: %s (use <time>=<value>)\n", arg)
+			return
+		}
+		at, err := bs.parseTime(track, parts[0])
+		if err != nil || at < 0 {
+			fmt.Fprintf(bs.Out, "Invalid time: %s\n", parts[0])
+			return
+		}
+		value, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			fmt.Fprintf(bs.Out, "Invalid volume: %s\n", parts[1])
+			return
+		}
+		points = append(points, AutomationPoint{Time: at, Value: clampFloat(value, 0.0, 200.0)})
+	}
+
+	sort.Slice(points, func(i, j int) bool { return points[i].Time < points[j].Time })
+	for i := 1; i < len(points); i++ {
+		if points[i].Time == points[i-1].Time {
+			fmt.Fprintf(bs.Out, "Two points at %.2fs; each time may appear once\n", points[i].Time)
+			return
+		}
+	}
+
+	bs.setTrackAutomation(track, points)
+	bs.showAutomation(track)
+}
+
+// handleCrossfadeAutoCommand toggles anti-click fades on every track and segment edge
+func (bs *Shell) handleCrossfadeAutoCommand(args []string) {
+	if len(args) == 0 {
+		bs.AutoFade = !bs.AutoFade
+	} else {
+		switch args[0] {
+		case "on":
+			bs.AutoFade = true
+		case "off":
+			bs.AutoFade = false
+		default:
+			fmt.Fprintf(bs.Out, "Usage: crossfade-auto [on|off]\n")
+			return
+		}
+	}
+
+	if bs.AutoFade {
+		fmt.Fprintf(bs.Out, "Auto anti-click fades on (%.0fms at every edge)\n", antiClickFade*1000)
+	} else {
+		fmt.Fprintf(bs.Out, "Auto anti-click fades off\n")
+	}
+}
+
+// showAutomation prints a track's volume envelope
+func (bs *Shell) showAutomation(track int) {
+	points := bs.trackAutomation(track)
+	if len(points) == 0 {
+		fmt.Fprintf(bs.Out, "Track %d volume: %.0f%% (no automation)\n", track, bs.trackVolume(track))
+		return
+	}
+
+	fmt.Fprintf(bs.Out, "Track %d volume automation:\n", track)
+	for _, p := range points {
+		fmt.Fprintf(bs.Out, "  %7.2fs (bar %s)  %.0f%%\n", p.Time, bs.formatBarBeat(track, p.Time), p.Value)
+	}
+}
+
+// trackAutomation returns a track's volume envelope, nil when it has none
+func (bs *Shell) trackAutomation(track int) []AutomationPoint {
+	if track == 2 {
+		return bs.Automation2
+	}
+	return bs.Automation1
+}
+
+func (bs *Shell) setTrackAutomation(track int, points []AutomationPoint) {
+	if track == 2 {
+		bs.Automation2 = points
+	} else {
+		bs.Automation1 = points
+	}
+}
+
+// trackVolume returns a track's static volume percentage
+func (bs *Shell) trackVolume(track int) float64 {
+	if track == 2 {
+		return bs.Volume2
+	}
+	return bs.Volume1
+}
@@ -16,21 +16,21 @@ func (bs *Shell) HandleMatchingCommand(cmd string, args []string) bool {
 		if len(args) > 0 {
 			bs.handleMatchCommand(args[0])
 		} else {
-			fmt.Printf("Usage: match <bpm1to2|bpm2to1|key1to2|key2to1>\n")
+			fmt.Fprintf(bs.Out, "Usage: match <bpm1to2|bpm2to1|key1to2|key2to1>\n")
 		}
 		
 	case "type1":
 		if len(args) > 0 {
 			bs.handleTypeCommand("1", args[0])
 		} else {
-			fmt.Printf("Usage: type1 <vocal|instrumental>\n")
+			fmt.Fprintf(bs.Out, "Usage: type1 <vocal|instrumental|drums|bass|other>[+stem...]\n")
 		}
 		
 	case "type2":
 		if len(args) > 0 {
 			bs.handleTypeCommand("2", args[0])
 		} else {
-			fmt.Printf("Usage: type2 <vocal|instrumental>\n")
+			fmt.Fprintf(bs.Out, "Usage: type2 <vocal|instrumental|drums|bass|other>[+stem...]\n")
 		}
 		
 	case "invert":
@@ -49,7 +49,7 @@ func (bs *Shell) HandleMatchingCommand(cmd string, args []string) bool {
 // handleMatchCommand handles BPM and key matching between tracks
 func (bs *Shell) handleMatchCommand(matchType string) {
 	if bs.Metadata1 == nil || bs.Metadata2 == nil {
-		fmt.Printf("Metadata not available for matching\n")
+		fmt.Fprintf(bs.Out, "Metadata not available for matching\n")
 		return
 	}
 
@@ -60,10 +60,10 @@ func (bs *Shell) handleMatchCommand(matchType string) {
 			currentBPM := *bs.Metadata1.BPM
 			tempoChange := ((targetBPM / currentBPM) - 1.0) * 100.0
 			bs.Tempo1 = clampFloat(tempoChange, -50.0, 100.0)
-			fmt.Printf("Matched track 1 BPM to track 2: %.1f -> %.1f (tempo %+.1f%%)\n", 
+			fmt.Fprintf(bs.Out, "Matched track 1 BPM to track 2: %.1f -> %.1f (tempo %+.1f%%)\n", 
 				currentBPM, targetBPM, bs.Tempo1)
 		} else {
-			fmt.Printf("BPM data not available for matching\n")
+			fmt.Fprintf(bs.Out, "BPM data not available for matching\n")
 		}
 		
 	case "bpm2to1":
@@ -72,69 +72,56 @@ func (bs *Shell) handleMatchCommand(matchType string) {
 			currentBPM := *bs.Metadata2.BPM
 			tempoChange := ((targetBPM / currentBPM) - 1.0) * 100.0
 			bs.Tempo2 = clampFloat(tempoChange, -50.0, 100.0)
-			fmt.Printf("Matched track 2 BPM to track 1: %.1f -> %.1f (tempo %+.1f%%)\n", 
+			fmt.Fprintf(bs.Out, "Matched track 2 BPM to track 1: %.1f -> %.1f (tempo %+.1f%%)\n", 
 				currentBPM, targetBPM, bs.Tempo2)
 		} else {
-			fmt.Printf("BPM data not available for matching\n")
+			fmt.Fprintf(bs.Out, "BPM data not available for matching\n")
 		}
 		
 	case "key1to2":
 		if bs.Metadata1.Key != nil && bs.Metadata2.Key != nil {
 			pitchChange := audio.CalculateKeyDifference(*bs.Metadata1.Key, *bs.Metadata2.Key)
 			bs.Pitch1 = clamp(pitchChange, -12, 12)
-			fmt.Printf("Matched track 1 key to track 2: %s -> %s (pitch %+d)\n", 
+			fmt.Fprintf(bs.Out, "Matched track 1 key to track 2: %s -> %s (pitch %+d)\n", 
 				*bs.Metadata1.Key, *bs.Metadata2.Key, bs.Pitch1)
 		} else {
-			fmt.Printf("Key data not available for matching\n")
+			fmt.Fprintf(bs.Out, "Key data not available for matching\n")
 		}
 		
 	case "key2to1":
 		if bs.Metadata1.Key != nil && bs.Metadata2.Key != nil {
 			pitchChange := audio.CalculateKeyDifference(*bs.Metadata2.Key, *bs.Metadata1.Key)
 			bs.Pitch2 = clamp(pitchChange, -12, 12)
-			fmt.Printf("Matched track 2 key to track 1: %s -> %s (pitch %+d)\n", 
+			fmt.Fprintf(bs.Out, "Matched track 2 key to track 1: %s -> %s (pitch %+d)\n", 
 				*bs.Metadata2.Key, *bs.Metadata1.Key, bs.Pitch2)
 		} else {
-			fmt.Printf("Key data not available for matching\n")
+			fmt.Fprintf(bs.Out, "Key data not available for matching\n")
 		}
 		
 	default:
-		fmt.Printf("Unknown match type: %s\n", matchType)
-		fmt.Printf("Usage: match <bpm1to2|bpm2to1|key1to2|key2to1>\n")
+		fmt.Fprintf(bs.Out, "Unknown match type: %s\n", matchType)
+		fmt.Fprintf(bs.Out, "Usage: match <bpm1to2|bpm2to1|key1to2|key2to1>\n")
 	}
 }
 
-// handleTypeCommand changes track types
+// handleTypeCommand changes track types. trackType may name several stems joined with '+'
 func (bs *Shell) handleTypeCommand(track, trackType string) {
-	switch strings.ToLower(trackType) {
-	case "vocal", "vocals", "v":
-		if track == "1" {
-			bs.Type1 = "V"
-			bs.InputPath1 = audio.GetAudioFilename(bs.ID1, "V")
-			fmt.Printf("Track 1 set to vocal\n")
-		} else {
-			bs.Type2 = "V"
-			bs.InputPath2 = audio.GetAudioFilename(bs.ID2, "V")
-			fmt.Printf("Track 2 set to vocal\n")
-		}
-	case "instrumental", "instrumentals", "i":
-		if track == "1" {
-			bs.Type1 = "I"
-			bs.InputPath1 = audio.GetAudioFilename(bs.ID1, "I")
-			fmt.Printf("Track 1 set to instrumental\n")
-		} else {
-			bs.Type2 = "I"
-			bs.InputPath2 = audio.GetAudioFilename(bs.ID2, "I")
-			fmt.Printf("Track 2 set to instrumental\n")
-		}
-	default:
-		fmt.Printf("Invalid track type: %s (use vocal or instrumental)\n", trackType)
+	trackNum := 1
+	if track == "2" {
+		trackNum = 2
 	}
+
+	if err := bs.setTrackStems(trackNum, trackType); err != nil {
+		fmt.Fprintf(bs.Out, "Invalid track type: %s (%v)\n", trackType, err)
+		return
+	}
+
+	fmt.Fprintf(bs.Out, "Track %s set to %s\n", track, bs.stemMixDescription(trackNum))
 }
 
 // handleInvertCommand intelligently matches tracks
 func (bs *Shell) handleInvertCommand() {
-	fmt.Printf("Inverting current match state...\n")
+	fmt.Fprintf(bs.Out, "Inverting current match state...\n")
 	
 	// Save current state to determine what was matched
 	stateFile := fmt.Sprintf("/tmp/starchive_invert_%s_%s.tmp", bs.ID1, bs.ID2)
@@ -223,11 +210,11 @@ func (bs *Shell) applyInvertedState() {
 // handleAutoMatchCommand intelligently determines best BPM/key matching direction
 func (bs *Shell) handleAutoMatchCommand() {
 	if bs.Metadata1 == nil || bs.Metadata2 == nil {
-		fmt.Printf("Metadata not available for auto-matching\n")
+		fmt.Fprintf(bs.Out, "Metadata not available for auto-matching\n")
 		return
 	}
 
-	fmt.Printf("Analyzing tracks for optimal matching...\n")
+	fmt.Fprintf(bs.Out, "Analyzing tracks for optimal matching...\n")
 	
 	// Reset current adjustments
 	bs.ResetAdjustments()
@@ -248,15 +235,15 @@ func (bs *Shell) handleAutoMatchCommand() {
 		
 		if diff1to2 <= diff2to1 {
 			bpmDirection = "bpm1to2"
-			fmt.Printf("  BPM: %.1f -> %.1f (ratio: %.2fx, %.1f%% change)\n", 
+			fmt.Fprintf(bs.Out, "  BPM: %.1f -> %.1f (ratio: %.2fx, %.1f%% change)\n", 
 				bpm1, bpm2, ratio1to2, (ratio1to2-1.0)*100)
 		} else {
 			bpmDirection = "bpm2to1"
-			fmt.Printf("  BPM: %.1f -> %.1f (ratio: %.2fx, %.1f%% change)\n", 
+			fmt.Fprintf(bs.Out, "  BPM: %.1f -> %.1f (ratio: %.2fx, %.1f%% change)\n", 
 				bpm2, bpm1, ratio2to1, (ratio2to1-1.0)*100)
 		}
 	} else {
-		fmt.Printf("  BPM: No BPM data available\n")
+		fmt.Fprintf(bs.Out, "  BPM: No BPM data available\n")
 	}
 	
 	// Determine key matching direction
@@ -272,13 +259,13 @@ func (bs *Shell) handleAutoMatchCommand() {
 		// Choose direction with smaller semitone adjustment
 		if abs(float64(diff1to2)) <= abs(float64(diff2to1)) {
 			keyDirection = "key1to2"
-			fmt.Printf("  Key: %s -> %s (%+d semitones)\n", key1, key2, diff1to2)
+			fmt.Fprintf(bs.Out, "  Key: %s -> %s (%+d semitones)\n", key1, key2, diff1to2)
 		} else {
 			keyDirection = "key2to1"
-			fmt.Printf("  Key: %s -> %s (%+d semitones)\n", key2, key1, diff2to1)
+			fmt.Fprintf(bs.Out, "  Key: %s -> %s (%+d semitones)\n", key2, key1, diff2to1)
 		}
 	} else {
-		fmt.Printf("  Key: No key data available\n")
+		fmt.Fprintf(bs.Out, "  Key: No key data available\n")
 	}
 	
 	// Apply the chosen matching
@@ -289,7 +276,7 @@ func (bs *Shell) handleAutoMatchCommand() {
 		bs.handleMatchCommand(keyDirection)
 	}
 	
-	fmt.Printf("Auto-match complete!\n")
+	fmt.Fprintf(bs.Out, "Auto-match complete!\n")
 }
 
 // abs returns absolute value of float64
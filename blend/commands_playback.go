@@ -5,9 +5,7 @@ import (
 	"fmt"
 	"math"
 	"os"
-	"os/exec"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -16,15 +14,20 @@ func (bs *Shell) HandlePlaybackCommand(cmd string, args []string) bool {
 	switch cmd {
 	case "play", "p":
 		if len(args) > 0 {
-			if startPos, err := strconv.ParseFloat(args[0], 64); err == nil {
+			if startPos, err := bs.parseTime(bs.gridTrack(), args[0]); err == nil {
 				bs.handlePlayCommand(startPos)
 			} else {
-				fmt.Printf("Invalid start position: %s\n", args[0])
+				fmt.Fprintf(bs.Out, "Invalid start position: %s\n", args[0])
 			}
+		} else if bs.LoopEnd > bs.LoopStart {
+			bs.handlePlayCommand(bs.LoopStart) // Start at the loop region
 		} else {
 			bs.handlePlayCommand(-1) // -1 means use default (middle)
 		}
 		
+	case "render":
+		bs.handleRenderCommand(args)
+		
 	default:
 		return false // Command not handled by this module
 	}
@@ -34,6 +37,35 @@ func (bs *Shell) HandlePlaybackCommand(cmd string, args []string) bool {
 
 // handlePlayCommand plays the blend
 func (bs *Shell) handlePlayCommand(startFrom float64) {
+	startPosition1, startPosition2, maxAvailableDuration := bs.playWindow(startFrom)
+
+	// Check for active segments
+	activeSegments1 := 0
+	activeSegments2 := 0
+	for _, seg := range bs.Segments1 {
+		if seg.Active { activeSegments1++ }
+	}
+	for _, seg := range bs.Segments2 {
+		if seg.Active { activeSegments2++ }
+	}
+
+	// Without a terminal and speakers, play renders what would have been heard
+	if bs.Headless {
+		bs.renderBlend(startPosition1, startPosition2, maxAvailableDuration)
+		return
+	}
+
+	if activeSegments1 > 0 || activeSegments2 > 0 {
+		fmt.Fprintf(bs.Out, "Playing blend with %d+%d active segments...\n", activeSegments1, activeSegments2)
+	} else {
+		fmt.Fprintf(bs.Out, "Playing blend...\n")
+	}
+	bs.playBlend(startPosition1, startPosition2, maxAvailableDuration)
+}
+
+// playWindow returns where each track starts, in its own seconds, when playing from
+// startFrom (-1 for the middle), and how many mix seconds both tracks can fill
+func (bs *Shell) playWindow(startFrom float64) (float64, float64, float64) {
 	var startPosition1, startPosition2 float64
 	
 	if startFrom < 0 {
@@ -61,93 +93,126 @@ func (bs *Shell) handlePlayCommand(startFrom float64) {
 		startPosition2 = bs.Duration2 - 1
 	}
 
-	// Calculate maximum available play duration for both tracks
-	remainingDuration1 := bs.Duration1 - startPosition1
-	remainingDuration2 := bs.Duration2 - startPosition2
+	// Calculate maximum available play duration for both tracks, in mix seconds
+	remainingDuration1 := (bs.Duration1 - startPosition1) / bs.trackSpeed(1)
+	remainingDuration2 := (bs.Duration2 - startPosition2) / bs.trackSpeed(2)
 	maxAvailableDuration := remainingDuration1
 	if remainingDuration2 < maxAvailableDuration {
 		maxAvailableDuration = remainingDuration2
 	}
 
-	// Check for active segments
-	activeSegments1 := 0
-	activeSegments2 := 0
-	for _, seg := range bs.Segments1 {
-		if seg.Active { activeSegments1++ }
-	}
-	for _, seg := range bs.Segments2 {
-		if seg.Active { activeSegments2++ }
+	return startPosition1, startPosition2, maxAvailableDuration
+}
+
+// handleRenderCommand mixes the blend to a wav file as fast as the decoders allow,
+// without playing it: render [start_pos] [seconds]
+func (bs *Shell) handleRenderCommand(args []string) {
+	startFrom := -1.0
+	if len(args) > 0 {
+		start, err := bs.parseTime(bs.gridTrack(), args[0])
+		if err != nil || start < 0 {
+			fmt.Fprintf(bs.Out, "Invalid start position: %s\n", args[0])
+			return
+		}
+		startFrom = start
+	} else if bs.LoopEnd > bs.LoopStart {
+		startFrom = bs.LoopStart
 	}
 
-	if activeSegments1 > 0 || activeSegments2 > 0 {
-		fmt.Printf("Playing blend with %d+%d active segments... Press any key to stop.\n", activeSegments1, activeSegments2)
-		bs.playBlendWithSegments(startPosition1, startPosition2, maxAvailableDuration)
-	} else {
-		fmt.Printf("Playing blend... Press any key to stop.\n")
-		bs.playBlendBasic(startPosition1, startPosition2, maxAvailableDuration)
+	startPosition1, startPosition2, maxAvailableDuration := bs.playWindow(startFrom)
+	if len(args) > 1 {
+		length, err := strconv.ParseFloat(args[1], 64)
+		if err != nil || length <= 0 {
+			fmt.Fprintf(bs.Out, "Invalid length: %s (seconds)\n", args[1])
+			return
+		}
+		maxAvailableDuration = math.Min(maxAvailableDuration, length)
+	} else if len(args) == 0 && bs.LoopEnd > bs.LoopStart {
+		maxAvailableDuration = math.Min(maxAvailableDuration, bs.LoopEnd-bs.LoopStart)
 	}
+
+	bs.renderBlend(startPosition1, startPosition2, maxAvailableDuration)
 }
 
-func (bs *Shell) playBlendBasic(startPosition1, startPosition2, maxAvailableDuration float64) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// renderBlend mixes the blend window to ./data like playBlend does, without a sink
+func (bs *Shell) renderBlend(startPosition1, startPosition2, maxAvailableDuration float64) {
+	graph := bs.blendGraph(startPosition1, startPosition2, maxAvailableDuration)
 
-	// Generate output filename with timestamp
 	outputFile := fmt.Sprintf("./data/blend_%s_%s_%d.wav", bs.ID1, bs.ID2, time.Now().Unix())
-	
-	// Start recording the mix to file
-	go bs.recordBlendBasic(ctx, startPosition1, startPosition2, maxAvailableDuration, outputFile)
-
-	ffplayArgs1 := bs.buildFFplayArgs(bs.InputPath1, startPosition1, bs.Pitch1, bs.Tempo1, bs.Volume1, maxAvailableDuration)
-	ffplayArgs2 := bs.buildFFplayArgs(bs.InputPath2, startPosition2, bs.Pitch2, bs.Tempo2, bs.Volume2, maxAvailableDuration)
-
-	go func() {
-		cmd1 := exec.CommandContext(ctx, "ffplay", ffplayArgs1...)
-		cmd1.Run()
-	}()
+	recorder, err := newWavRecorder(outputFile)
+	if err != nil {
+		fmt.Fprintf(bs.Out, "Error creating %s: %v\n", outputFile, err)
+		return
+	}
 
-	go func() {
-		cmd2 := exec.CommandContext(ctx, "ffplay", ffplayArgs2...)
-		cmd2.Run()
-	}()
+	fmt.Fprintf(bs.Out, "Rendering %.1fs of the blend...\n", maxAvailableDuration)
+	mixer, err := NewMixer(context.Background(), graph, maxAvailableDuration)
+	if err != nil {
+		recorder.Close()
+		os.Remove(outputFile)
+		fmt.Fprintf(bs.Out, "Error rendering blend: %v\n", err)
+		return
+	}
+	defer mixer.Close()
 
-	// Wait for any key press
-	go func() {
-		var input string
-		fmt.Scanf("%s", &input)
-		cancel()
-	}()
+	for {
+		samples, err := mixer.Next(4096)
+		if err != nil {
+			break
+		}
+		if err := recorder.Write(samples); err != nil {
+			fmt.Fprintf(bs.Out, "Error writing %s: %v\n", outputFile, err)
+			break
+		}
+	}
 
-	<-ctx.Done()
-	fmt.Printf("Playback stopped. Mix saved to %s\n", outputFile)
+	if err := recorder.Close(); err != nil {
+		fmt.Fprintf(bs.Out, "Error finishing %s: %v\n", outputFile, err)
+		return
+	}
+	bs.LastRender = outputFile
+	fmt.Fprintf(bs.Out, "Rendered %.1fs. Mix saved to %s\n", mixer.Position(), outputFile)
 }
 
-func (bs *Shell) playBlendWithSegments(startPosition1, startPosition2, maxAvailableDuration float64) {
+// playBlend mixes the blend in-process and sends the same samples to the audio
+// sink and to a wav recording
+func (bs *Shell) playBlend(startPosition1, startPosition2, maxAvailableDuration float64) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	graph := bs.blendGraph(startPosition1, startPosition2, maxAvailableDuration)
+
 	// Generate output filename with timestamp
 	outputFile := fmt.Sprintf("./data/blend_%s_%s_%d.wav", bs.ID1, bs.ID2, time.Now().Unix())
-	
-	// Start recording the mix to file
-	go bs.recordBlendWithSegments(ctx, startPosition1, startPosition2, maxAvailableDuration, outputFile)
-
-	// Play base tracks
-	ffplayArgs1 := bs.buildFFplayArgs(bs.InputPath1, startPosition1, bs.Pitch1, bs.Tempo1, bs.Volume1, maxAvailableDuration)
-	ffplayArgs2 := bs.buildFFplayArgs(bs.InputPath2, startPosition2, bs.Pitch2, bs.Tempo2, bs.Volume2, maxAvailableDuration)
+	recorder, err := newWavRecorder(outputFile)
+	if err != nil {
+		fmt.Fprintf(bs.Out, "Error creating %s: %v\n", outputFile, err)
+		return
+	}
 
-	go func() {
-		cmd1 := exec.CommandContext(ctx, "ffplay", ffplayArgs1...)
-		cmd1.Run()
-	}()
+	// The interactive transport needs the shell's terminal
+	if bs.rl != nil {
+		position, err := bs.runTransport(startPosition1, startPosition2, maxAvailableDuration, recorder)
+		if err != nil {
+			fmt.Fprintf(bs.Out, "Error during playback: %v\n", err)
+		}
+		if err := recorder.Close(); err != nil {
+			fmt.Fprintf(bs.Out, "Error finishing %s: %v\n", outputFile, err)
+		}
+		bs.LastRender = outputFile
+		fmt.Fprintf(bs.Out, "Playback stopped at %.1fs. Mix saved to %s\n", position, outputFile)
+		return
+	}
 
+	fmt.Fprintf(bs.Out, "Press any key to stop.\n")
+	played := make(chan float64, 1)
 	go func() {
-		cmd2 := exec.CommandContext(ctx, "ffplay", ffplayArgs2...)
-		cmd2.Run()
+		position, err := playGraph(ctx, graph, maxAvailableDuration, recorder)
+		if err != nil {
+			fmt.Fprintf(bs.Out, "Error during playback: %v\n", err)
+		}
+		played <- position
 	}()
-	
-	// Play active segments
-	bs.playActiveSegments(ctx, startPosition1, startPosition2, maxAvailableDuration)
 
 	// Wait for any key press
 	go func() {
@@ -157,152 +222,76 @@ func (bs *Shell) playBlendWithSegments(startPosition1, startPosition2, maxAvaila
 	}()
 
 	<-ctx.Done()
-	fmt.Printf("Playback stopped. Mix saved to %s\n", outputFile)
+	position := <-played
+	if err := recorder.Close(); err != nil {
+		fmt.Fprintf(bs.Out, "Error finishing %s: %v\n", outputFile, err)
+	}
+	bs.LastRender = outputFile
+	fmt.Fprintf(bs.Out, "Playback stopped at %.1fs. Mix saved to %s\n", position, outputFile)
 }
 
-// buildFFplayArgs constructs ffplay arguments with audio effects
-func (bs *Shell) buildFFplayArgs(inputPath string, startPos float64, pitch int, tempo float64, volume float64, playDuration float64) []string {
-	args := []string{
-		"-ss", fmt.Sprintf("%.1f", startPos),
-		"-t", fmt.Sprintf("%.1f", playDuration),
-		"-autoexit",
-		"-nodisp",
-		"-loglevel", "quiet",
+// playGraph mixes duration seconds of graph and plays it until the end or until ctx
+// is cancelled, also writing every sample to recorder when it is not nil. It returns
+// the position reached in seconds.
+func playGraph(ctx context.Context, graph Graph, duration float64, recorder *wavRecorder) (float64, error) {
+	mixer, err := NewMixer(ctx, graph, duration)
+	if err != nil {
+		return 0, err
 	}
+	defer mixer.Close()
 
-	if pitch != 0 || tempo != 0 || volume != 100 {
-		var filters []string
-		
-		if tempo != 0 {
-			tempoMultiplier := 1.0 + (tempo / 100.0)
-			if tempoMultiplier > 0.5 && tempoMultiplier <= 2.0 {
-				filters = append(filters, fmt.Sprintf("atempo=%.6f", tempoMultiplier))
-			}
-		}
-		
-		if pitch != 0 {
-			pitchSemitones := float64(pitch)
-			filters = append(filters, fmt.Sprintf("asetrate=44100*%.6f,aresample=44100,atempo=%.6f", 
-				math.Pow(2, pitchSemitones/12.0), 1.0/math.Pow(2, pitchSemitones/12.0)))
+	sink, sinkIn, err := startSink(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer sink.Wait()
+	defer sinkIn.Close()
+
+	for ctx.Err() == nil {
+		samples, err := mixer.Next(4096)
+		if err != nil {
+			break
 		}
-		
-		if volume != 100 {
-			volumeMultiplier := volume / 100.0
-			filters = append(filters, fmt.Sprintf("volume=%.6f", volumeMultiplier))
+		// The sink blocks while its buffer is full, which paces the mix in real time
+		if err := writeSamples(sinkIn, samples); err != nil {
+			break
 		}
-		
-		if len(filters) > 0 {
-			filter := strings.Join(filters, ",")
-			args = append(args, "-af", filter)
+		if recorder != nil {
+			if err := recorder.Write(samples); err != nil {
+				return mixer.Position(), fmt.Errorf("recording failed: %v", err)
+			}
 		}
 	}
 
-	args = append(args, inputPath)
-	return args
+	return mixer.Position(), nil
 }
 
-// playActiveSegments plays vocal segments at their designated placement times
-func (bs *Shell) playActiveSegments(ctx context.Context, startPosition1, startPosition2, maxAvailableDuration float64) {
-	
-	// Play active segments from track 1
-	for _, seg := range bs.Segments1 {
-		if !seg.Active {
-			continue
-		}
-		
-		// Check if segment should play during our playback window
-		segmentStart := seg.Placement
-		segmentEnd := seg.Placement + seg.Duration
-		playbackEnd := startPosition1 + maxAvailableDuration
-		
-		// Skip if segment is completely outside our playback window
-		if segmentEnd < startPosition1 || segmentStart > playbackEnd {
-			continue
-		}
-		
-		// Calculate delay and duration for this segment
-		var delay float64
-		var segmentDuration float64 = seg.Duration
-		
-		if segmentStart >= startPosition1 {
-			delay = segmentStart - startPosition1
-		} else {
-			// Segment started before our window, need to seek into it
-			delay = 0
-			segmentDuration = segmentEnd - startPosition1
+// blendGraph builds the mix of both tracks and every active segment in the playback
+// window. The start positions are where each track is, in its own seconds, at mix time 0.
+func (bs *Shell) blendGraph(startPosition1, startPosition2, maxAvailableDuration float64) Graph {
+	graph := Graph{Buses: []Bus{
+		bs.trackBus(1, startPosition1, maxAvailableDuration),
+		bs.trackBus(2, startPosition2, maxAvailableDuration),
+	}}
+
+	// Segments are placed on the other track's timeline
+	for track := 1; track <= 2; track++ {
+		segments, startTarget := bs.Segments1, startPosition2
+		if track == 2 {
+			segments, startTarget = bs.Segments2, startPosition1
 		}
-		
-		// Launch segment playback with delay
-		go func(segment VocalSegment, delaySeconds float64, duration float64) {
-			if delaySeconds > 0 {
-				select {
-				case <-time.After(time.Duration(delaySeconds * 1000) * time.Millisecond):
-				case <-ctx.Done():
-					return
-				}
+		for _, seg := range segments {
+			if !seg.Active {
+				continue
 			}
-			
-			segmentPath := fmt.Sprintf("%s/part_%03d.wav", bs.SegmentsDir1, segment.Index)
-			if _, err := os.Stat(segmentPath); os.IsNotExist(err) {
-				return
-			}
-			
-			cmd := exec.CommandContext(ctx, "ffplay", 
-				"-t", fmt.Sprintf("%.1f", duration),
-				"-autoexit", "-nodisp", "-loglevel", "quiet", 
-				segmentPath)
-			cmd.Run()
-		}(seg, delay, segmentDuration)
-	}
-	
-	// Play active segments from track 2
-	for _, seg := range bs.Segments2 {
-		if !seg.Active {
-			continue
-		}
-		
-		// Check if segment should play during our playback window
-		segmentStart := seg.Placement
-		segmentEnd := seg.Placement + seg.Duration
-		playbackEnd := startPosition2 + maxAvailableDuration
-		
-		// Skip if segment is completely outside our playback window
-		if segmentEnd < startPosition2 || segmentStart > playbackEnd {
-			continue
-		}
-		
-		// Calculate delay and duration for this segment
-		var delay float64
-		var segmentDuration float64 = seg.Duration
-		
-		if segmentStart >= startPosition2 {
-			delay = segmentStart - startPosition2
-		} else {
-			// Segment started before our window, need to seek into it
-			delay = 0
-			segmentDuration = segmentEnd - startPosition2
-		}
-		
-		// Launch segment playback with delay
-		go func(segment VocalSegment, delaySeconds float64, duration float64) {
-			if delaySeconds > 0 {
-				select {
-				case <-time.After(time.Duration(delaySeconds * 1000) * time.Millisecond):
-				case <-ctx.Done():
-					return
-				}
+			if _, err := os.Stat(bs.segmentPath(track, seg)); os.IsNotExist(err) {
+				continue
 			}
-			
-			segmentPath := fmt.Sprintf("%s/part_%03d.wav", bs.SegmentsDir2, segment.Index)
-			if _, err := os.Stat(segmentPath); os.IsNotExist(err) {
-				return
+			if bus, ok := bs.segmentBus(track, seg, startTarget, maxAvailableDuration); ok {
+				graph.Buses = append(graph.Buses, bus)
 			}
-			
-			cmd := exec.CommandContext(ctx, "ffplay", 
-				"-t", fmt.Sprintf("%.1f", duration),
-				"-autoexit", "-nodisp", "-loglevel", "quiet", 
-				segmentPath)
-			cmd.Run()
-		}(seg, delay, segmentDuration)
+		}
 	}
-}
\ No newline at end of file
+
+	return graph
+}
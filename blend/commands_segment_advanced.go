@@ -3,7 +3,6 @@ package blend
 import (
 	"context"
 	"fmt"
-	"math/rand"
 	"os"
 	"os/exec"
 	"strconv"
@@ -18,21 +17,31 @@ func (bs *Shell) HandleSegmentAdvancedCommand(cmd string, args []string) bool {
 		if len(args) > 0 {
 			bs.handlePreviewCommand(args[0])
 		} else {
-			fmt.Printf("Usage: preview <track:segment> (e.g. 1:3)\n")
+			fmt.Fprintf(bs.Out, "Usage: preview <track:segment> (e.g. 1:3)\n")
 		}
 		
 	case "segment-trim":
 		if len(args) > 0 {
 			bs.handleSegmentTrimCommand(args[0])
 		} else {
-			fmt.Printf("Usage: segment-trim <1|2|all>\n")
+			fmt.Fprintf(bs.Out, "Usage: segment-trim <1|2|all>\n")
 		}
 		
 	case "smart-random":
+		args, seed, seeded, err := parseSeedFlag(args)
+		if err != nil {
+			fmt.Fprintf(bs.Out, "%v\n", err)
+		} else if len(args) > 0 {
+			bs.handleSmartRandomCommand(args[0], seed, seeded)
+		} else {
+			fmt.Fprintf(bs.Out, "Usage: smart-random <1|2> [--seed N]\n")
+		}
+		
+	case "call-response":
 		if len(args) > 0 {
-			bs.handleSmartRandomCommand(args[0])
+			bs.handleCallResponseCommand(args[0])
 		} else {
-			fmt.Printf("Usage: smart-random <1|2>\n")
+			fmt.Fprintf(bs.Out, "Usage: call-response <1|2>  (place track's segments between the other track's phrases)\n")
 		}
 		
 	default:
@@ -46,7 +55,7 @@ func (bs *Shell) HandleSegmentAdvancedCommand(cmd string, args []string) bool {
 func (bs *Shell) handlePreviewCommand(segRef string) {
 	trackNum, segNum, ok := bs.parseSegmentRef(segRef)
 	if !ok {
-		fmt.Printf("Invalid segment reference: %s (use format track:segment like 1:3)\n", segRef)
+		fmt.Fprintf(bs.Out, "Invalid segment reference: %s (use format track:segment like 1:3)\n", segRef)
 		return
 	}
 	
@@ -60,17 +69,17 @@ func (bs *Shell) handlePreviewCommand(segRef string) {
 		segments = bs.Segments2
 		segmentsDir = bs.SegmentsDir2
 	default:
-		fmt.Printf("Invalid track number: %d\n", trackNum)
+		fmt.Fprintf(bs.Out, "Invalid track number: %d\n", trackNum)
 		return
 	}
 	
 	if len(segments) == 0 {
-		fmt.Printf("No segments found for track %d. Run 'split %d' first.\n", trackNum, trackNum)
+		fmt.Fprintf(bs.Out, "No segments found for track %d. Run 'split %d' first.\n", trackNum, trackNum)
 		return
 	}
 	
 	if segNum < 1 || segNum > len(segments) {
-		fmt.Printf("Segment %d not found for track %d (has %d segments)\n", segNum, trackNum, len(segments))
+		fmt.Fprintf(bs.Out, "Segment %d not found for track %d (has %d segments)\n", segNum, trackNum, len(segments))
 		return
 	}
 	
@@ -78,25 +87,35 @@ func (bs *Shell) handlePreviewCommand(segRef string) {
 	segmentPath := fmt.Sprintf("%s/part_%03d.wav", segmentsDir, segment.Index)
 	
 	if _, err := os.Stat(segmentPath); os.IsNotExist(err) {
-		fmt.Printf("Segment file not found: %s\n", segmentPath)
+		fmt.Fprintf(bs.Out, "Segment file not found: %s\n", segmentPath)
+		return
+	}
+	
+	if bs.Headless {
+		fmt.Fprintf(bs.Out, "Segment previews play on the terminal's speakers; use render instead\n")
 		return
 	}
 	
-	fmt.Printf("Previewing segment %s (%.1fs duration)...\n", segRef, segment.Duration)
-	fmt.Println("Press Ctrl+C to stop...")
+	fmt.Fprintf(bs.Out, "Previewing segment %s (%.1fs duration)...\n", segRef, segment.Duration)
+	fmt.Fprintln(bs.Out, "Press Ctrl+C to stop...")
+	
+	// Preview through the track's and segment's effects so it sounds the same as in the blend
+	playDuration := bs.segmentLength(trackNum, segment)
+	graph := Graph{Buses: []Bus{bs.segmentVoice(trackNum, segment, 0)}}
 	
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(segment.Duration*1000)*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration((playDuration+1)*1000)*time.Millisecond)
 	defer cancel()
 	
-	playCmd := exec.CommandContext(ctx, "ffplay", "-autoexit", "-nodisp", "-loglevel", "quiet", segmentPath)
-	playCmd.Run()
+	if _, err := playGraph(ctx, graph, playDuration, nil); err != nil {
+		fmt.Fprintf(bs.Out, "Error previewing segment: %v\n", err)
+	}
 	
-	fmt.Printf("Preview completed.\n")
+	fmt.Fprintf(bs.Out, "Preview completed.\n")
 }
 
 // handleSegmentTrimCommand automatically trims silence from segment edges
 func (bs *Shell) handleSegmentTrimCommand(target string) {
-	fmt.Printf("Auto-trimming silence from segment edges...\n")
+	fmt.Fprintf(bs.Out, "Auto-trimming silence from segment edges...\n")
 	
 	silenceThreshold := -40.0 // dB threshold for silence detection
 	minTrimAmount := 0.1     // Minimum trim amount in seconds
@@ -111,7 +130,7 @@ func (bs *Shell) handleSegmentTrimCommand(target string) {
 		bs.trimSegmentsForTrack(1, silenceThreshold, minTrimAmount, maxTrimAmount)
 		bs.trimSegmentsForTrack(2, silenceThreshold, minTrimAmount, maxTrimAmount)
 	default:
-		fmt.Printf("Invalid target: %s (use 1, 2, or all)\n", target)
+		fmt.Fprintf(bs.Out, "Invalid target: %s (use 1, 2, or all)\n", target)
 	}
 }
 
@@ -131,16 +150,16 @@ func (bs *Shell) trimSegmentsForTrack(trackNum int, threshold, minTrim, maxTrim
 		segmentsDir = bs.SegmentsDir2
 		id = bs.ID2
 	default:
-		fmt.Printf("Invalid track number: %d\n", trackNum)
+		fmt.Fprintf(bs.Out, "Invalid track number: %d\n", trackNum)
 		return
 	}
 	
 	if len(*segments) == 0 {
-		fmt.Printf("No segments found for track %d. Run 'split %d' first.\n", trackNum, trackNum)
+		fmt.Fprintf(bs.Out, "No segments found for track %d. Run 'split %d' first.\n", trackNum, trackNum)
 		return
 	}
 	
-	fmt.Printf("Trimming %d segments for track %d (%s)...\n", len(*segments), trackNum, id)
+	fmt.Fprintf(bs.Out, "Trimming %d segments for track %d (%s)...\n", len(*segments), trackNum, id)
 	
 	trimmedCount := 0
 	totalTimeSaved := 0.0
@@ -151,7 +170,7 @@ func (bs *Shell) trimSegmentsForTrack(trackNum int, threshold, minTrim, maxTrim
 		
 		// Check if segment file exists
 		if _, err := os.Stat(segmentPath); os.IsNotExist(err) {
-			fmt.Printf("  Segment %d: File not found, skipping\n", segment.Index)
+			fmt.Fprintf(bs.Out, "  Segment %d: File not found, skipping\n", segment.Index)
 			continue
 		}
 		
@@ -161,7 +180,7 @@ func (bs *Shell) trimSegmentsForTrack(trackNum int, threshold, minTrim, maxTrim
 		startTrim, endTrim := bs.detectSilenceAtEdges(segmentPath, threshold, maxTrim)
 		
 		if startTrim < minTrim && endTrim < minTrim {
-			fmt.Printf("  Segment %d: No significant silence detected (< %.1fs)\n", 
+			fmt.Fprintf(bs.Out, "  Segment %d: No significant silence detected (< %.1fs)\n", 
 				segment.Index, minTrim)
 			continue
 		}
@@ -169,7 +188,7 @@ func (bs *Shell) trimSegmentsForTrack(trackNum int, threshold, minTrim, maxTrim
 		// Apply trimming to segment metadata
 		newDuration := originalDuration - startTrim - endTrim
 		if newDuration < 0.5 { // Don't trim too aggressively
-			fmt.Printf("  Segment %d: Would be too short after trimming, skipping\n", segment.Index)
+			fmt.Fprintf(bs.Out, "  Segment %d: Would be too short after trimming, skipping\n", segment.Index)
 			continue
 		}
 		
@@ -180,11 +199,11 @@ func (bs *Shell) trimSegmentsForTrack(trackNum int, threshold, minTrim, maxTrim
 		timeSaved := startTrim + endTrim
 		totalTimeSaved += timeSaved
 		
-		fmt.Printf("  Segment %d: Trimmed %.2fs start + %.2fs end = %.2fs saved (%.1fs → %.1fs)\n",
+		fmt.Fprintf(bs.Out, "  Segment %d: Trimmed %.2fs start + %.2fs end = %.2fs saved (%.1fs → %.1fs)\n",
 			segment.Index, startTrim, endTrim, timeSaved, originalDuration, newDuration)
 	}
 	
-	fmt.Printf("Track %d trimming complete: %d/%d segments trimmed, %.2fs total time saved\n",
+	fmt.Fprintf(bs.Out, "Track %d trimming complete: %d/%d segments trimmed, %.2fs total time saved\n",
 		trackNum, trimmedCount, len(*segments), totalTimeSaved)
 }
 
@@ -335,8 +354,9 @@ func (bs *Shell) estimateSilenceAtEnd(filePath string, maxTrim float64) float64
 	return 0.0
 }
 
-// handleSmartRandomCommand intelligently places segments with beat alignment and collision avoidance
-func (bs *Shell) handleSmartRandomCommand(trackNum string) {
+// handleSmartRandomCommand intelligently places segments with beat alignment and collision
+// avoidance; the same seed always gives the same placements
+func (bs *Shell) handleSmartRandomCommand(trackNum string, seed int64, seeded bool) {
 	var segments *[]VocalSegment
 	var beats []float64
 	var targetDuration float64
@@ -357,23 +377,28 @@ func (bs *Shell) handleSmartRandomCommand(trackNum string) {
 		otherSegments = &bs.Segments1  // Check for collisions with track 1
 		id = bs.ID2
 	default:
-		fmt.Printf("Invalid track number: %s (use 1 or 2)\n", trackNum)
+		fmt.Fprintf(bs.Out, "Invalid track number: %s (use 1 or 2)\n", trackNum)
 		return
 	}
 	
 	if len(*segments) == 0 {
-		fmt.Printf("No segments found for track %s. Run 'split %s' first.\n", trackNum, trackNum)
+		fmt.Fprintf(bs.Out, "No segments found for track %s. Run 'split %s' first.\n", trackNum, trackNum)
 		return
 	}
 	
 	if len(beats) == 0 {
-		fmt.Printf("No beats detected for target track. Run 'beat-detect' first.\n")
+		fmt.Fprintf(bs.Out, "No beats detected for target track. Run 'beat-detect' first.\n")
 		return
 	}
 	
-	fmt.Printf("Smart-placing %d segments from track %s (%s) with beat alignment and collision avoidance...\n", 
+	fmt.Fprintf(bs.Out, "Smart-placing %d segments from track %s (%s) with beat alignment and collision avoidance...\n", 
 		len(*segments), trackNum, id)
 	
+	track := 1
+	if trackNum == "2" {
+		track = 2
+	}
+	
 	// Reset all segments to inactive first
 	for i := range *segments {
 		(*segments)[i].Active = false
@@ -390,18 +415,17 @@ func (bs *Shell) handleSmartRandomCommand(trackNum string) {
 	}
 	
 	if len(usableBeats) == 0 {
-		fmt.Printf("No usable beats found in target time range\n")
+		fmt.Fprintf(bs.Out, "No usable beats found in target time range\n")
 		return
 	}
 	
-	fmt.Printf("Found %d usable beats in %.1fs timeframe\n", len(usableBeats), maxTime)
+	fmt.Fprintf(bs.Out, "Found %d usable beats in %.1fs timeframe\n", len(usableBeats), maxTime)
 	
 	// Smart placement algorithm
 	placedCount := 0
 	maxAttempts := len(*segments) * 10 // Allow multiple attempts per segment
 	
-	// Seed random generator
-	rand.Seed(time.Now().UnixNano())
+	rng := bs.seededRand("smart-random "+trackNum, seed, seeded)
 	
 	// Try to place each segment
 	for i := range *segments {
@@ -412,11 +436,11 @@ func (bs *Shell) handleSmartRandomCommand(trackNum string) {
 		// Try to find a good placement for this segment
 		for attempts < maxAttempts && !placed {
 			// Pick a random beat position
-			beatIdx := rand.Intn(len(usableBeats))
+			beatIdx := rng.Intn(len(usableBeats))
 			candidateTime := usableBeats[beatIdx]
 			
 			// Check if this placement would cause conflicts
-			if bs.wouldCauseConflict(segment, candidateTime, segments, otherSegments) {
+			if bs.wouldCauseConflict(track, segment, candidateTime, segments, otherSegments) {
 				attempts++
 				continue
 			}
@@ -427,31 +451,32 @@ func (bs *Shell) handleSmartRandomCommand(trackNum string) {
 			placed = true
 			placedCount++
 			
-			fmt.Printf("  %s:%d placed at beat %.1fs (beat %d/%d)\n", 
+			fmt.Fprintf(bs.Out, "  %s:%d placed at beat %.1fs (beat %d/%d)\n", 
 				trackNum, segment.Index, candidateTime, beatIdx+1, len(usableBeats))
 		}
 		
 		if !placed {
-			fmt.Printf("  %s:%d could not be placed without conflicts (tried %d positions)\n", 
+			fmt.Fprintf(bs.Out, "  %s:%d could not be placed without conflicts (tried %d positions)\n", 
 				trackNum, segment.Index, attempts)
 		}
 	}
 	
-	fmt.Printf("Smart-random placement complete: %d/%d segments placed successfully\n", 
+	fmt.Fprintf(bs.Out, "Smart-random placement complete: %d/%d segments placed successfully\n", 
 		placedCount, len(*segments))
 	
 	// Show collision summary
 	if placedCount < len(*segments) {
-		fmt.Printf("💡 Tip: Use 'gap-finder' to find better placement opportunities\n")
+		fmt.Fprintf(bs.Out, "💡 Tip: Use 'gap-finder' to find better placement opportunities\n")
 	}
 }
 
-// wouldCauseConflict checks if placing a segment at a given time would cause conflicts
-func (bs *Shell) wouldCauseConflict(segment *VocalSegment, placementTime float64, 
+// wouldCauseConflict checks if placing a segment from track at a given time would cause
+// conflicts. Placements on either track are compared in mix seconds at the current tempos.
+func (bs *Shell) wouldCauseConflict(track int, segment *VocalSegment, placementTime float64, 
 	sameTrackSegments, otherTrackSegments *[]VocalSegment) bool {
 	
-	segmentStart := placementTime
-	segmentEnd := placementTime + segment.Duration
+	segmentStart := bs.mixTime(otherTrack(track), placementTime)
+	segmentEnd := segmentStart + bs.segmentLength(track, *segment)
 	
 	// Check conflicts with other segments on the same track
 	for _, otherSeg := range *sameTrackSegments {
@@ -459,8 +484,7 @@ func (bs *Shell) wouldCauseConflict(segment *VocalSegment, placementTime float64
 			continue
 		}
 		
-		otherStart := otherSeg.Placement
-		otherEnd := otherSeg.Placement + otherSeg.Duration
+		otherStart, otherEnd := bs.mixSpan(track, otherSeg)
 		
 		// Check for overlap
 		if !(segmentEnd <= otherStart || segmentStart >= otherEnd) {
@@ -469,14 +493,13 @@ func (bs *Shell) wouldCauseConflict(segment *VocalSegment, placementTime float64
 	}
 	
 	// Check conflicts with segments on the other track (if both are vocal tracks)
-	if bs.Type1 == "V" && bs.Type2 == "V" {
+	if bs.isVocalTrack(1) && bs.isVocalTrack(2) {
 		for _, otherSeg := range *otherTrackSegments {
 			if !otherSeg.Active {
 				continue
 			}
 			
-			otherStart := otherSeg.Placement
-			otherEnd := otherSeg.Placement + otherSeg.Duration
+			otherStart, otherEnd := bs.mixSpan(otherTrack(track), otherSeg)
 			
 			// Check for overlap (more strict for vocal-vocal conflicts)
 			minGap := 0.5 // Require at least 0.5s gap between vocals
@@ -2,20 +2,21 @@ package blend
 
 import (
 	"fmt"
-	"math/rand"
 	"strconv"
 	"strings"
-	"time"
 )
 
 // HandleSegmentBasicCommand processes basic segment manipulation commands
 func (bs *Shell) HandleSegmentBasicCommand(cmd string, args []string) bool {
 	switch cmd {
 	case "random":
-		if len(args) > 0 {
-			bs.handleRandomCommand(args[0])
+		args, seed, seeded, err := parseSeedFlag(args)
+		if err != nil {
+			fmt.Fprintf(bs.Out, "%v\n", err)
+		} else if len(args) > 0 {
+			bs.handleRandomCommand(args[0], seed, seeded)
 		} else {
-			fmt.Printf("Usage: random <1|2>\n")
+			fmt.Fprintf(bs.Out, "Usage: random <1|2> [--seed N]\n")
 		}
 		
 	case "place":
@@ -28,7 +29,7 @@ func (bs *Shell) HandleSegmentBasicCommand(cmd string, args []string) bool {
 		if len(args) > 0 {
 			bs.handleToggleCommand(args[0])
 		} else {
-			fmt.Printf("Usage: toggle <track:segment> (e.g. 1:3)\n")
+			fmt.Fprintf(bs.Out, "Usage: toggle <track:segment> (e.g. 1:3)\n")
 		}
 		
 	default:
@@ -38,8 +39,9 @@ func (bs *Shell) HandleSegmentBasicCommand(cmd string, args []string) bool {
 	return true
 }
 
-// handleRandomCommand randomly places segments from a track
-func (bs *Shell) handleRandomCommand(trackNum string) {
+// handleRandomCommand randomly places segments from a track; the same seed always
+// gives the same placements
+func (bs *Shell) handleRandomCommand(trackNum string, seed int64, seeded bool) {
 	var segments *[]VocalSegment
 	var targetDuration float64
 	var id string
@@ -54,40 +56,40 @@ func (bs *Shell) handleRandomCommand(trackNum string) {
 		targetDuration = bs.Duration1  // Place track 2 segments across track 1
 		id = bs.ID2
 	default:
-		fmt.Printf("Invalid track number: %s (use 1 or 2)\n", trackNum)
+		fmt.Fprintf(bs.Out, "Invalid track number: %s (use 1 or 2)\n", trackNum)
 		return
 	}
 	
 	if len(*segments) == 0 {
-		fmt.Printf("No segments found for track %s. Run 'split %s' first.\n", trackNum, trackNum)
+		fmt.Fprintf(bs.Out, "No segments found for track %s. Run 'split %s' first.\n", trackNum, trackNum)
 		return
 	}
 	
-	fmt.Printf("Randomly placing %d segments from track %s (%s) across %.1fs...\n", 
+	fmt.Fprintf(bs.Out, "Randomly placing %d segments from track %s (%s) across %.1fs...\n", 
 		len(*segments), trackNum, id, targetDuration)
 	
 	// Generate random placements, ensuring no overlaps
-	rand.Seed(time.Now().UnixNano())
+	rng := bs.seededRand("random "+trackNum, seed, seeded)
 	
 	for i := range *segments {
 		// Place randomly in first 80% of target track to avoid cutting off
 		maxPlacement := targetDuration * 0.8
-		placement := rand.Float64() * maxPlacement
+		placement := rng.Float64() * maxPlacement
 		
 		(*segments)[i].Placement = placement
 		(*segments)[i].Active = true
 		
-		fmt.Printf("  %s:%d placed at %.1fs\n", trackNum, (*segments)[i].Index, placement)
+		fmt.Fprintf(bs.Out, "  %s:%d placed at %.1fs\n", trackNum, (*segments)[i].Index, placement)
 	}
 	
-	fmt.Printf("Random placement completed for track %s\n", trackNum)
+	fmt.Fprintf(bs.Out, "Random placement completed for track %s\n", trackNum)
 }
 
 // handlePlaceCommand places a segment at a specific time
 func (bs *Shell) handlePlaceCommand(args []string) {
 	if len(args) < 3 || args[1] != "at" {
-		fmt.Printf("Usage: place <track:segment> at <time>\n")
-		fmt.Printf("Example: place 1:3 at 45.2\n")
+		fmt.Fprintf(bs.Out, "Usage: place <track:segment> at <time>\n")
+		fmt.Fprintf(bs.Out, "Example: place 1:3 at 45.2s (seconds) or place 1:3 at 17.1b (bar 17, beat 1)\n")
 		return
 	}
 	
@@ -96,13 +98,14 @@ func (bs *Shell) handlePlaceCommand(args []string) {
 	
 	trackNum, segNum, ok := bs.parseSegmentRef(segmentRef)
 	if !ok {
-		fmt.Printf("Invalid segment reference: %s (use format track:segment, e.g., 1:3)\n", segmentRef)
+		fmt.Fprintf(bs.Out, "Invalid segment reference: %s (use format track:segment, e.g., 1:3)\n", segmentRef)
 		return
 	}
 	
-	placement, err := strconv.ParseFloat(timeStr, 64)
+	// Positions are read on the grid of the track the segment is placed onto
+	placement, err := bs.parseTime(otherTrack(trackNum), timeStr)
 	if err != nil {
-		fmt.Printf("Invalid time: %s\n", timeStr)
+		fmt.Fprintf(bs.Out, "Invalid time: %s\n", timeStr)
 		return
 	}
 	
@@ -113,17 +116,17 @@ func (bs *Shell) handlePlaceCommand(args []string) {
 	case 2:
 		segments = &bs.Segments2
 	default:
-		fmt.Printf("Invalid track number: %d\n", trackNum)
+		fmt.Fprintf(bs.Out, "Invalid track number: %d\n", trackNum)
 		return
 	}
 	
 	if len(*segments) == 0 {
-		fmt.Printf("No segments found for track %d. Run 'split %d' first.\n", trackNum, trackNum)
+		fmt.Fprintf(bs.Out, "No segments found for track %d. Run 'split %d' first.\n", trackNum, trackNum)
 		return
 	}
 	
 	if segNum < 1 || segNum > len(*segments) {
-		fmt.Printf("Segment %d not found. Track %d has %d segments.\n", segNum, trackNum, len(*segments))
+		fmt.Fprintf(bs.Out, "Segment %d not found. Track %d has %d segments.\n", segNum, trackNum, len(*segments))
 		return
 	}
 	
@@ -132,15 +135,17 @@ func (bs *Shell) handlePlaceCommand(args []string) {
 	segment.Placement = placement
 	segment.Active = true // Placing a segment activates it
 	
-	fmt.Printf("Segment %d:%d placed at %.2fs and activated\n", trackNum, segNum, placement)
+	fmt.Fprintf(bs.Out, "Segment %d:%d placed at %.2fs (bar %s) and activated\n",
+		trackNum, segNum, placement, bs.formatBarBeat(otherTrack(trackNum), placement))
 }
 
 // handleShiftCommand shifts a segment timing
 func (bs *Shell) handleShiftCommand(args []string) {
 	if len(args) < 2 {
-		fmt.Printf("Usage: shift <track:segment> <+/-time>\n")
-		fmt.Printf("Example: shift 1:3 +2.5 (shift forward by 2.5 seconds)\n")
-		fmt.Printf("Example: shift 1:3 -1.0 (shift backward by 1.0 seconds)\n")
+		fmt.Fprintf(bs.Out, "Usage: shift <track:segment> <+/-time>\n")
+		fmt.Fprintf(bs.Out, "Example: shift 1:3 +2.5 (shift forward by 2.5 seconds)\n")
+		fmt.Fprintf(bs.Out, "Example: shift 1:3 -1.0 (shift backward by 1.0 seconds)\n")
+		fmt.Fprintf(bs.Out, "Example: shift 1:3 +2b (shift forward by 2 beats)\n")
 		return
 	}
 	
@@ -149,13 +154,7 @@ func (bs *Shell) handleShiftCommand(args []string) {
 	
 	trackNum, segNum, ok := bs.parseSegmentRef(segmentRef)
 	if !ok {
-		fmt.Printf("Invalid segment reference: %s (use format track:segment, e.g., 1:3)\n", segmentRef)
-		return
-	}
-	
-	shift, err := strconv.ParseFloat(shiftStr, 64)
-	if err != nil {
-		fmt.Printf("Invalid shift amount: %s\n", shiftStr)
+		fmt.Fprintf(bs.Out, "Invalid segment reference: %s (use format track:segment, e.g., 1:3)\n", segmentRef)
 		return
 	}
 	
@@ -166,23 +165,29 @@ func (bs *Shell) handleShiftCommand(args []string) {
 	case 2:
 		segments = &bs.Segments2
 	default:
-		fmt.Printf("Invalid track number: %d\n", trackNum)
+		fmt.Fprintf(bs.Out, "Invalid track number: %d\n", trackNum)
 		return
 	}
 	
 	if len(*segments) == 0 {
-		fmt.Printf("No segments found for track %d. Run 'split %d' first.\n", trackNum, trackNum)
+		fmt.Fprintf(bs.Out, "No segments found for track %d. Run 'split %d' first.\n", trackNum, trackNum)
 		return
 	}
 	
 	if segNum < 1 || segNum > len(*segments) {
-		fmt.Printf("Segment %d not found. Track %d has %d segments.\n", segNum, trackNum, len(*segments))
+		fmt.Fprintf(bs.Out, "Segment %d not found. Track %d has %d segments.\n", segNum, trackNum, len(*segments))
 		return
 	}
 	
 	// Update segment placement
 	segment := &(*segments)[segNum-1] // Convert 1-based to 0-based index
 	oldPlacement := segment.Placement
+	
+	shift, err := bs.parseShift(otherTrack(trackNum), oldPlacement, shiftStr)
+	if err != nil {
+		fmt.Fprintf(bs.Out, "Invalid shift amount: %s\n", shiftStr)
+		return
+	}
 	segment.Placement += shift
 	
 	// Prevent negative placement
@@ -190,7 +195,7 @@ func (bs *Shell) handleShiftCommand(args []string) {
 		segment.Placement = 0
 	}
 	
-	fmt.Printf("Segment %d:%d shifted from %.2fs to %.2fs (%+.2fs)\n", 
+	fmt.Fprintf(bs.Out, "Segment %d:%d shifted from %.2fs to %.2fs (%+.2fs)\n", 
 		trackNum, segNum, oldPlacement, segment.Placement, shift)
 }
 
@@ -198,7 +203,7 @@ func (bs *Shell) handleShiftCommand(args []string) {
 func (bs *Shell) handleToggleCommand(segmentRef string) {
 	trackNum, segNum, ok := bs.parseSegmentRef(segmentRef)
 	if !ok {
-		fmt.Printf("Invalid segment reference: %s (use format track:segment, e.g., 1:3)\n", segmentRef)
+		fmt.Fprintf(bs.Out, "Invalid segment reference: %s (use format track:segment, e.g., 1:3)\n", segmentRef)
 		return
 	}
 	
@@ -209,17 +214,17 @@ func (bs *Shell) handleToggleCommand(segmentRef string) {
 	case 2:
 		segments = &bs.Segments2
 	default:
-		fmt.Printf("Invalid track number: %d\n", trackNum)
+		fmt.Fprintf(bs.Out, "Invalid track number: %d\n", trackNum)
 		return
 	}
 	
 	if len(*segments) == 0 {
-		fmt.Printf("No segments found for track %d. Run 'split %d' first.\n", trackNum, trackNum)
+		fmt.Fprintf(bs.Out, "No segments found for track %d. Run 'split %d' first.\n", trackNum, trackNum)
 		return
 	}
 	
 	if segNum < 1 || segNum > len(*segments) {
-		fmt.Printf("Segment %d not found. Track %d has %d segments.\n", segNum, trackNum, len(*segments))
+		fmt.Fprintf(bs.Out, "Segment %d not found. Track %d has %d segments.\n", segNum, trackNum, len(*segments))
 		return
 	}
 	
@@ -232,7 +237,7 @@ func (bs *Shell) handleToggleCommand(segmentRef string) {
 		status = "active"
 	}
 	
-	fmt.Printf("Segment %d:%d is now %s\n", trackNum, segNum, status)
+	fmt.Fprintf(bs.Out, "Segment %d:%d is now %s\n", trackNum, segNum, status)
 }
 
 // parseSegmentRef parses segment references like "1:3" 
@@ -253,4 +258,30 @@ func (bs *Shell) parseSegmentRef(segRef string) (int, int, bool) {
 	}
 	
 	return trackNum, segNum, true
-}
\ No newline at end of file
+}
+
+// lookupSegment resolves a track:segment reference, printing the problem if it can't
+func (bs *Shell) lookupSegment(segRef string) (int, *VocalSegment, bool) {
+	trackNum, segNum, ok := bs.parseSegmentRef(segRef)
+	if !ok {
+		fmt.Fprintf(bs.Out, "Invalid segment reference: %s (use format track:segment, e.g., 1:3)\n", segRef)
+		return 0, nil, false
+	}
+	
+	segments := &bs.Segments1
+	if trackNum == 2 {
+		segments = &bs.Segments2
+	}
+	
+	if len(*segments) == 0 {
+		fmt.Fprintf(bs.Out, "No segments found for track %d. Run 'split %d' first.\n", trackNum, trackNum)
+		return 0, nil, false
+	}
+	
+	if segNum > len(*segments) {
+		fmt.Fprintf(bs.Out, "Segment %d not found. Track %d has %d segments.\n", segNum, trackNum, len(*segments))
+		return 0, nil, false
+	}
+	
+	return trackNum, &(*segments)[segNum-1], true
+}
@@ -19,7 +19,7 @@ func (bs *Shell) HandleSegmentCreationCommand(cmd string, args []string) bool {
 		if len(args) > 0 {
 			bs.handleSplitCommand(args[0])
 		} else {
-			fmt.Printf("Usage: split <1|2>\n")
+			fmt.Fprintf(bs.Out, "Usage: split <1|2>\n")
 		}
 		
 	case "segments":
@@ -33,7 +33,7 @@ func (bs *Shell) HandleSegmentCreationCommand(cmd string, args []string) bool {
 		if len(args) > 0 {
 			bs.handleAnalyzeSegmentsCommand(args[0])
 		} else {
-			fmt.Printf("Usage: analyze-segments <1|2>\n")
+			fmt.Fprintf(bs.Out, "Usage: analyze-segments <1|2>\n")
 		}
 		
 	default:
@@ -61,25 +61,30 @@ func (bs *Shell) handleSplitCommand(trackNum string) {
 		segments = &bs.Segments2
 		segmentsDir = bs.SegmentsDir2
 	default:
-		fmt.Printf("Invalid track number: %s (use 1 or 2)\n", trackNum)
+		fmt.Fprintf(bs.Out, "Invalid track number: %s (use 1 or 2)\n", trackNum)
 		return
 	}
 	
-	// Only split vocal tracks
-	trackType := bs.Type1
+	// Only split vocal tracks, always from the vocal stem
+	trackIndex := 1
 	if trackNum == "2" {
-		trackType = bs.Type2
+		trackIndex = 2
 	}
-	if trackType != "V" {
-		fmt.Printf("Track %s is not vocal type. Switch to vocal first using 'type%s vocal'\n", trackNum, trackNum)
+	if !bs.isVocalTrack(trackIndex) {
+		fmt.Fprintf(bs.Out, "Track %s is not vocal type. Switch to vocal first using 'type%s vocal'\n", trackNum, trackNum)
 		return
 	}
+	for _, ch := range bs.trackStems(trackIndex) {
+		if ch.Name == audio.StemVocals {
+			inputPath = ch.Path
+		}
+	}
 	
-	fmt.Printf("Splitting track %s (%s) into vocal segments...\n", trackNum, id)
+	fmt.Fprintf(bs.Out, "Splitting track %s (%s) into vocal segments...\n", trackNum, id)
 	
 	err := os.MkdirAll(segmentsDir, 0755)
 	if err != nil {
-		fmt.Printf("Error creating segments directory: %v\n", err)
+		fmt.Fprintf(bs.Out, "Error creating segments directory: %v\n", err)
 		return
 	}
 	
@@ -89,7 +94,7 @@ func (bs *Shell) handleSplitCommand(trackNum string) {
 	
 	silenceOutput, err := silenceCmd.CombinedOutput()
 	if err != nil {
-		fmt.Printf("Error detecting silence: %v\n", err)
+		fmt.Fprintf(bs.Out, "Error detecting silence: %v\n", err)
 		return
 	}
 	
@@ -98,14 +103,14 @@ func (bs *Shell) handleSplitCommand(trackNum string) {
 	sedCmd.Stdin = strings.NewReader(string(silenceOutput))
 	sedOutput, err := sedCmd.Output()
 	if err != nil {
-		fmt.Printf("Error extracting timestamps: %v\n", err)
+		fmt.Fprintf(bs.Out, "Error extracting timestamps: %v\n", err)
 		return
 	}
 	
 	timestamps := strings.TrimSpace(string(sedOutput))
 	timestamps = strings.ReplaceAll(timestamps, "\n", ",")
 	if timestamps == "" {
-		fmt.Printf("No silence detected in track %s\n", trackNum)
+		fmt.Fprintf(bs.Out, "No silence detected in track %s\n", trackNum)
 		return
 	}
 	
@@ -116,20 +121,20 @@ func (bs *Shell) handleSplitCommand(trackNum string) {
 	
 	err = splitCmd.Run()
 	if err != nil {
-		fmt.Printf("Error splitting file: %v\n", err)
+		fmt.Fprintf(bs.Out, "Error splitting file: %v\n", err)
 		return
 	}
 	
 	// Analyze created segments
 	bs.loadSegments(trackNum)
-	fmt.Printf("Successfully split track %s into %d segments\n", trackNum, len(*segments))
+	fmt.Fprintf(bs.Out, "Successfully split track %s into %d segments\n", trackNum, len(*segments))
 }
 
 // handleSegmentsCommand lists available segments for a track
 func (bs *Shell) handleSegmentsCommand(track string) {
 	if track == "" {
 		// List segments for both tracks
-		fmt.Printf("Track 1 segments: %d total\n", len(bs.Segments1))
+		fmt.Fprintf(bs.Out, "Track 1 segments: %d total\n", len(bs.Segments1))
 		for i, seg := range bs.Segments1 {
 			status := "inactive"
 			if seg.Active {
@@ -140,9 +145,12 @@ func (bs *Shell) handleSegmentsCommand(track string) {
 			if seg.EnergyCategory != "" {
 				energyInfo = fmt.Sprintf(" [%s energy: %.3f RMS]", seg.EnergyCategory, seg.RMSEnergy)
 			}
-			fmt.Printf("  1:%d - %.2fs to %.2fs (%s)%s\n", i+1, seg.StartTime, endTime, status, energyInfo)
+			if len(seg.Effects) > 0 {
+				energyInfo += " {" + describeSegmentEffects(seg.Effects) + "}"
+			}
+			fmt.Fprintf(bs.Out, "  1:%d - %.2fs to %.2fs (%s)%s\n", i+1, seg.StartTime, endTime, status, energyInfo)
 		}
-		fmt.Printf("Track 2 segments: %d total\n", len(bs.Segments2))
+		fmt.Fprintf(bs.Out, "Track 2 segments: %d total\n", len(bs.Segments2))
 		for i, seg := range bs.Segments2 {
 			status := "inactive"
 			if seg.Active {
@@ -153,10 +161,13 @@ func (bs *Shell) handleSegmentsCommand(track string) {
 			if seg.EnergyCategory != "" {
 				energyInfo = fmt.Sprintf(" [%s energy: %.3f RMS]", seg.EnergyCategory, seg.RMSEnergy)
 			}
-			fmt.Printf("  2:%d - %.2fs to %.2fs (%s)%s\n", i+1, seg.StartTime, endTime, status, energyInfo)
+			if len(seg.Effects) > 0 {
+				energyInfo += " {" + describeSegmentEffects(seg.Effects) + "}"
+			}
+			fmt.Fprintf(bs.Out, "  2:%d - %.2fs to %.2fs (%s)%s\n", i+1, seg.StartTime, endTime, status, energyInfo)
 		}
 	} else if track == "1" {
-		fmt.Printf("Track 1 segments: %d total\n", len(bs.Segments1))
+		fmt.Fprintf(bs.Out, "Track 1 segments: %d total\n", len(bs.Segments1))
 		for i, seg := range bs.Segments1 {
 			status := "inactive"
 			if seg.Active {
@@ -167,10 +178,13 @@ func (bs *Shell) handleSegmentsCommand(track string) {
 			if seg.EnergyCategory != "" {
 				energyInfo = fmt.Sprintf(" [%s energy: %.3f RMS]", seg.EnergyCategory, seg.RMSEnergy)
 			}
-			fmt.Printf("  1:%d - %.2fs to %.2fs (%s)%s\n", i+1, seg.StartTime, endTime, status, energyInfo)
+			if len(seg.Effects) > 0 {
+				energyInfo += " {" + describeSegmentEffects(seg.Effects) + "}"
+			}
+			fmt.Fprintf(bs.Out, "  1:%d - %.2fs to %.2fs (%s)%s\n", i+1, seg.StartTime, endTime, status, energyInfo)
 		}
 	} else if track == "2" {
-		fmt.Printf("Track 2 segments: %d total\n", len(bs.Segments2))
+		fmt.Fprintf(bs.Out, "Track 2 segments: %d total\n", len(bs.Segments2))
 		for i, seg := range bs.Segments2 {
 			status := "inactive"
 			if seg.Active {
@@ -181,10 +195,13 @@ func (bs *Shell) handleSegmentsCommand(track string) {
 			if seg.EnergyCategory != "" {
 				energyInfo = fmt.Sprintf(" [%s energy: %.3f RMS]", seg.EnergyCategory, seg.RMSEnergy)
 			}
-			fmt.Printf("  2:%d - %.2fs to %.2fs (%s)%s\n", i+1, seg.StartTime, endTime, status, energyInfo)
+			if len(seg.Effects) > 0 {
+				energyInfo += " {" + describeSegmentEffects(seg.Effects) + "}"
+			}
+			fmt.Fprintf(bs.Out, "  2:%d - %.2fs to %.2fs (%s)%s\n", i+1, seg.StartTime, endTime, status, energyInfo)
 		}
 	} else {
-		fmt.Printf("Invalid track: %s (use 1 or 2)\n", track)
+		fmt.Fprintf(bs.Out, "Invalid track: %s (use 1 or 2)\n", track)
 	}
 }
 
@@ -252,16 +269,16 @@ func (bs *Shell) handleAnalyzeSegmentsCommand(trackNum string) {
 		segmentsDir = bs.SegmentsDir2
 		id = bs.ID2
 	default:
-		fmt.Printf("Invalid track number: %s (use 1 or 2)\n", trackNum)
+		fmt.Fprintf(bs.Out, "Invalid track number: %s (use 1 or 2)\n", trackNum)
 		return
 	}
 	
 	if len(*segments) == 0 {
-		fmt.Printf("No segments found for track %s. Run 'split %s' first.\n", trackNum, trackNum)
+		fmt.Fprintf(bs.Out, "No segments found for track %s. Run 'split %s' first.\n", trackNum, trackNum)
 		return
 	}
 	
-	fmt.Printf("Analyzing energy levels for %d segments in track %s (%s)...\n", len(*segments), trackNum, id)
+	fmt.Fprintf(bs.Out, "Analyzing energy levels for %d segments in track %s (%s)...\n", len(*segments), trackNum, id)
 	
 	// Collect all RMS and peak values to determine thresholds
 	var rmsValues, peakValues []float64
@@ -271,14 +288,14 @@ func (bs *Shell) handleAnalyzeSegmentsCommand(trackNum string) {
 		segmentPath := fmt.Sprintf("%s/part_%03d.wav", segmentsDir, segment.Index)
 		
 		if _, err := os.Stat(segmentPath); os.IsNotExist(err) {
-			fmt.Printf("  Segment %d: file not found\n", segment.Index)
+			fmt.Fprintf(bs.Out, "  Segment %d: file not found\n", segment.Index)
 			continue
 		}
 		
 		// Use ffprobe to get audio statistics
 		rms, peak, err := bs.getAudioStatistics(segmentPath)
 		if err != nil {
-			fmt.Printf("  Segment %d: analysis failed - %v\n", segment.Index, err)
+			fmt.Fprintf(bs.Out, "  Segment %d: analysis failed - %v\n", segment.Index, err)
 			continue
 		}
 		
@@ -287,7 +304,7 @@ func (bs *Shell) handleAnalyzeSegmentsCommand(trackNum string) {
 		rmsValues = append(rmsValues, rms)
 		peakValues = append(peakValues, peak)
 		
-		fmt.Printf("  Segment %d: RMS=%.3f, Peak=%.3f\n", segment.Index, rms, peak)
+		fmt.Fprintf(bs.Out, "  Segment %d: RMS=%.3f, Peak=%.3f\n", segment.Index, rms, peak)
 	}
 	
 	// Calculate thresholds for categorization (tertiles)
@@ -319,7 +336,7 @@ func (bs *Shell) handleAnalyzeSegmentsCommand(trackNum string) {
 			}
 		}
 		
-		fmt.Printf("Energy analysis complete: %d low, %d medium, %d high energy segments\n", low, medium, high)
+		fmt.Fprintf(bs.Out, "Energy analysis complete: %d low, %d medium, %d high energy segments\n", low, medium, high)
 	}
 }
 
@@ -0,0 +1,307 @@
+package blend
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"starchive/audio"
+)
+
+// Segment effect types
+const (
+	EffectReverse = "reverse"
+	EffectStutter = "stutter"
+	EffectChop    = "chop"
+	EffectEcho    = "echo"
+	EffectHarmony = "harmony"
+)
+
+// harmonyLayerVolume is the level of each harmony layer relative to the lead
+const harmonyLayerVolume = 60.0
+
+// HandleSegmentEffectCommand processes creative segment effect commands
+// (reverse, stutter, vocal-chop, echo-place, harmony-stack, effects)
+func (bs *Shell) HandleSegmentEffectCommand(cmd string, args []string) bool {
+	switch cmd {
+	case "reverse", "stutter", "vocal-chop", "echo-place", "harmony-stack", "effects":
+	default:
+		return false // Command not handled by this module
+	}
+
+	if len(args) == 0 {
+		bs.showSegmentEffectUsage(cmd)
+		return true
+	}
+
+	segRef := args[0]
+	track, seg, ok := bs.lookupSegment(segRef)
+	if !ok {
+		return true
+	}
+	args = args[1:]
+
+	switch cmd {
+	case "reverse":
+		if hasSegmentEffect(seg, EffectReverse) {
+			removeSegmentEffect(seg, EffectReverse)
+		} else {
+			setSegmentEffect(seg, SegmentEffect{Type: EffectReverse})
+		}
+
+	case "stutter":
+		if len(args) == 0 {
+			bs.showSegmentEffectUsage(cmd)
+			return true
+		}
+		repeats, err := strconv.Atoi(args[0])
+		if err != nil || repeats < 0 || repeats > 16 {
+			fmt.Fprintf(bs.Out, "Invalid repeat count: %s (0-16, 0 or 1 removes the stutter)\n", args[0])
+			return true
+		}
+		if repeats < 2 {
+			removeSegmentEffect(seg, EffectStutter)
+		} else {
+			setSegmentEffect(seg, SegmentEffect{Type: EffectStutter, Count: repeats})
+		}
+
+	case "vocal-chop":
+		if len(args) == 0 {
+			bs.showSegmentEffectUsage(cmd)
+			return true
+		}
+		if args[0] == "off" {
+			removeSegmentEffect(seg, EffectChop)
+			break
+		}
+		division, err := parseNoteValue(args[0])
+		if err != nil {
+			fmt.Fprintf(bs.Out, "%v\n", err)
+			return true
+		}
+		setSegmentEffect(seg, SegmentEffect{Type: EffectChop, Division: division})
+
+	case "echo-place":
+		copies := 3
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 0 || n > 8 {
+				fmt.Fprintf(bs.Out, "Invalid echo count: %s (0-8, 0 removes the echo)\n", args[0])
+				return true
+			}
+			copies = n
+		}
+		if copies == 0 {
+			removeSegmentEffect(seg, EffectEcho)
+		} else {
+			setSegmentEffect(seg, SegmentEffect{Type: EffectEcho, Count: copies})
+		}
+
+	case "harmony-stack":
+		if len(args) > 0 && args[0] == "off" {
+			removeSegmentEffect(seg, EffectHarmony)
+			break
+		}
+		shifts := bs.defaultHarmony(track)
+		if len(args) > 0 {
+			shifts = nil
+			for _, arg := range args {
+				n, err := strconv.Atoi(strings.TrimPrefix(arg, "+"))
+				if err != nil || n == 0 || n < -12 || n > 12 {
+					fmt.Fprintf(bs.Out, "Invalid harmony interval: %s (semitones, -12 to +12)\n", arg)
+					return true
+				}
+				shifts = append(shifts, n)
+			}
+		}
+		setSegmentEffect(seg, SegmentEffect{Type: EffectHarmony, Semitones: shifts})
+
+	case "effects":
+		if len(args) > 0 && (args[0] == "clear" || args[0] == "off") {
+			seg.Effects = nil
+		}
+	}
+
+	fmt.Fprintf(bs.Out, "Segment %s effects: %s (%.1fs long in the blend)\n",
+		segRef, describeSegmentEffects(seg.Effects), bs.segmentLength(track, *seg))
+	return true
+}
+
+// showSegmentEffectUsage prints the usage line for one effect command
+func (bs *Shell) showSegmentEffectUsage(cmd string) {
+	switch cmd {
+	case "reverse":
+		fmt.Fprintf(bs.Out, "Usage: reverse <track:segment>  (toggles)\n")
+	case "stutter":
+		fmt.Fprintf(bs.Out, "Usage: stutter <track:segment> <repeats>  (repeats the first beat, e.g. stutter 1:3 4)\n")
+	case "vocal-chop":
+		fmt.Fprintf(bs.Out, "Usage: vocal-chop <track:segment> <1/4|1/8|1/16|off>\n")
+	case "echo-place":
+		fmt.Fprintf(bs.Out, "Usage: echo-place <track:segment> [copies]  (one copy per beat, default 3, 0 removes)\n")
+	case "harmony-stack":
+		fmt.Fprintf(bs.Out, "Usage: harmony-stack <track:segment> [semitones...|off]  (default: third and fifth in the track's key)\n")
+	case "effects":
+		fmt.Fprintf(bs.Out, "Usage: effects <track:segment> [clear]\n")
+	}
+}
+
+// parseNoteValue reads a chop pattern such as 1/4 or 1/8 into its note division
+func parseNoteValue(s string) (int, error) {
+	division, err := strconv.Atoi(strings.TrimPrefix(s, "1/"))
+	if err != nil || !strings.HasPrefix(s, "1/") {
+		return 0, fmt.Errorf("invalid chop pattern: %s (use 1/4, 1/8 or 1/16)", s)
+	}
+	switch division {
+	case 2, 4, 8, 16:
+		return division, nil
+	}
+	return 0, fmt.Errorf("invalid chop pattern: %s (use 1/4, 1/8 or 1/16)", s)
+}
+
+// defaultHarmony returns a third and a fifth above the track, minor or major to match its key
+func (bs *Shell) defaultHarmony(track int) []int {
+	metadata, pitch := bs.Metadata1, bs.Pitch1
+	if track == 2 {
+		metadata, pitch = bs.Metadata2, bs.Pitch2
+	}
+	if metadata != nil && metadata.Key != nil &&
+		strings.Contains(audio.CalculateEffectiveKey(*metadata.Key, pitch), "minor") {
+		return []int{3, 7}
+	}
+	return []int{4, 7}
+}
+
+func hasSegmentEffect(seg *VocalSegment, effectType string) bool {
+	for _, effect := range seg.Effects {
+		if effect.Type == effectType {
+			return true
+		}
+	}
+	return false
+}
+
+// setSegmentEffect replaces an effect of the same type in place, or appends it
+func setSegmentEffect(seg *VocalSegment, effect SegmentEffect) {
+	for i := range seg.Effects {
+		if seg.Effects[i].Type == effect.Type {
+			seg.Effects[i] = effect
+			return
+		}
+	}
+	seg.Effects = append(seg.Effects, effect)
+}
+
+func removeSegmentEffect(seg *VocalSegment, effectType string) {
+	var kept []SegmentEffect
+	for _, effect := range seg.Effects {
+		if effect.Type != effectType {
+			kept = append(kept, effect)
+		}
+	}
+	seg.Effects = kept
+}
+
+// describeSegmentEffects renders an effects list as e.g. "reverse, stutter x4, chop 1/8"
+func describeSegmentEffects(effects []SegmentEffect) string {
+	if len(effects) == 0 {
+		return "none"
+	}
+
+	var parts []string
+	for _, effect := range effects {
+		switch effect.Type {
+		case EffectStutter:
+			parts = append(parts, fmt.Sprintf("stutter x%d", effect.Count))
+		case EffectChop:
+			parts = append(parts, fmt.Sprintf("chop 1/%d", effect.Division))
+		case EffectEcho:
+			parts = append(parts, fmt.Sprintf("echo x%d", effect.Count))
+		case EffectHarmony:
+			var shifts []string
+			for _, n := range effect.Semitones {
+				shifts = append(shifts, fmt.Sprintf("%+d", n))
+			}
+			parts = append(parts, "harmony "+strings.Join(shifts, " "))
+		default:
+			parts = append(parts, effect.Type)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// segmentBeat returns one beat of the segment's target track in mix seconds
+func (bs *Shell) segmentBeat(track int) float64 {
+	target := otherTrack(track)
+	_, secondsPerBeat := bs.beatGrid(target)
+	return secondsPerBeat / bs.trackSpeed(target)
+}
+
+// segmentEffectChain returns the chain for a segment's effects, in mix seconds.
+// Harmony is not part of the chain; its layers are extra sources, see harmonySources.
+func (bs *Shell) segmentEffectChain(track int, seg VocalSegment) EffectChain {
+	beat := bs.segmentBeat(track)
+	slices, _ := bs.segmentTiming(track, seg)
+
+	var chain EffectChain
+	for i, effect := range seg.Effects {
+		switch effect.Type {
+		case EffectReverse:
+			chain = chain.Reverse()
+		case EffectStutter:
+			chain = chain.Stutter(slices[i], effect.Count)
+		case EffectChop:
+			chain = chain.Chop(beat * beatsPerBar / float64(effect.Division))
+		case EffectEcho:
+			chain = chain.Echo(beat, effect.Count)
+		}
+	}
+	return chain
+}
+
+// segmentLength returns how long a segment plays in mix seconds, including the time
+// its stutter and echo effects add
+func (bs *Shell) segmentLength(track int, seg VocalSegment) float64 {
+	_, length := bs.segmentTiming(track, seg)
+	return length
+}
+
+// segmentTiming walks a segment's effects in order and returns the slice each stutter
+// repeats (0 for other effects) and the segment's total length, all in mix seconds. A
+// stutter slice is a beat, or the whole segment as far as it has grown if shorter.
+func (bs *Shell) segmentTiming(track int, seg VocalSegment) (slices []float64, length float64) {
+	beat := bs.segmentBeat(track)
+	length = seg.Duration / bs.trackSpeed(track)
+
+	slices = make([]float64, len(seg.Effects))
+	for i, effect := range seg.Effects {
+		switch effect.Type {
+		case EffectStutter:
+			slices[i] = math.Min(beat, length)
+			if effect.Count > 1 {
+				length += slices[i] * float64(effect.Count-1)
+			}
+		case EffectEcho:
+			if effect.Count > 0 {
+				length += beat * float64(effect.Count)
+			}
+		}
+	}
+	return slices, length
+}
+
+// harmonySources returns the pitch-shifted layers of a segment's harmony stack
+func (bs *Shell) harmonySources(track int, seg VocalSegment, path string) []Source {
+	var sources []Source
+	for _, effect := range seg.Effects {
+		if effect.Type != EffectHarmony {
+			continue
+		}
+		for _, shift := range effect.Semitones {
+			layer := bs.shiftedEngineSource(track, path, 0, shift)
+			layer.Chain = append(EffectChain{}, layer.Chain...).Volume(harmonyLayerVolume)
+			sources = append(sources, layer)
+		}
+	}
+	return sources
+}
@@ -11,8 +11,19 @@ import (
 	"starchive/util"
 )
 
-// NewShell creates a new blend shell for mixing two tracks
+// NewShell creates a new blend shell for mixing two tracks, exiting if a track has
+// no audio to mix
 func NewShell(id1, id2 string, db *util.Database) *Shell {
+	shell, err := OpenShell(id1, id2, db)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return shell
+}
+
+// OpenShell creates a blend shell for mixing two tracks
+func OpenShell(id1, id2 string, db *util.Database) (*Shell, error) {
 	metadata1, found1 := db.GetCachedMetadata(id1)
 	metadata2, found2 := db.GetCachedMetadata(id2)
 
@@ -23,7 +34,7 @@ func NewShell(id1, id2 string, db *util.Database) *Shell {
 		fmt.Printf("Warning: No metadata found for %s\n", id2)
 	}
 
-	type1, type2 := audio.DetectTrackTypes(id1, id2)
+	type1, type2 := audio.DetectTrackTypes(db, id1, id2)
 	
 	shell := &Shell{
 		ID1:       id1,
@@ -45,18 +56,16 @@ func NewShell(id1, id2 string, db *util.Database) *Shell {
 		Segments2: []VocalSegment{},
 		SegmentsDir1: fmt.Sprintf("./data/%s", id1),
 		SegmentsDir2: fmt.Sprintf("./data/%s", id2),
+		Engine:    EngineFast,
+		Timebase:  TimebaseSeconds,
+		Out:       os.Stdout,
 	}
 
-	shell.InputPath1 = audio.GetAudioFilename(id1, type1)
-	shell.InputPath2 = audio.GetAudioFilename(id2, type2)
-
-	if _, err := os.Stat(shell.InputPath1); os.IsNotExist(err) {
-		fmt.Printf("Error: Input file %s does not exist\n", shell.InputPath1)
-		os.Exit(1)
+	if err := shell.setTrackStems(1, type1); err != nil {
+		return nil, err
 	}
-	if _, err := os.Stat(shell.InputPath2); os.IsNotExist(err) {
-		fmt.Printf("Error: Input file %s does not exist\n", shell.InputPath2)
-		os.Exit(1)
+	if err := shell.setTrackStems(2, type2); err != nil {
+		return nil, err
 	}
 
 	shell.Duration1, _ = audio.GetAudioDuration(shell.InputPath1)
@@ -66,20 +75,20 @@ func NewShell(id1, id2 string, db *util.Database) *Shell {
 	shell.loadSegments("1")
 	shell.loadSegments("2")
 
-	return shell
+	return shell, nil
 }
 
 // Run starts the interactive blend shell
 func (bs *Shell) Run() {
-	fmt.Printf("=== Blend Shell ===\n")
-	fmt.Printf("Track 1: %s (%s)\n", bs.ID1, bs.getTrackTypeDesc(bs.Type1))
-	fmt.Printf("Track 2: %s (%s)\n", bs.ID2, bs.getTrackTypeDesc(bs.Type2))
+	fmt.Fprintf(bs.Out, "=== Blend Shell ===\n")
+	fmt.Fprintf(bs.Out, "Track 1: %s (%s)\n", bs.ID1, bs.stemMixDescription(1))
+	fmt.Fprintf(bs.Out, "Track 2: %s (%s)\n", bs.ID2, bs.stemMixDescription(2))
 	
 	if bs.Metadata1 != nil && bs.Metadata1.BPM != nil && bs.Metadata1.Key != nil {
-		fmt.Printf("  %.1f BPM, %s\n", *bs.Metadata1.BPM, *bs.Metadata1.Key)
+		fmt.Fprintf(bs.Out, "  %.1f BPM, %s\n", *bs.Metadata1.BPM, *bs.Metadata1.Key)
 	}
 	if bs.Metadata2 != nil && bs.Metadata2.BPM != nil && bs.Metadata2.Key != nil {
-		fmt.Printf("  %.1f BPM, %s\n", *bs.Metadata2.BPM, *bs.Metadata2.Key)
+		fmt.Fprintf(bs.Out, "  %.1f BPM, %s\n", *bs.Metadata2.BPM, *bs.Metadata2.Key)
 	}
 	
 	bs.printCommands()
@@ -88,7 +97,7 @@ func (bs *Shell) Run() {
 	// Set up history file
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Printf("Warning: Could not get home directory: %v\n", err)
+		fmt.Fprintf(bs.Out, "Warning: Could not get home directory: %v\n", err)
 		homeDir = "."
 	}
 	historyFile := filepath.Join(homeDir, ".blend_history")
@@ -97,24 +106,26 @@ func (bs *Shell) Run() {
 		Prompt:      "blend> ",
 		HistoryFile: historyFile,
 		AutoComplete: bs.Completer(),
+		FuncFilterInputRune: bs.filterInputRune,
 	}
 	
 	rl, err := readline.NewEx(config)
 	if err != nil {
-		fmt.Printf("Error initializing readline: %v\n", err)
+		fmt.Fprintf(bs.Out, "Error initializing readline: %v\n", err)
 		return
 	}
 	defer rl.Close()
+	bs.rl = rl
 	defer bs.cleanup()
 	
 	for {
 		input, err := rl.Readline()
 		if err != nil {
 			if err == readline.ErrInterrupt {
-				fmt.Println("\nExiting blend shell...")
+				fmt.Fprintln(bs.Out, "\nExiting blend shell...")
 				break
 			}
-			fmt.Printf("Error reading input: %v\n", err)
+			fmt.Fprintf(bs.Out, "Error reading input: %v\n", err)
 			break
 		}
 		
@@ -130,34 +141,51 @@ func (bs *Shell) Run() {
 }
 
 func (bs *Shell) printCommands() {
-	fmt.Printf("\nCommands:\n")
-	fmt.Printf("  play [start_pos]     Play current blend (press any key to stop)\n")
-	fmt.Printf("  pitch1 <n>           Adjust track 1 pitch (semitones)\n")
-	fmt.Printf("  pitch2 <n>           Adjust track 2 pitch (semitones)\n")
-	fmt.Printf("  tempo1 <n>           Adjust track 1 tempo (%%)\n")
-	fmt.Printf("  tempo2 <n>           Adjust track 2 tempo (%%)\n")
-	fmt.Printf("  volume1 <n>          Set track 1 volume (0-200)\n")
-	fmt.Printf("  volume2 <n>          Set track 2 volume (0-200)\n")
-	fmt.Printf("  window <n1> <n2>     Set track start offsets from middle (seconds)\n")
-	fmt.Printf("  match bpm1to2        Match track 1 BPM to track 2\n")
-	fmt.Printf("  match bpm2to1        Match track 2 BPM to track 1\n")
-	fmt.Printf("  match key1to2        Match track 1 key to track 2\n")
-	fmt.Printf("  match key2to1        Match track 2 key to track 1\n")
-	fmt.Printf("  invert               Reset and intelligently match tracks\n")
-	fmt.Printf("  type1 <vocal|instrumental> Set track 1 type\n")
-	fmt.Printf("  type2 <vocal|instrumental> Set track 2 type\n")
-	fmt.Printf("  split <1|2>          Split track into vocal segments\n")
-	fmt.Printf("  segments [1|2]       List vocal segments\n")
-	fmt.Printf("  place <track:seg> at <time> Place segment at specific time\n")
-	fmt.Printf("  shift <track:seg> <+/-time> Adjust segment timing\n")
-	fmt.Printf("  toggle <track:seg>   Enable/disable segment\n")
-	fmt.Printf("  preview <track:seg>  Preview single segment\n")
-	fmt.Printf("  random <track>       Randomly place all segments\n")
-	fmt.Printf("  reset                Reset all adjustments\n")
-	fmt.Printf("  status               Show current settings\n")
-	fmt.Printf("  help                 Show this help\n")
-	fmt.Printf("  exit                 Exit blend shell\n")
-	fmt.Printf("\n")
+	fmt.Fprintf(bs.Out, "\nCommands:\n")
+	fmt.Fprintf(bs.Out, "  play [start_pos]     Play current blend (enter stops; space, arrows, [ ], a/b loop)\n")
+	fmt.Fprintf(bs.Out, "  render [start_pos] [s] Mix the blend to a file without playing it\n")
+	fmt.Fprintf(bs.Out, "  pitch1 <n>           Adjust track 1 pitch (semitones)\n")
+	fmt.Fprintf(bs.Out, "  pitch2 <n>           Adjust track 2 pitch (semitones)\n")
+	fmt.Fprintf(bs.Out, "  tempo1 <n>           Adjust track 1 tempo (%%)\n")
+	fmt.Fprintf(bs.Out, "  tempo2 <n>           Adjust track 2 tempo (%%)\n")
+	fmt.Fprintf(bs.Out, "  volume1 <n>          Set track 1 volume (0-200)\n")
+	fmt.Fprintf(bs.Out, "  volume2 <n>          Set track 2 volume (0-200)\n")
+	fmt.Fprintf(bs.Out, "  window <n1> <n2>     Set track start offsets from middle (seconds)\n")
+	fmt.Fprintf(bs.Out, "  match bpm1to2        Match track 1 BPM to track 2\n")
+	fmt.Fprintf(bs.Out, "  match bpm2to1        Match track 2 BPM to track 1\n")
+	fmt.Fprintf(bs.Out, "  match key1to2        Match track 1 key to track 2\n")
+	fmt.Fprintf(bs.Out, "  match key2to1        Match track 2 key to track 1\n")
+	fmt.Fprintf(bs.Out, "  invert               Reset and intelligently match tracks\n")
+	fmt.Fprintf(bs.Out, "  engine <name>        Pitch/tempo engine: fast, rubberband, prerender\n")
+	fmt.Fprintf(bs.Out, "  timebase <s|bars>    Read plain times as seconds or bar.beat\n")
+	fmt.Fprintf(bs.Out, "  loop <start> <end>   Loop a region in bars (e.g. loop 17 25)\n")
+	fmt.Fprintf(bs.Out, "  timeline [1|2]       Draw energy, beats, gaps and placed segments\n")
+	fmt.Fprintf(bs.Out, "  fade <track:seg> <in> <out> Fade a segment in and out\n")
+	fmt.Fprintf(bs.Out, "  automate volume1 <t>=<v> ... Volume envelope for a track\n")
+	fmt.Fprintf(bs.Out, "  crossfade-auto       Toggle anti-click fades on every edge\n")
+	fmt.Fprintf(bs.Out, "  type1 <stem[+stem]>  Set track 1 stems (vocal, instrumental, drums, bass, other)\n")
+	fmt.Fprintf(bs.Out, "  type2 <stem[+stem]>  Set track 2 stems (e.g. drums+bass)\n")
+	fmt.Fprintf(bs.Out, "  stems                List available stems and the current stem mix\n")
+	fmt.Fprintf(bs.Out, "  stem <t:stem> <op>   Stem volume/mute/solo (e.g. stem 2:other mute)\n")
+	fmt.Fprintf(bs.Out, "  split <1|2>          Split track into vocal segments\n")
+	fmt.Fprintf(bs.Out, "  segments [1|2]       List vocal segments\n")
+	fmt.Fprintf(bs.Out, "  place <track:seg> at <time> Place segment at specific time\n")
+	fmt.Fprintf(bs.Out, "  shift <track:seg> <+/-time> Adjust segment timing\n")
+	fmt.Fprintf(bs.Out, "  toggle <track:seg>   Enable/disable segment\n")
+	fmt.Fprintf(bs.Out, "  reverse|stutter|vocal-chop|echo-place|harmony-stack <track:seg> Segment effects\n")
+	fmt.Fprintf(bs.Out, "  preview <track:seg>  Preview single segment\n")
+	fmt.Fprintf(bs.Out, "  random <track> [--seed N] Randomly place all segments\n")
+	fmt.Fprintf(bs.Out, "  call-response <track> Answer the other track's vocal phrases\n")
+	fmt.Fprintf(bs.Out, "  score                Score the blend with a per-rule breakdown\n")
+	fmt.Fprintf(bs.Out, "  auto-arrange [style] Arrange segments automatically (minimal, dense, ...)\n")
+	fmt.Fprintf(bs.Out, "  magic-blend [style]  Analyze everything and auto-arrange\n")
+	fmt.Fprintf(bs.Out, "  template save|apply <name> Save or apply an arrangement template\n")
+	fmt.Fprintf(bs.Out, "  reset                Reset all adjustments\n")
+	fmt.Fprintf(bs.Out, "  status               Show current settings\n")
+	fmt.Fprintf(bs.Out, "  history              Show session commands and seeds\n")
+	fmt.Fprintf(bs.Out, "  help                 Show this help\n")
+	fmt.Fprintf(bs.Out, "  exit                 Exit blend shell\n")
+	fmt.Fprintf(bs.Out, "\n")
 }
 
 // cleanup removes temporary files and performs other cleanup tasks
@@ -179,12 +207,17 @@ func (bs *Shell) ResetAdjustments() {
 	bs.Volume2 = 100.0
 	bs.Window1 = 0.0
 	bs.Window2 = 0.0
-	fmt.Printf("All adjustments reset to defaults\n")
-}
-
-func (bs *Shell) getTrackTypeDesc(trackType string) string {
-	if trackType == "V" {
-		return "vocal"
+	bs.Automation1 = nil
+	bs.Automation2 = nil
+	for i := range bs.Stems1 {
+		bs.Stems1[i].Volume = 100.0
+		bs.Stems1[i].Mute = false
+		bs.Stems1[i].Solo = false
+	}
+	for i := range bs.Stems2 {
+		bs.Stems2[i].Volume = 100.0
+		bs.Stems2[i].Mute = false
+		bs.Stems2[i].Solo = false
 	}
-	return "instrumental"
-}
\ No newline at end of file
+	fmt.Fprintf(bs.Out, "All adjustments reset to defaults\n")
+}
@@ -0,0 +1,401 @@
+package blend
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Filter is a single ffmpeg audio filter, e.g. {Name: "atempo", Options: ["1.050000"]}
+type Filter struct {
+	Name    string
+	Options []string
+}
+
+// String renders the filter as name=opt1:opt2
+func (f Filter) String() string {
+	if len(f.Options) == 0 {
+		return f.Name
+	}
+	return f.Name + "=" + strings.Join(f.Options, ":")
+}
+
+// EffectChain is an ordered list of filters applied to one audio stream.
+// It is the only place filter strings for pitch, tempo and volume are built.
+type EffectChain []Filter
+
+// Tempo adds a tempo change in percent (e.g. +5 = 5% faster)
+func (c EffectChain) Tempo(percent float64) EffectChain {
+	multiplier := tempoMultiplier(percent)
+	if multiplier == 1.0 {
+		return c
+	}
+	return append(c, Filter{Name: "atempo", Options: []string{fmt.Sprintf("%.6f", multiplier)}})
+}
+
+// Pitch adds a pitch shift in semitones that keeps the tempo unchanged
+func (c EffectChain) Pitch(semitones int) EffectChain {
+	if semitones == 0 {
+		return c
+	}
+	ratio := math.Pow(2, float64(semitones)/12.0)
+	return append(c,
+		Filter{Name: "asetrate", Options: []string{fmt.Sprintf("44100*%.6f", ratio)}},
+		Filter{Name: "aresample", Options: []string{"44100"}},
+		Filter{Name: "atempo", Options: []string{fmt.Sprintf("%.6f", 1.0/ratio)}},
+	)
+}
+
+// Rubberband adds a combined tempo (percent) and pitch (semitones) change using the
+// rubberband filter, which preserves formants and works at any sample rate
+func (c EffectChain) Rubberband(percent float64, semitones int) EffectChain {
+	multiplier := tempoMultiplier(percent)
+	if multiplier == 1.0 && semitones == 0 {
+		return c
+	}
+	return append(c, Filter{Name: "rubberband", Options: []string{
+		fmt.Sprintf("tempo=%.6f", multiplier),
+		fmt.Sprintf("pitch=%.6f", math.Pow(2, float64(semitones)/12.0)),
+		"formant=preserved",
+		"pitchq=quality",
+	}})
+}
+
+// Volume adds a gain in percent (100 = unchanged)
+func (c EffectChain) Volume(percent float64) EffectChain {
+	if percent == 100 {
+		return c
+	}
+	return append(c, Filter{Name: "volume", Options: []string{fmt.Sprintf("%.6f", percent/100.0)}})
+}
+
+// Delay adds silence before the stream
+func (c EffectChain) Delay(seconds float64) EffectChain {
+	if seconds <= 0 {
+		return c
+	}
+	ms := int(seconds * 1000)
+	return append(c, Filter{Name: "adelay", Options: []string{fmt.Sprintf("%d|%d", ms, ms)}})
+}
+
+// FadeIn adds a fade from silence starting at start seconds into the stream
+func (c EffectChain) FadeIn(start, duration float64) EffectChain {
+	if duration <= 0 {
+		return c
+	}
+	return append(c, Filter{Name: "afade", Options: []string{
+		"t=in", fmt.Sprintf("st=%.3f", start), fmt.Sprintf("d=%.3f", duration),
+	}})
+}
+
+// FadeOut adds a fade to silence starting at start seconds into the stream
+func (c EffectChain) FadeOut(start, duration float64) EffectChain {
+	if duration <= 0 {
+		return c
+	}
+	if start < 0 {
+		duration += start
+		start = 0
+	}
+	return append(c, Filter{Name: "afade", Options: []string{
+		"t=out", fmt.Sprintf("st=%.3f", start), fmt.Sprintf("d=%.3f", duration),
+	}})
+}
+
+// Reverse plays the stream backwards
+func (c EffectChain) Reverse() EffectChain {
+	return append(c, Filter{Name: "areverse"})
+}
+
+// Stutter plays the first slice seconds of the stream repeats times before the rest
+func (c EffectChain) Stutter(slice float64, repeats int) EffectChain {
+	if repeats < 2 || slice <= 0 {
+		return c
+	}
+	return append(c,
+		Filter{Name: "aresample", Options: []string{"44100"}},
+		Filter{Name: "aloop", Options: []string{
+			fmt.Sprintf("loop=%d", repeats-1), fmt.Sprintf("size=%d", int(slice*44100)), "start=0",
+		}},
+	)
+}
+
+// Chop gates the stream on and off every note seconds, starting on
+func (c EffectChain) Chop(note float64) EffectChain {
+	if note <= 0 {
+		return c
+	}
+	return append(c,
+		Filter{Name: "asetpts", Options: []string{"PTS-STARTPTS"}},
+		Filter{Name: "volume", Options: []string{fmt.Sprintf("'lt(mod(t,%.6f),%.6f)'", 2*note, note), "eval=frame"}},
+	)
+}
+
+// Echo adds copies of the stream every delay seconds, each at half the level of the last
+func (c EffectChain) Echo(delay float64, copies int) EffectChain {
+	if copies < 1 || delay <= 0 {
+		return c
+	}
+	delays := make([]string, copies)
+	decays := make([]string, copies)
+	for i := range delays {
+		delays[i] = fmt.Sprintf("%d", int(delay*1000*float64(i+1)))
+		decays[i] = fmt.Sprintf("%.4f", math.Pow(0.5, float64(i+1)))
+	}
+	return append(c, Filter{Name: "aecho", Options: []string{
+		"1.0", "0.9", strings.Join(delays, "|"), strings.Join(decays, "|"),
+	}})
+}
+
+// Trim drops the first start seconds of the stream
+func (c EffectChain) Trim(start float64) EffectChain {
+	if start <= 0 {
+		return c
+	}
+	return append(c,
+		Filter{Name: "atrim", Options: []string{fmt.Sprintf("start=%.6f", start)}},
+		Filter{Name: "asetpts", Options: []string{"PTS-STARTPTS"}},
+	)
+}
+
+// VolumeEnvelope adds a volume that follows points, interpolating linearly between
+// them and holding the first and last values. Point times are track seconds; the
+// stream starts at track position start and advances speed track seconds per second.
+func (c EffectChain) VolumeEnvelope(points []AutomationPoint, start, speed float64) EffectChain {
+	if len(points) == 0 {
+		return c
+	}
+
+	pos := fmt.Sprintf("(%.6f+t*%.6f)", start, speed)
+	last := points[len(points)-1]
+	expr := fmt.Sprintf("%.6f", last.Value/100.0)
+	for i := len(points) - 2; i >= 0; i-- {
+		p, next := points[i], points[i+1]
+		segment := fmt.Sprintf("%.6f+%.6f*(%s-%.6f)", p.Value/100.0,
+			(next.Value-p.Value)/100.0/(next.Time-p.Time), pos, p.Time)
+		expr = fmt.Sprintf("if(lt(%s,%.6f),%s,%s)", pos, next.Time, segment, expr)
+	}
+	expr = fmt.Sprintf("if(lt(%s,%.6f),%.6f,%s)", pos, points[0].Time, points[0].Value/100.0, expr)
+
+	// Timestamps restart at 0 so t counts from the start of the stream
+	return append(c,
+		Filter{Name: "asetpts", Options: []string{"PTS-STARTPTS"}},
+		Filter{Name: "volume", Options: []string{"'" + expr + "'", "eval=frame"}},
+	)
+}
+
+// Render joins the chain into a filter string; an empty chain renders as anull
+func (c EffectChain) Render() string {
+	if len(c) == 0 {
+		return "anull"
+	}
+	parts := make([]string, len(c))
+	for i, f := range c {
+		parts[i] = f.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Source is an audio file read from Seek seconds with its own effect chain
+type Source struct {
+	Path  string
+	Seek  float64
+	Chain EffectChain
+}
+
+// Bus sums its sources without normalization and then applies its chain.
+// A bus without sources is silence. Start delays the whole bus in the mix.
+type Bus struct {
+	Sources []Source
+	Chain   EffectChain
+	Start   float64
+	Track   int // Track the bus belongs to (1 or 2), used for live volume changes
+}
+
+// Graph is a complete mix: every bus is mixed into a single output
+type Graph struct {
+	Buses []Bus
+}
+
+// Render returns the ffmpeg filtergraph for the whole mix with its output labelled out.
+// Sources are read with amovie so a graph needs no -i inputs.
+func (g Graph) Render(out string) string {
+	var parts []string
+	var busLabels string
+
+	for b, bus := range g.Buses {
+		busLabel := fmt.Sprintf("[b%d]", b)
+		busLabels += busLabel
+		parts = append(parts, bus.render(fmt.Sprintf("b%d", b), bus.Start)+busLabel)
+	}
+
+	if len(g.Buses) == 1 {
+		parts = append(parts, busLabels+"anull"+out)
+	} else {
+		parts = append(parts, fmt.Sprintf("%samix=inputs=%d:normalize=0%s", busLabels, len(g.Buses), out))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// render returns the filtergraph for one bus, delayed by start seconds, leaving the
+// final output pad unlabelled. prefix keeps source labels unique within a graph.
+func (bus Bus) render(prefix string, start float64) string {
+	chain := append(append(EffectChain{}, bus.Chain...), EffectChain{}.Delay(start)...)
+
+	switch len(bus.Sources) {
+	case 0:
+		return "anullsrc=r=44100:cl=stereo," + chain.Render()
+	case 1:
+		src := bus.Sources[0]
+		return src.movie() + "," + append(append(EffectChain{}, src.Chain...), chain...).Render()
+	}
+
+	var parts []string
+	var srcLabels string
+	for s, src := range bus.Sources {
+		srcLabel := fmt.Sprintf("[%ss%d]", prefix, s)
+		srcLabels += srcLabel
+		parts = append(parts, src.movie()+","+src.Chain.Render()+srcLabel)
+	}
+	parts = append(parts, fmt.Sprintf("%samix=inputs=%d:normalize=0,%s", srcLabels, len(bus.Sources), chain.Render()))
+	return strings.Join(parts, ";")
+}
+
+// movie renders the amovie source for a file
+func (s Source) movie() string {
+	movie := "amovie=filename=" + lavfiQuote(s.Path)
+	if s.Seek > 0 {
+		movie += fmt.Sprintf(":seek_point=%.6f", s.Seek) // Microseconds, finer than a sample at 44.1 kHz
+	}
+	return movie
+}
+
+// lavfiQuote quotes a path for use as a filter option value
+func lavfiQuote(path string) string {
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+// antiClickFade is the fade length used on every edge when AutoFade is on
+const antiClickFade = 0.01
+
+// trackChain returns the track-level chain applied after the stems are mixed.
+// Pitch and tempo are applied per source by the selected engine, see engineSource.
+func (bs *Shell) trackChain(track int) EffectChain {
+	if track == 2 {
+		return EffectChain{}.Volume(bs.Volume2)
+	}
+	return EffectChain{}.Volume(bs.Volume1)
+}
+
+// trackBus returns a bus with duration seconds of a track's audible stems starting at
+// startPos. A volume automation envelope replaces the track's static volume.
+func (bs *Shell) trackBus(track int, startPos, duration float64) Bus {
+	bus := Bus{Chain: bs.trackChain(track), Track: track}
+	if automation := bs.trackAutomation(track); len(automation) > 0 {
+		bus.Chain = EffectChain{}.VolumeEnvelope(automation, startPos, bs.trackSpeed(track))
+	}
+	if bs.AutoFade {
+		bus.Chain = bus.Chain.FadeIn(0, antiClickFade).FadeOut(duration-antiClickFade, antiClickFade)
+	}
+
+	for _, stem := range bs.audibleStems(track) {
+		src := bs.engineSource(track, stem.Path, startPos)
+		src.Chain = src.Chain.Volume(stem.Volume)
+		bus.Sources = append(bus.Sources, src)
+	}
+	return bus
+}
+
+// segmentPath returns the file for a segment of a track
+func (bs *Shell) segmentPath(track int, seg VocalSegment) string {
+	dir := bs.SegmentsDir1
+	if track == 2 {
+		dir = bs.SegmentsDir2
+	}
+	return fmt.Sprintf("%s/part_%03d.wav", dir, seg.Index)
+}
+
+// segmentBus returns a bus that plays a segment through its track's effects.
+// Placements are in the target track's original seconds, so the segment starts when
+// the target track reaches that point at its current tempo; startTarget is where the
+// target track is at mix time 0. ok is false if the segment falls outside the window.
+func (bs *Shell) segmentBus(track int, seg VocalSegment, startTarget, duration float64) (Bus, bool) {
+	delay := (seg.Placement - startTarget) / bs.trackSpeed(otherTrack(track))
+	length := bs.segmentLength(track, seg)
+	if delay+length < 0 || delay > duration {
+		return Bus{}, false
+	}
+
+	// A segment already under way is entered part way
+	entered := 0.0
+	if delay < 0 {
+		entered = -delay
+		delay = 0
+	}
+
+	bus := bs.segmentVoice(track, seg, entered)
+	bus.Start = delay
+	return bus, true
+}
+
+// segmentVoice returns a bus playing a segment with its effects and fades from
+// entered seconds (mix time) into it
+func (bs *Shell) segmentVoice(track int, seg VocalSegment, entered float64) Bus {
+	sourceSpeed := bs.trackSpeed(track)
+	path := bs.segmentPath(track, seg)
+	length := bs.segmentLength(track, seg)
+
+	var bus Bus
+	effects := bs.segmentEffectChain(track, seg)
+	harmony := bs.harmonySources(track, seg, path)
+	if len(effects) == 0 && len(harmony) == 0 {
+		bus.Sources = []Source{bs.engineSource(track, path, entered*sourceSpeed)}
+	} else {
+		// Effects work on the whole segment, so it is read from the start and trimmed after
+		bus.Sources = append([]Source{bs.engineSource(track, path, 0)}, harmony...)
+		bus.Chain = effects.Trim(entered)
+	}
+
+	// Fades are in mix seconds from the start of the segment
+	fadeIn, fadeOut := seg.FadeIn, seg.FadeOut
+	if bs.AutoFade {
+		fadeIn = math.Max(fadeIn, antiClickFade)
+		fadeOut = math.Max(fadeOut, antiClickFade)
+	}
+	chain := append(bus.Chain, bs.trackChain(track)...)
+	if entered == 0 {
+		chain = chain.FadeIn(0, fadeIn)
+	} else if bs.AutoFade {
+		chain = chain.FadeIn(0, antiClickFade)
+	}
+	remaining := length - entered
+	chain = chain.FadeOut(remaining-math.Min(fadeOut, remaining), math.Min(fadeOut, remaining))
+
+	bus.Chain = chain
+	bus.Track = track
+	return bus
+}
+
+// placedDuration returns how long a segment lasts on its target track's timeline once
+// it is stretched to its source track's tempo and lengthened by its effects
+func (bs *Shell) placedDuration(track int, seg VocalSegment) float64 {
+	return bs.segmentLength(track, seg) * bs.trackSpeed(otherTrack(track))
+}
+
+// mixTime converts a position on a track's timeline, in its original seconds, to mix
+// seconds from where playback starts by default, so placements on either track compare
+func (bs *Shell) mixTime(track int, position float64) float64 {
+	startPosition1, startPosition2, _ := bs.playWindow(-1)
+	start := startPosition1
+	if track == 2 {
+		start = startPosition2
+	}
+	return (position - start) / bs.trackSpeed(track)
+}
+
+// mixSpan returns when a segment from track starts and ends in mix seconds
+func (bs *Shell) mixSpan(track int, seg VocalSegment) (start, end float64) {
+	start = bs.mixTime(otherTrack(track), seg.Placement)
+	return start, start + bs.segmentLength(track, seg)
+}
@@ -0,0 +1,129 @@
+package blend
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// testShell returns a shell for two 200s tracks with stems and one placed segment
+// per track, whose segment files live in a temporary directory
+func testShell(t *testing.T) *Shell {
+	dir := t.TempDir()
+	bs := &Shell{
+		ID1: "aaa", ID2: "bbb",
+		Duration1: 200, Duration2: 200,
+		Volume1: 100, Volume2: 100,
+		Stems1: []StemChannel{
+			{Name: "vocals", Path: "./data/aaa_(Vocals)_model.wav", Volume: 100},
+			{Name: "instrumental", Path: "./data/aaa_(Instrumental)_model.wav", Volume: 80},
+		},
+		Stems2: []StemChannel{
+			{Name: "vocals", Path: "./data/bbb_(Vocals)_model.wav", Volume: 100, Mute: true},
+			{Name: "instrumental", Path: "./data/bbb_(Instrumental)_model.wav", Volume: 100},
+		},
+		Segments1: []VocalSegment{
+			{Index: 1, StartTime: 10, Duration: 4, Placement: 104, Active: true, FadeIn: 0.25},
+		},
+		Segments2: []VocalSegment{
+			{Index: 2, StartTime: 30, Duration: 6, Placement: 95, Active: true, FadeOut: 0.5},
+		},
+		SegmentsDir1: filepath.Join(dir, "aaa"),
+		SegmentsDir2: filepath.Join(dir, "bbb"),
+		Engine:       EngineFast,
+		Timebase:     TimebaseSeconds,
+		Out:          io.Discard,
+	}
+
+	for track, segments := range map[int][]VocalSegment{1: bs.Segments1, 2: bs.Segments2} {
+		for _, seg := range segments {
+			path := bs.segmentPath(track, seg)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return bs
+}
+
+// graphLines renders a filtergraph one filter chain per line, with the temporary
+// segment directory replaced so the output is the same on every run
+func graphLines(bs *Shell, graph Graph) string {
+	rendered := graph.Render("[out]")
+	rendered = strings.ReplaceAll(rendered, filepath.Dir(bs.SegmentsDir1), "TMP")
+	return strings.ReplaceAll(rendered, ";", ";\n") + "\n"
+}
+
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test ./blend -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from %s:\ngot:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
+
+func TestPlayGraph(t *testing.T) {
+	bs := testShell(t)
+	bs.Tempo1, bs.Pitch2 = 5, -2
+	bs.Volume2 = 90
+
+	startPosition1, startPosition2, duration := bs.playWindow(-1)
+	checkGolden(t, "play", graphLines(bs, bs.blendGraph(startPosition1, startPosition2, duration)))
+}
+
+func TestRenderGraph(t *testing.T) {
+	bs := testShell(t)
+	bs.Engine = EngineRubberband
+	bs.Tempo2, bs.Pitch1 = -4, 3
+	bs.Window1 = 2
+	bs.AutoFade = true
+	bs.Automation1 = []AutomationPoint{{Time: 90, Value: 100}, {Time: 110, Value: 50}}
+
+	startPosition1, startPosition2, _ := bs.playWindow(85)
+	checkGolden(t, "render", graphLines(bs, bs.blendGraph(startPosition1, startPosition2, 30)))
+}
+
+func TestSegmentPreviewGraph(t *testing.T) {
+	bs := testShell(t)
+	bs.Tempo1, bs.Pitch1 = 10, 2
+	seg := bs.Segments1[0]
+	seg.Effects = []SegmentEffect{{Type: EffectHarmony, Semitones: []int{4, 7}}}
+
+	checkGolden(t, "segment_preview", graphLines(bs, Graph{Buses: []Bus{bs.segmentVoice(1, seg, 0)}}))
+}
+
+func TestSegmentEffectChain(t *testing.T) {
+	bs := testShell(t)
+	bs.Tempo2 = 20 // Beats of the target track are shorter in mix seconds
+	seg := bs.Segments1[0]
+	seg.Effects = []SegmentEffect{
+		{Type: EffectReverse},
+		{Type: EffectStutter, Count: 4},
+		{Type: EffectChop, Division: 8},
+		{Type: EffectEcho, Count: 2},
+	}
+
+	var got strings.Builder
+	got.WriteString(bs.segmentEffectChain(1, seg).Render() + "\n")
+	got.WriteString(graphLines(bs, Graph{Buses: []Bus{bs.segmentVoice(1, seg, 1.5)}}))
+	checkGolden(t, "segment_effect", got.String())
+}
@@ -0,0 +1,97 @@
+package blend
+
+import (
+	"fmt"
+
+	"starchive/audio"
+)
+
+// Pitch/tempo engines selectable with the engine command
+const (
+	EngineFast       = "fast"       // asetrate/atempo chain, cheap but degrades vocals
+	EngineRubberband = "rubberband" // ffmpeg rubberband filter with formant preservation
+	EnginePrerender  = "prerender"  // rubberband CLI renders cached on disk
+)
+
+// handleEngineCommand shows or selects the pitch/tempo engine
+func (bs *Shell) handleEngineCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(bs.Out, "Pitch/tempo engine: %s\n", bs.Engine)
+		fmt.Fprintf(bs.Out, "Usage: engine <fast|rubberband|prerender>\n")
+		fmt.Fprintf(bs.Out, "  fast        asetrate/atempo chain, instant (preview quality)\n")
+		fmt.Fprintf(bs.Out, "  rubberband  ffmpeg rubberband filter with formant preservation\n")
+		fmt.Fprintf(bs.Out, "  prerender   rubberband CLI renders, cached in %s\n", audio.RubberbandCacheDir)
+		return
+	}
+
+	switch args[0] {
+	case EngineFast, EnginePrerender:
+		bs.Engine = args[0]
+	case EngineRubberband:
+		if !audio.HasRubberbandFilter() {
+			fmt.Fprintf(bs.Out, "Your ffmpeg was built without the rubberband filter. Use 'engine prerender' instead.\n")
+			return
+		}
+		bs.Engine = args[0]
+	default:
+		fmt.Fprintf(bs.Out, "Unknown engine: %s (use fast, rubberband or prerender)\n", args[0])
+		return
+	}
+
+	fmt.Fprintf(bs.Out, "Pitch/tempo engine set to %s\n", bs.Engine)
+}
+
+// trackPitchTempo returns a track's pitch and tempo adjustments
+func (bs *Shell) trackPitchTempo(track int) (int, float64) {
+	if track == 2 {
+		return bs.Pitch2, bs.Tempo2
+	}
+	return bs.Pitch1, bs.Tempo1
+}
+
+// trackSpeed returns how fast a track plays relative to its original file
+func (bs *Shell) trackSpeed(track int) float64 {
+	_, tempo := bs.trackPitchTempo(track)
+	return tempoMultiplier(tempo)
+}
+
+// engineSource returns a source that reads path from seek (in original track seconds)
+// with the track's pitch and tempo applied by the selected engine
+func (bs *Shell) engineSource(track int, path string, seek float64) Source {
+	return bs.shiftedEngineSource(track, path, seek, 0)
+}
+
+// shiftedEngineSource is engineSource with extra semitones on top of the track's
+// pitch, as for harmony layers
+func (bs *Shell) shiftedEngineSource(track int, path string, seek float64, semitones int) Source {
+	pitch, tempo := bs.trackPitchTempo(track)
+	pitch += semitones
+	if pitch == 0 && tempo == 0 {
+		return Source{Path: path, Seek: seek}
+	}
+
+	switch bs.Engine {
+	case EngineRubberband:
+		return Source{Path: path, Seek: seek, Chain: EffectChain{}.Rubberband(tempo, pitch)}
+	case EnginePrerender:
+		multiplier := tempoMultiplier(tempo)
+		rendered, err := audio.RenderRubberband(path, multiplier, pitch)
+		if err == nil {
+			// The render is already stretched, so positions scale with the tempo
+			return Source{Path: rendered, Seek: seek / multiplier}
+		}
+		fmt.Fprintf(bs.Out, "Warning: %v; falling back to fast engine\n", err)
+	}
+
+	return Source{Path: path, Seek: seek, Chain: EffectChain{}.Tempo(tempo).Pitch(pitch)}
+}
+
+// tempoMultiplier converts a tempo percentage into a speed factor, ignoring
+// values outside the range atempo and rubberband accept
+func tempoMultiplier(percent float64) float64 {
+	multiplier := 1.0 + (percent / 100.0)
+	if multiplier <= 0.5 || multiplier > 2.0 {
+		return 1.0
+	}
+	return multiplier
+}
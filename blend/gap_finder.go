@@ -14,7 +14,7 @@ func (bs *Shell) HandleGapFinderCommand(cmd string, args []string) bool {
 		if len(args) > 0 {
 			bs.handleGapFinderCommand(args[0])
 		} else {
-			fmt.Printf("Usage: gap-finder <1|2>\n")
+			fmt.Fprintf(bs.Out, "Usage: gap-finder <1|2>\n")
 		}
 		
 	default:
@@ -26,10 +26,10 @@ func (bs *Shell) HandleGapFinderCommand(cmd string, args []string) bool {
 
 // VocalGap represents a low-energy period suitable for vocal placement
 type VocalGap struct {
-	StartTime   float64 // Start time in seconds
-	Duration    float64 // Duration in seconds
-	EnergyLevel float64 // Average RMS energy level (0.0-1.0)
-	IsOnBeat    bool    // Whether gap starts/ends near a beat
+	StartTime   float64 `json:"start_time"`   // Start time in seconds
+	Duration    float64 `json:"duration"`     // Duration in seconds
+	EnergyLevel float64 `json:"energy_level"` // Average RMS energy level (0.0-1.0)
+	IsOnBeat    bool    `json:"is_on_beat"`   // Whether gap starts/ends near a beat
 }
 
 // handleGapFinderCommand analyzes instrumental track for vocal gaps (low energy periods)
@@ -48,34 +48,39 @@ func (bs *Shell) handleGapFinderCommand(track string) {
 		id = bs.ID2
 		duration = bs.Duration2
 	default:
-		fmt.Printf("Invalid track: %s (use 1 or 2)\n", track)
+		fmt.Fprintf(bs.Out, "Invalid track: %s (use 1 or 2)\n", track)
 		return
 	}
 	
 	if inputPath == "" || id == "" {
-		fmt.Printf("Track %s not loaded. Use 'load' command first.\n", track)
+		fmt.Fprintf(bs.Out, "Track %s not loaded. Use 'load' command first.\n", track)
 		return
 	}
 	
-	fmt.Printf("Analyzing track %s (%s) for vocal gaps...\n", track, id)
+	fmt.Fprintf(bs.Out, "Analyzing track %s (%s) for vocal gaps...\n", track, id)
 	
 	gaps := bs.findVocalGaps(inputPath, duration)
+	if bs.gaps == nil {
+		bs.gaps = make(map[int][]VocalGap)
+	}
+	trackNum, _ := strconv.Atoi(track)
+	bs.gaps[trackNum] = gaps // Reused by placement commands and the editor
 	
 	if len(gaps) == 0 {
-		fmt.Printf("No significant vocal gaps found in track %s\n", track)
+		fmt.Fprintf(bs.Out, "No significant vocal gaps found in track %s\n", track)
 		return
 	}
 	
-	fmt.Printf("Found %d vocal gaps suitable for vocal placement:\n", len(gaps))
+	fmt.Fprintf(bs.Out, "Found %d vocal gaps suitable for vocal placement:\n", len(gaps))
 	
 	totalGapDuration := 0.0
 	for i, gap := range gaps {
-		fmt.Printf("  Gap %d: %.2fs - %.2fs (%.2fs duration, energy: %.3f)\n", 
+		fmt.Fprintf(bs.Out, "  Gap %d: %.2fs - %.2fs (%.2fs duration, energy: %.3f)\n", 
 			i+1, gap.StartTime, gap.StartTime+gap.Duration, gap.Duration, gap.EnergyLevel)
 		totalGapDuration += gap.Duration
 	}
 	
-	fmt.Printf("Total gap time available: %.2fs (%.1f%% of track)\n", 
+	fmt.Fprintf(bs.Out, "Total gap time available: %.2fs (%.1f%% of track)\n", 
 		totalGapDuration, (totalGapDuration/duration)*100)
 }
 
@@ -163,7 +168,7 @@ func (bs *Shell) analyzeEnergyLevels(inputPath string, duration float64) []float
 	
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		fmt.Printf("  Warning: Basic energy analysis failed, using fallback method\n")
+		fmt.Fprintf(bs.Out, "  Warning: Basic energy analysis failed, using fallback method\n")
 		return bs.analyzeEnergyLevelsFallback(inputPath, duration)
 	}
 	
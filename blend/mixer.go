@@ -0,0 +1,191 @@
+package blend
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+)
+
+// Mix format used between the decoders, the mixer and every sink
+const (
+	mixSampleRate = 44100
+	mixChannels   = 2
+)
+
+// busStream is one bus decoded by its own ffmpeg process into s16le PCM
+type busStream struct {
+	cmd   *exec.Cmd
+	r     *bufio.Reader
+	start int64 // First frame of the mix this bus contributes to
+	track int
+	gain  float64
+	done  bool
+}
+
+// Mixer sums the buses of a graph in Go. Each bus is decoded to PCM by ffmpeg and
+// placed at its start frame, so tracks and segments stay sample-aligned no matter
+// when the decoder processes started.
+type Mixer struct {
+	streams []*busStream
+	pos     int64 // Frames mixed so far
+	total   int64 // Frames to mix
+}
+
+// NewMixer starts a decoder per bus for duration seconds of the graph
+func NewMixer(ctx context.Context, g Graph, duration float64) (*Mixer, error) {
+	m := &Mixer{total: int64(duration * mixSampleRate)}
+
+	for b, bus := range g.Buses {
+		if bus.Start >= duration {
+			continue
+		}
+
+		args := []string{
+			"-hide_banner",
+			"-loglevel", "error",
+			"-filter_complex", bus.render(fmt.Sprintf("b%d", b), 0) + "[out]",
+			"-map", "[out]",
+			"-t", fmt.Sprintf("%.3f", duration-bus.Start),
+			"-f", "s16le",
+			"-ar", fmt.Sprintf("%d", mixSampleRate),
+			"-ac", fmt.Sprintf("%d", mixChannels),
+			"pipe:1",
+		}
+
+		cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("failed to create decoder pipe: %v", err)
+		}
+		if err := cmd.Start(); err != nil {
+			m.Close()
+			return nil, fmt.Errorf("failed to start decoder: %v", err)
+		}
+
+		m.streams = append(m.streams, &busStream{
+			cmd:   cmd,
+			r:     bufio.NewReaderSize(stdout, 64*1024),
+			start: int64(math.Round(bus.Start * mixSampleRate)),
+			track: bus.Track,
+			gain:  1.0,
+		})
+	}
+
+	return m, nil
+}
+
+// Next mixes up to frames frames and returns them as interleaved samples.
+// It returns io.EOF once the whole duration has been mixed.
+func (m *Mixer) Next(frames int) ([]int16, error) {
+	remaining := m.total - m.pos
+	if remaining <= 0 {
+		return nil, io.EOF
+	}
+	if int64(frames) > remaining {
+		frames = int(remaining)
+	}
+
+	acc := make([]int32, frames*mixChannels)
+	buf := make([]byte, frames*mixChannels*2)
+
+	for _, stream := range m.streams {
+		if stream.done {
+			continue
+		}
+
+		// Frames of this block before the bus starts stay silent for it
+		offset := stream.start - m.pos
+		if offset >= int64(frames) {
+			continue
+		}
+		from := 0
+		if offset > 0 {
+			from = int(offset)
+		}
+
+		need := (frames - from) * mixChannels * 2
+		n, err := io.ReadFull(stream.r, buf[:need])
+		for i := 0; i+1 < n; i += 2 {
+			sample := int32(int16(uint16(buf[i]) | uint16(buf[i+1])<<8))
+			if stream.gain != 1.0 {
+				sample = int32(float64(sample) * stream.gain)
+			}
+			acc[from*mixChannels+i/2] += sample
+		}
+		if err != nil {
+			stream.done = true
+		}
+	}
+
+	out := make([]int16, len(acc))
+	for i, v := range acc {
+		if v > math.MaxInt16 {
+			v = math.MaxInt16
+		} else if v < math.MinInt16 {
+			v = math.MinInt16
+		}
+		out[i] = int16(v)
+	}
+
+	m.pos += int64(frames)
+	return out, nil
+}
+
+// SetTrackGain scales every bus of a track by gain from the next block on,
+// without restarting the decoders
+func (m *Mixer) SetTrackGain(track int, gain float64) {
+	for _, stream := range m.streams {
+		if stream.track == track {
+			stream.gain = gain
+		}
+	}
+}
+
+// Position returns the mix position in seconds
+func (m *Mixer) Position() float64 {
+	return float64(m.pos) / mixSampleRate
+}
+
+// Close stops every decoder
+func (m *Mixer) Close() {
+	for _, stream := range m.streams {
+		if stream.cmd.Process != nil {
+			stream.cmd.Process.Kill()
+		}
+		stream.cmd.Wait()
+	}
+	m.streams = nil
+}
+
+// startSink starts the audio output process, reading a WAV stream on stdin.
+// ffplay is preferred; aplay is used where ffplay is not installed.
+func startSink(ctx context.Context) (*exec.Cmd, io.WriteCloser, error) {
+	var cmd *exec.Cmd
+	if _, err := exec.LookPath("ffplay"); err == nil {
+		cmd = exec.CommandContext(ctx, "ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet", "-i", "pipe:0")
+	} else if _, err := exec.LookPath("aplay"); err == nil {
+		cmd = exec.CommandContext(ctx, "aplay", "-q", "-")
+	} else {
+		return nil, nil, fmt.Errorf("no audio output found (install ffplay or aplay)")
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := writeWavHeader(stdin, 0xFFFFFFFF); err != nil {
+		stdin.Close()
+		cmd.Wait()
+		return nil, nil, err
+	}
+
+	return cmd, stdin, nil
+}
@@ -0,0 +1,367 @@
+package blend
+
+import (
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"starchive/audio"
+)
+
+// ScoreRule is one musical rule's contribution to a blend score
+type ScoreRule struct {
+	Name    string
+	Score   float64 // 0.0 (bad) to 1.0 (good)
+	Weight  float64
+	Detail  string
+	Skipped bool // Not enough information; left out of the total
+}
+
+// BlendScore is an overall blend score (0-100) with its per-rule breakdown
+type BlendScore struct {
+	Total float64
+	Rules []ScoreRule
+}
+
+// Score weights for each rule; skipped rules give their weight to the others
+const (
+	scoreWeightKey     = 25.0
+	scoreWeightBPM     = 15.0
+	scoreWeightStretch = 10.0
+	scoreWeightOverlap = 20.0
+	scoreWeightBeat    = 15.0
+	scoreWeightGaps    = 10.0
+	scoreWeightBalance = 5.0
+)
+
+// HandleScoreCommand processes the score command
+func (bs *Shell) HandleScoreCommand(cmd string, args []string) bool {
+	if cmd != "score" {
+		return false // Command not handled by this module
+	}
+
+	bs.analyzeForScore()
+	score := bs.Score()
+	fmt.Fprintf(bs.Out, "--- Blend Score: %.0f/100 ---\n", score.Total)
+	for _, rule := range score.Rules {
+		if rule.Skipped {
+			fmt.Fprintf(bs.Out, "  %-15s   skipped  %s\n", rule.Name, rule.Detail)
+			continue
+		}
+		fmt.Fprintf(bs.Out, "  %-15s %3.0f%% x%-3.0f %s\n", rule.Name, rule.Score*100, rule.Weight, rule.Detail)
+	}
+	return true
+}
+
+// analyzeForScore runs the analysis Score reads but does not start itself: the gap
+// finder and the loudness measurement for both tracks
+func (bs *Shell) analyzeForScore() {
+	for track := 1; track <= 2; track++ {
+		bs.trackGaps(track)
+		for _, stem := range bs.trackStems(track) {
+			bs.stemLoudness(stem.Path)
+		}
+	}
+}
+
+// Score evaluates the current shell state against the musical rules. It only reads
+// state, so arrangement commands can call it to compare candidates; rules whose
+// analysis has not run (see analyzeForScore) are skipped.
+func (bs *Shell) Score() BlendScore {
+	rules := []ScoreRule{
+		bs.scoreKey(),
+		bs.scoreBPM(),
+		bs.scoreStretch(),
+		bs.scoreOverlap(),
+		bs.scoreBeatAlignment(),
+		bs.scoreGaps(),
+		bs.scoreBalance(),
+	}
+
+	total, weights := 0.0, 0.0
+	for _, rule := range rules {
+		if rule.Skipped {
+			continue
+		}
+		total += rule.Score * rule.Weight
+		weights += rule.Weight
+	}
+
+	score := BlendScore{Rules: rules}
+	if weights > 0 {
+		score.Total = total / weights * 100
+	}
+	return score
+}
+
+// keyDistanceScores rates each folded key distance by how far apart the scales are
+// on the circle of fifths: a fifth (5) is one step, a whole tone (2) two, a semitone
+// (1) five and a tritone (6) six
+var keyDistanceScores = [7]float64{1.0, 0.1, 0.5, 0.35, 0.2, 0.8, 0.0}
+
+// scoreKey rates how well the keys fit after pitch shifting: the same key or its
+// relative major/minor is best, a fifth apart is close, a semitone or tritone clashes
+func (bs *Shell) scoreKey() ScoreRule {
+	rule := ScoreRule{Name: "key", Weight: scoreWeightKey}
+	if bs.Metadata1 == nil || bs.Metadata1.Key == nil || bs.Metadata2 == nil || bs.Metadata2.Key == nil {
+		rule.Skipped, rule.Detail = true, "keys unknown"
+		return rule
+	}
+
+	key1 := audio.CalculateEffectiveKey(*bs.Metadata1.Key, bs.Pitch1)
+	key2 := audio.CalculateEffectiveKey(*bs.Metadata2.Key, bs.Pitch2)
+	distance, ok := keyDistance(key1, key2)
+	if !ok {
+		rule.Skipped, rule.Detail = true, fmt.Sprintf("can't compare %s and %s", key1, key2)
+		return rule
+	}
+
+	rule.Score = keyDistanceScores[distance]
+	rule.Detail = fmt.Sprintf("%s vs %s (%d semitones apart)", key1, key2, distance)
+	return rule
+}
+
+// notePitchClasses maps note names to semitones above C
+var notePitchClasses = map[string]int{
+	"C": 0, "C#": 1, "Db": 1, "D": 2, "D#": 3, "Eb": 3, "E": 4, "F": 5, "F#": 6, "Gb": 6,
+	"G": 7, "G#": 8, "Ab": 8, "A": 9, "A#": 10, "Bb": 10, "B": 11,
+}
+
+// keyDistance returns how many semitones apart two keys' scales are (0-6), treating
+// a minor key as its relative major
+func keyDistance(key1, key2 string) (int, bool) {
+	scale := func(key string) (int, bool) {
+		fields := strings.Fields(key)
+		if len(fields) != 2 {
+			return 0, false
+		}
+		pitch, ok := notePitchClasses[fields[0]]
+		if fields[1] == "minor" {
+			pitch += 3
+		}
+		return pitch, ok
+	}
+
+	p1, ok1 := scale(key1)
+	p2, ok2 := scale(key2)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+
+	diff := ((p2-p1)%12 + 12) % 12
+	if diff > 6 {
+		diff = 12 - diff
+	}
+	return diff, true
+}
+
+// scoreBPM rates how closely the effective tempos line up, allowing half and double time
+func (bs *Shell) scoreBPM() ScoreRule {
+	rule := ScoreRule{Name: "bpm match", Weight: scoreWeightBPM}
+	bpm1, bpm2 := bs.trackBPM(1), bs.trackBPM(2)
+	if bpm1 == 0 || bpm2 == 0 {
+		rule.Skipped, rule.Detail = true, "BPM unknown"
+		return rule
+	}
+
+	effective1 := audio.CalculateEffectiveBPM(bpm1, bs.Tempo1)
+	effective2 := audio.CalculateEffectiveBPM(bpm2, bs.Tempo2)
+
+	ratio := effective1 / effective2
+	off := math.Inf(1)
+	for _, target := range []float64{0.5, 1, 2} {
+		off = math.Min(off, math.Abs(ratio/target-1))
+	}
+
+	// 1% off is barely audible over a phrase; 6% drifts a beat within bars
+	rule.Score = clampFloat(1.0-(off-0.01)/0.05, 0, 1)
+	rule.Detail = fmt.Sprintf("%.1f vs %.1f BPM (%.1f%% off)", effective1, effective2, off*100)
+	return rule
+}
+
+// scoreStretch rates how far the tracks are stretched from their original tempos
+func (bs *Shell) scoreStretch() ScoreRule {
+	rule := ScoreRule{Name: "tempo stretch", Weight: scoreWeightStretch}
+	stretch := math.Abs(bs.Tempo1) + math.Abs(bs.Tempo2)
+	pitch := math.Abs(float64(bs.Pitch1)) + math.Abs(float64(bs.Pitch2))
+
+	// Up to 5% of stretch is free; 25% or an octave of pitch shifting is the worst
+	rule.Score = clampFloat(1.0-math.Max(stretch-5, 0)/20.0-pitch/24.0, 0, 1)
+	rule.Detail = fmt.Sprintf("%.1f%% tempo, %.0f semitones pitch in total", stretch, pitch)
+	return rule
+}
+
+// scoreOverlap rates the mix seconds of vocals overlapping, counted as conflict-detect does
+func (bs *Shell) scoreOverlap() ScoreRule {
+	rule := ScoreRule{Name: "vocal overlap", Weight: scoreWeightOverlap}
+	active1, active2 := bs.getActiveSegments(1), bs.getActiveSegments(2)
+	if len(active1)+len(active2) == 0 {
+		rule.Skipped, rule.Detail = true, "no active segments"
+		return rule
+	}
+
+	overlap, total := 0.0, 0.0
+	for t, segments := range [][]VocalSegment{active1, active2} {
+		track := t + 1
+		for i, seg := range segments {
+			total += bs.segmentLength(track, seg)
+			for _, other := range segments[i+1:] {
+				overlap += bs.calculateOverlap(track, seg, track, other)
+			}
+		}
+	}
+	if bs.isVocalTrack(1) && bs.isVocalTrack(2) {
+		for _, seg1 := range active1 {
+			for _, seg2 := range active2 {
+				overlap += bs.calculateOverlap(1, seg1, 2, seg2)
+			}
+		}
+	}
+
+	rule.Score = clampFloat(1.0-overlap/total*4, 0, 1)
+	rule.Detail = fmt.Sprintf("%.1fs overlapping of %.1fs placed", overlap, total)
+	return rule
+}
+
+// scoreBeatAlignment rates how close placements start to a beat of their target track
+func (bs *Shell) scoreBeatAlignment() ScoreRule {
+	rule := ScoreRule{Name: "beat alignment", Weight: scoreWeightBeat}
+
+	count, totalError := 0, 0.0
+	for track := 1; track <= 2; track++ {
+		target := otherTrack(track)
+		beats, _ := bs.beatGrid(target)
+		if len(beats) == 0 {
+			continue
+		}
+		for _, seg := range bs.getActiveSegments(track) {
+			index := bs.secondsToBeat(target, seg.Placement)
+			totalError += math.Abs(index - math.Round(index))
+			count++
+		}
+	}
+	if count == 0 {
+		rule.Skipped, rule.Detail = true, "no placements on detected beats (run beat-detect)"
+		return rule
+	}
+
+	meanError := totalError / float64(count)
+	rule.Score = clampFloat(1.0-meanError*2, 0, 1)
+	rule.Detail = fmt.Sprintf("%d placements, %.2f beats off on average", count, meanError)
+	return rule
+}
+
+// scoreGaps rates the fraction of placements that start in a gap of their target track.
+// Targets whose gaps have not been found are left out.
+func (bs *Shell) scoreGaps() ScoreRule {
+	rule := ScoreRule{Name: "gap usage", Weight: scoreWeightGaps}
+
+	placed, inGaps, unanalyzed := 0, 0, 0
+	for track := 1; track <= 2; track++ {
+		segments := bs.getActiveSegments(track)
+		if len(segments) == 0 {
+			continue
+		}
+		gaps, ok := bs.gaps[otherTrack(track)]
+		if !ok {
+			unanalyzed += len(segments)
+			continue
+		}
+		for _, seg := range segments {
+			placed++
+			for _, gap := range gaps {
+				if seg.Placement >= gap.StartTime && seg.Placement < gap.StartTime+gap.Duration {
+					inGaps++
+					break
+				}
+			}
+		}
+	}
+	if placed == 0 {
+		rule.Skipped, rule.Detail = true, "no active segments"
+		if unanalyzed > 0 {
+			rule.Detail = "gaps not analyzed"
+		}
+		return rule
+	}
+
+	rule.Score = float64(inGaps) / float64(placed)
+	rule.Detail = fmt.Sprintf("%d of %d placements in gaps", inGaps, placed)
+	return rule
+}
+
+// scoreBalance rates the loudness difference between the two tracks as they play:
+// their audible stems' measured levels with the stem and track volumes applied
+func (bs *Shell) scoreBalance() ScoreRule {
+	rule := ScoreRule{Name: "loudness", Weight: scoreWeightBalance}
+	if bs.Volume1 <= 0 || bs.Volume2 <= 0 {
+		rule.Detail = fmt.Sprintf("a track is silent (%.0f%%/%.0f%%)", bs.Volume1, bs.Volume2)
+		return rule
+	}
+	level1, ok1 := bs.trackLevel(1)
+	level2, ok2 := bs.trackLevel(2)
+	if !ok1 || !ok2 {
+		rule.Skipped, rule.Detail = true, "loudness not measured"
+		return rule
+	}
+
+	db := math.Abs(level1 - level2)
+	rule.Score = clampFloat(1.0-math.Max(db-3, 0)/9.0, 0, 1)
+	rule.Detail = fmt.Sprintf("%.1f dB between tracks (%.1f/%.1f dB)", db, level1, level2)
+	return rule
+}
+
+// trackLevel returns a track's mean level in dB as mixed: the power sum of its audible
+// stems at their volumes, then the track volume. ok is false if a stem is unmeasured.
+func (bs *Shell) trackLevel(track int) (level float64, ok bool) {
+	power := 0.0
+	for _, stem := range bs.audibleStems(track) {
+		stemLevel, measured := bs.loudness[stem.Path]
+		if !measured {
+			return 0, false
+		}
+		if stem.Volume > 0 {
+			power += math.Pow(10, stemLevel/10) * math.Pow(stem.Volume/100, 2)
+		}
+	}
+	if power == 0 {
+		return 0, false
+	}
+	return 10*math.Log10(power) + 20*math.Log10(bs.trackVolume(track)/100), true
+}
+
+var meanVolumePattern = regexp.MustCompile(`mean_volume: (-?[0-9.]+) dB`)
+
+// stemLoudness returns a file's mean level in dB as measured by ffmpeg volumedetect,
+// running it once per file; ok is false if the file could not be measured
+func (bs *Shell) stemLoudness(path string) (level float64, ok bool) {
+	if level, ok := bs.loudness[path]; ok {
+		return level, true
+	}
+
+	fmt.Fprintf(bs.Out, "Measuring loudness of %s...\n", filepath.Base(path))
+	output, err := exec.Command("ffmpeg", "-hide_banner", "-i", path,
+		"-af", "volumedetect", "-f", "null", "-").CombinedOutput()
+	if err != nil {
+		fmt.Fprintf(bs.Out, "Warning: failed to measure %s: %v\n", path, err)
+		return 0, false
+	}
+	match := meanVolumePattern.FindSubmatch(output)
+	if match == nil {
+		return 0, false
+	}
+	level, err = strconv.ParseFloat(string(match[1]), 64)
+	if err != nil {
+		return 0, false
+	}
+
+	if bs.loudness == nil {
+		bs.loudness = make(map[string]float64)
+	}
+	bs.loudness[path] = level
+	return level, true
+}
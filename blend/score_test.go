@@ -0,0 +1,52 @@
+package blend
+
+import "testing"
+
+func TestKeyScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		key1, key2 string
+		distance   int
+		minScore   float64
+		maxScore   float64
+	}{
+		{"same key", "C major", "C major", 0, 1.0, 1.0},
+		{"relative minor", "C major", "A minor", 0, 1.0, 1.0},
+		{"fifth", "C major", "G major", 5, 0.8, 0.8},
+		{"fourth", "C major", "F major", 5, 0.8, 0.8},
+		{"whole tone", "C major", "D major", 2, 0.4, 0.6},
+		{"semitone", "C major", "C# major", 1, 0, 0.1},
+		{"semitone between minors", "A minor", "Bb minor", 1, 0, 0.1},
+		{"tritone", "C major", "F# major", 6, 0, 0.1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance, ok := keyDistance(tt.key1, tt.key2)
+			if !ok || distance != tt.distance {
+				t.Fatalf("keyDistance(%q, %q) = %d, %v; want %d", tt.key1, tt.key2, distance, ok, tt.distance)
+			}
+
+			key1, key2 := tt.key1, tt.key2
+			bs := &Shell{Metadata1: &VideoMetadata{Key: &key1}, Metadata2: &VideoMetadata{Key: &key2}}
+			rule := bs.scoreKey()
+			if rule.Skipped || rule.Score < tt.minScore || rule.Score > tt.maxScore {
+				t.Errorf("scoreKey() = %.2f (skipped %v); want %.2f-%.2f", rule.Score, rule.Skipped, tt.minScore, tt.maxScore)
+			}
+		})
+	}
+
+	fifth, _ := keyDistance("C major", "G major")
+	semitone, _ := keyDistance("C major", "C# major")
+	if keyDistanceScores[fifth] <= keyDistanceScores[semitone] {
+		t.Errorf("a fifth scores %.2f, not above a semitone's %.2f", keyDistanceScores[fifth], keyDistanceScores[semitone])
+	}
+}
+
+func TestKeyDistanceUnknown(t *testing.T) {
+	for _, keys := range [][2]string{{"C major", ""}, {"H major", "C major"}, {"C", "C major"}} {
+		if _, ok := keyDistance(keys[0], keys[1]); ok {
+			t.Errorf("keyDistance(%q, %q) ok; want unknown", keys[0], keys[1])
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package blend
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseSeedFlag removes "--seed N" (or "--seed=N") from args. ok is false when no
+// seed was given.
+func parseSeedFlag(args []string) (rest []string, seed int64, ok bool, err error) {
+	for i := 0; i < len(args); i++ {
+		value, found := "", false
+		switch {
+		case args[i] == "--seed":
+			if i+1 >= len(args) {
+				return nil, 0, false, fmt.Errorf("--seed needs a number")
+			}
+			value, found = args[i+1], true
+			i++
+		case strings.HasPrefix(args[i], "--seed="):
+			value, found = strings.TrimPrefix(args[i], "--seed="), true
+		}
+
+		if !found {
+			rest = append(rest, args[i])
+			continue
+		}
+		seed, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, 0, false, fmt.Errorf("invalid seed: %s", value)
+		}
+		ok = true
+	}
+	return rest, seed, ok, nil
+}
+
+// seededRand returns the random source for a stochastic command. Without an explicit
+// seed one is picked from the clock. The seed is printed and the command is recorded
+// in the session history with it, so the same placements can be produced again.
+func (bs *Shell) seededRand(command string, seed int64, ok bool) *rand.Rand {
+	if !ok {
+		seed = time.Now().UnixNano() % 1000000
+	}
+
+	line := fmt.Sprintf("%s --seed %d", command, seed)
+	fmt.Fprintf(bs.Out, "Seed: %d (repeat with '%s')\n", seed, line)
+	bs.recordHistory(line)
+
+	return rand.New(rand.NewSource(seed))
+}
+
+// recordHistory adds a command to the session history. A seeded command replaces
+// the unseeded line that ran it; the line editor's history gets it too, so the
+// up arrow repeats the exact run.
+func (bs *Shell) recordHistory(line string) {
+	fields := strings.Fields(line)
+	if n := len(bs.History); n > 0 && len(fields) > 0 {
+		last := strings.Fields(bs.History[n-1])
+		if len(last) > 0 && strings.TrimPrefix(last[0], "/") == fields[0] {
+			bs.History[n-1] = line
+		} else {
+			bs.History = append(bs.History, line)
+		}
+	} else {
+		bs.History = append(bs.History, line)
+	}
+
+	if bs.rl != nil {
+		bs.rl.SaveHistory(line)
+	}
+}
+
+// showHistory prints the commands run this session
+func (bs *Shell) showHistory() {
+	if len(bs.History) == 0 {
+		fmt.Fprintf(bs.Out, "No commands run yet\n")
+		return
+	}
+	for i, line := range bs.History {
+		fmt.Fprintf(bs.Out, "  %3d  %s\n", i+1, line)
+	}
+}
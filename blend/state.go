@@ -0,0 +1,119 @@
+package blend
+
+import (
+	"bytes"
+	"strings"
+
+	"starchive/audio"
+)
+
+// TrackState is one track of a ShellState
+type TrackState struct {
+	ID           string            `json:"id"`
+	Title        string            `json:"title"`
+	Type         string            `json:"type"`
+	Stems        []StemChannel     `json:"stems"`
+	Duration     float64           `json:"duration"` // Original seconds
+	Pitch        int               `json:"pitch"`
+	Tempo        float64           `json:"tempo"`
+	Volume       float64           `json:"volume"`
+	Window       float64           `json:"window"`
+	BPM          *float64          `json:"bpm"`
+	Key          *string           `json:"key"`
+	EffectiveBPM float64           `json:"effective_bpm,omitempty"`
+	EffectiveKey string            `json:"effective_key,omitempty"`
+	Beats        []float64         `json:"beats"`
+	Gaps         []VocalGap        `json:"gaps"`     // Only once gap-finder or a placement command has run
+	Segments     []VocalSegment    `json:"segments"` // Placed on the other track's timeline
+	Automation   []AutomationPoint `json:"automation"`
+}
+
+// ShellState is a snapshot of a blend session, for clients that drive the shell
+// without a terminal
+type ShellState struct {
+	Tracks      [2]TrackState `json:"tracks"`
+	Engine      string        `json:"engine"`
+	Timebase    string        `json:"timebase"`
+	GridTrack   int           `json:"grid_track"` // Track whose beats define bars
+	BeatsPerBar int           `json:"beats_per_bar"`
+	LoopStart   float64       `json:"loop_start"`
+	LoopEnd     float64       `json:"loop_end"`
+	AutoFade    bool          `json:"auto_fade"`
+	LastRender  string        `json:"last_render,omitempty"`
+	History     []string      `json:"history"`
+}
+
+// State returns a snapshot of the shell's settings, analysis and arrangement
+func (bs *Shell) State() ShellState {
+	return ShellState{
+		Tracks:      [2]TrackState{bs.trackState(1), bs.trackState(2)},
+		Engine:      bs.Engine,
+		Timebase:    bs.Timebase,
+		GridTrack:   bs.gridTrack(),
+		BeatsPerBar: beatsPerBar,
+		LoopStart:   bs.LoopStart,
+		LoopEnd:     bs.LoopEnd,
+		AutoFade:    bs.AutoFade,
+		LastRender:  bs.LastRender,
+		History:     append([]string{}, bs.History...),
+	}
+}
+
+func (bs *Shell) trackState(track int) TrackState {
+	state := TrackState{
+		ID: bs.ID1, Type: bs.Type1, Stems: bs.Stems1, Duration: bs.Duration1,
+		Pitch: bs.Pitch1, Tempo: bs.Tempo1, Volume: bs.Volume1, Window: bs.Window1,
+		Beats: bs.Beats1, Segments: bs.Segments1, Automation: bs.Automation1,
+	}
+	metadata := bs.Metadata1
+	if track == 2 {
+		state = TrackState{
+			ID: bs.ID2, Type: bs.Type2, Stems: bs.Stems2, Duration: bs.Duration2,
+			Pitch: bs.Pitch2, Tempo: bs.Tempo2, Volume: bs.Volume2, Window: bs.Window2,
+			Beats: bs.Beats2, Segments: bs.Segments2, Automation: bs.Automation2,
+		}
+		metadata = bs.Metadata2
+	}
+
+	state.Title = state.ID
+	if metadata != nil {
+		if metadata.Title != nil && *metadata.Title != "" {
+			state.Title = *metadata.Title
+		}
+		state.BPM, state.Key = metadata.BPM, metadata.Key
+		if metadata.BPM != nil {
+			state.EffectiveBPM = audio.CalculateEffectiveBPM(*metadata.BPM, state.Tempo)
+		}
+		if metadata.Key != nil {
+			state.EffectiveKey = audio.CalculateEffectiveKey(*metadata.Key, state.Pitch)
+		}
+	}
+	state.Gaps = bs.gaps[track]
+
+	// Copies, so a snapshot can be encoded while the shell keeps changing
+	state.Stems = append([]StemChannel{}, state.Stems...)
+	state.Beats = append([]float64{}, state.Beats...)
+	state.Gaps = append([]VocalGap{}, state.Gaps...)
+	state.Segments = append([]VocalSegment{}, state.Segments...)
+	state.Automation = append([]AutomationPoint{}, state.Automation...)
+	return state
+}
+
+// Exec runs a command line exactly as the interactive shell would and returns what
+// it printed. exit is true when the command ends the session.
+func (bs *Shell) Exec(line string) (output string, exit bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", false
+	}
+
+	var buf bytes.Buffer
+	out := bs.Out
+	bs.Out = &buf
+	defer func() {
+		bs.Out = out
+		output = buf.String()
+	}()
+
+	return "", !bs.HandleCommand(line)
+}
@@ -0,0 +1,242 @@
+package blend
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"starchive/audio"
+)
+
+// HandleStemCommand processes stem mix commands (stems, stem)
+func (bs *Shell) HandleStemCommand(cmd string, args []string) bool {
+	switch cmd {
+	case "stems":
+		bs.handleStemsCommand()
+
+	case "stem":
+		bs.handleStemCommand(args)
+
+	default:
+		return false // Command not handled by this module
+	}
+
+	return true
+}
+
+// parseTrackStems resolves a type spec such as "vocal", "drums" or "drums+bass" into stem channels
+func (bs *Shell) parseTrackStems(id, spec string) ([]StemChannel, string, error) {
+	var channels []StemChannel
+	var codes []string
+
+	for _, part := range strings.Split(spec, "+") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		code := trackTypeCode(part)
+		path := audio.GetAudioFilename(bs.DB, id, code)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, "", fmt.Errorf("no %s stem for %s (%s)", audio.TrackTypeDescription(code), id, path)
+		}
+
+		channels = append(channels, StemChannel{
+			Name:   audio.NormalizeStemName(part),
+			Path:   path,
+			Volume: 100.0,
+		})
+		codes = append(codes, code)
+	}
+
+	if len(channels) == 0 {
+		return nil, "", fmt.Errorf("no stems given")
+	}
+
+	return channels, strings.Join(codes, "+"), nil
+}
+
+// trackTypeCode converts a user-supplied type into the code stored on the shell (V, I or stem name)
+func trackTypeCode(name string) string {
+	switch audio.NormalizeStemName(name) {
+	case audio.StemVocals:
+		return "V"
+	case audio.StemInstrumental:
+		return "I"
+	default:
+		return audio.NormalizeStemName(name)
+	}
+}
+
+// setTrackStems replaces the stems that make up a track
+func (bs *Shell) setTrackStems(track int, spec string) error {
+	id := bs.ID1
+	if track == 2 {
+		id = bs.ID2
+	}
+
+	channels, code, err := bs.parseTrackStems(id, spec)
+	if err != nil {
+		return err
+	}
+
+	if track == 1 {
+		bs.Stems1 = channels
+		bs.Type1 = code
+		bs.InputPath1 = channels[0].Path
+	} else {
+		bs.Stems2 = channels
+		bs.Type2 = code
+		bs.InputPath2 = channels[0].Path
+	}
+	delete(bs.gaps, track) // Gaps were found in the old input
+
+	return nil
+}
+
+// trackStems returns the stem channels for a track
+func (bs *Shell) trackStems(track int) []StemChannel {
+	if track == 1 {
+		return bs.Stems1
+	}
+	return bs.Stems2
+}
+
+// anySolo reports whether any stem on either track is soloed
+func (bs *Shell) anySolo() bool {
+	for _, ch := range bs.Stems1 {
+		if ch.Solo {
+			return true
+		}
+	}
+	for _, ch := range bs.Stems2 {
+		if ch.Solo {
+			return true
+		}
+	}
+	return false
+}
+
+// audibleStems returns the stems of a track that should be heard after mute/solo
+func (bs *Shell) audibleStems(track int) []StemChannel {
+	solo := bs.anySolo()
+	var audible []StemChannel
+	for _, ch := range bs.trackStems(track) {
+		if ch.Mute || (solo && !ch.Solo) {
+			continue
+		}
+		audible = append(audible, ch)
+	}
+	return audible
+}
+
+// isVocalTrack reports whether a track's mix contains the vocal stem
+func (bs *Shell) isVocalTrack(track int) bool {
+	for _, ch := range bs.trackStems(track) {
+		if ch.Name == audio.StemVocals {
+			return true
+		}
+	}
+	return false
+}
+
+// stemMixDescription summarizes a track's stem mix for status output
+func (bs *Shell) stemMixDescription(track int) string {
+	solo := bs.anySolo()
+	var parts []string
+	for _, ch := range bs.trackStems(track) {
+		desc := ch.Name
+		if ch.Volume != 100 {
+			desc += fmt.Sprintf(" %.0f%%", ch.Volume)
+		}
+		if ch.Mute {
+			desc += " (muted)"
+		} else if ch.Solo {
+			desc += " (solo)"
+		} else if solo {
+			desc += " (silent)"
+		}
+		parts = append(parts, desc)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handleStemsCommand lists available stems and the current stem mix
+func (bs *Shell) handleStemsCommand() {
+	for _, track := range []int{1, 2} {
+		id := bs.ID1
+		if track == 2 {
+			id = bs.ID2
+		}
+		available := audio.ListStemNames(bs.DB, id)
+		fmt.Fprintf(bs.Out, "Track %d (%s) available stems: %s\n", track, id, strings.Join(available, ", "))
+		fmt.Fprintf(bs.Out, "  Mix: %s\n", bs.stemMixDescription(track))
+	}
+}
+
+// handleStemCommand adjusts a single stem: stem <track:stem> <volume n|mute|unmute|solo|unsolo>
+func (bs *Shell) handleStemCommand(args []string) {
+	if len(args) < 2 {
+		fmt.Fprintf(bs.Out, "Usage: stem <track:stem> <volume <n>|mute|unmute|solo|unsolo>\n")
+		fmt.Fprintf(bs.Out, "Example: stem 2:drums volume 80\n")
+		fmt.Fprintf(bs.Out, "Example: stem 2:other mute\n")
+		return
+	}
+
+	parts := strings.SplitN(args[0], ":", 2)
+	if len(parts) != 2 || (parts[0] != "1" && parts[0] != "2") {
+		fmt.Fprintf(bs.Out, "Invalid stem reference: %s (use format track:stem, e.g. 2:drums)\n", args[0])
+		return
+	}
+
+	track := 1
+	stems := &bs.Stems1
+	if parts[0] == "2" {
+		track = 2
+		stems = &bs.Stems2
+	}
+
+	name := audio.NormalizeStemName(parts[1])
+	var ch *StemChannel
+	for i := range *stems {
+		if (*stems)[i].Name == name {
+			ch = &(*stems)[i]
+			break
+		}
+	}
+	if ch == nil {
+		fmt.Fprintf(bs.Out, "Stem %s is not part of track %d. Use 'type%d %s' to add it (e.g. 'type%d vocal+%s').\n",
+			name, track, track, name, track, name)
+		return
+	}
+
+	switch args[1] {
+	case "volume", "vol":
+		if len(args) < 3 {
+			fmt.Fprintf(bs.Out, "Usage: stem %s volume <0-200>\n", args[0])
+			return
+		}
+		val, err := strconv.ParseFloat(args[2], 64)
+		if err != nil {
+			fmt.Fprintf(bs.Out, "Invalid volume value: %s\n", args[2])
+			return
+		}
+		ch.Volume = clampFloat(val, 0.0, 200.0)
+		fmt.Fprintf(bs.Out, "Track %d %s volume set to %.0f%%\n", track, ch.Name, ch.Volume)
+	case "mute":
+		ch.Mute = true
+		fmt.Fprintf(bs.Out, "Track %d %s muted\n", track, ch.Name)
+	case "unmute":
+		ch.Mute = false
+		fmt.Fprintf(bs.Out, "Track %d %s unmuted\n", track, ch.Name)
+	case "solo":
+		ch.Solo = true
+		fmt.Fprintf(bs.Out, "Track %d %s soloed\n", track, ch.Name)
+	case "unsolo":
+		ch.Solo = false
+		fmt.Fprintf(bs.Out, "Track %d %s unsoloed\n", track, ch.Name)
+	default:
+		fmt.Fprintf(bs.Out, "Unknown stem action: %s (use volume, mute, unmute, solo, unsolo)\n", args[1])
+	}
+}
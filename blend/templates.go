@@ -0,0 +1,298 @@
+package blend
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TemplatesDir holds saved arrangement templates, one JSON file each
+const TemplatesDir = "./data/templates"
+
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Template is an arrangement stored as relative structure: which kind of segment goes
+// where on the target track's bar grid and gaps, not absolute seconds, so it can be
+// applied to any pair of tracks
+type Template struct {
+	Name  string         `json:"name"`
+	From  string         `json:"from"` // Track pair the template was captured from
+	Slots []TemplateSlot `json:"slots"`
+}
+
+// TemplateSlot is one placed segment of a template
+type TemplateSlot struct {
+	Track    int             `json:"track"`     // Track the segment comes from (placed on the other)
+	Energy   string          `json:"energy"`    // Energy category of the segment, "" if unanalyzed
+	Bar      float64         `json:"bar"`       // 0-based position in bars on the target grid
+	Beats    float64         `json:"beats"`     // Length in target beats
+	Gap      int             `json:"gap"`       // Index of the target gap it starts in, -1 if none
+	GapBeats float64         `json:"gap_beats"` // Beats from the start of that gap
+	FadeIn   float64         `json:"fade_in,omitempty"`
+	FadeOut  float64         `json:"fade_out,omitempty"`
+	Effects  []SegmentEffect `json:"effects,omitempty"`
+}
+
+// HandleTemplateCommand processes arrangement template commands (template save/apply/list)
+func (bs *Shell) HandleTemplateCommand(cmd string, args []string) bool {
+	if cmd != "template" {
+		return false // Command not handled by this module
+	}
+
+	if len(args) == 0 {
+		fmt.Fprintf(bs.Out, "Usage: template save <name> | template apply <name> | template list\n")
+		return true
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		bs.listTemplates()
+	case "save", "apply", "load":
+		if len(args) < 2 {
+			fmt.Fprintf(bs.Out, "Usage: template %s <name>\n", args[0])
+			return true
+		}
+		if !templateNamePattern.MatchString(args[1]) {
+			fmt.Fprintf(bs.Out, "Invalid template name: %s (letters, digits, - and _ only)\n", args[1])
+			return true
+		}
+		if args[0] == "save" {
+			bs.saveTemplate(args[1])
+		} else {
+			bs.applyTemplate(args[1])
+		}
+	default:
+		fmt.Fprintf(bs.Out, "Unknown template command: %s (use save, apply or list)\n", args[0])
+	}
+
+	return true
+}
+
+// saveTemplate captures the active segment placements as a template
+func (bs *Shell) saveTemplate(name string) {
+	tmpl := Template{Name: name, From: bs.ID1 + "+" + bs.ID2}
+
+	inGaps := 0
+	for track := 1; track <= 2; track++ {
+		target := otherTrack(track)
+		_, secondsPerBeat := bs.beatGrid(target)
+		gaps := bs.trackGaps(target)
+
+		for _, seg := range bs.trackSegments(track) {
+			if !seg.Active {
+				continue
+			}
+
+			beat := bs.secondsToBeat(target, seg.Placement)
+			slot := TemplateSlot{
+				Track:   track,
+				Energy:  seg.EnergyCategory,
+				Bar:     beat / beatsPerBar,
+				Beats:   bs.placedDuration(track, seg) / secondsPerBeat,
+				Gap:     -1,
+				FadeIn:  seg.FadeIn,
+				FadeOut: seg.FadeOut,
+				Effects: seg.Effects,
+			}
+			for i, gap := range gaps {
+				if seg.Placement >= gap.StartTime && seg.Placement < gap.StartTime+gap.Duration {
+					slot.Gap = i
+					slot.GapBeats = beat - bs.secondsToBeat(target, gap.StartTime)
+					inGaps++
+					break
+				}
+			}
+			tmpl.Slots = append(tmpl.Slots, slot)
+		}
+	}
+
+	if len(tmpl.Slots) == 0 {
+		fmt.Fprintf(bs.Out, "No active segments to save. Place some segments first.\n")
+		return
+	}
+
+	if err := os.MkdirAll(TemplatesDir, 0755); err != nil {
+		fmt.Fprintf(bs.Out, "Error creating %s: %v\n", TemplatesDir, err)
+		return
+	}
+	data, err := json.MarshalIndent(tmpl, "", "  ")
+	if err != nil {
+		fmt.Fprintf(bs.Out, "Error encoding template: %v\n", err)
+		return
+	}
+	path := filepath.Join(TemplatesDir, name+".json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		fmt.Fprintf(bs.Out, "Error saving template: %v\n", err)
+		return
+	}
+
+	fmt.Fprintf(bs.Out, "Saved template '%s': %d segments (%d in gaps) to %s\n", name, len(tmpl.Slots), inGaps, path)
+}
+
+// applyTemplate maps a saved template onto the current tracks. Each slot is filled
+// with an unused segment of the same energy and similar length, placed in the same
+// gap when the target has one, otherwise at the same bar.
+func (bs *Shell) applyTemplate(name string) {
+	tmpl, err := loadTemplate(name)
+	if err != nil {
+		fmt.Fprintf(bs.Out, "Error loading template: %v\n", err)
+		return
+	}
+
+	placed, skipped := 0, 0
+	for track := 1; track <= 2; track++ {
+		var slots []TemplateSlot
+		for _, slot := range tmpl.Slots {
+			if slot.Track == track {
+				slots = append(slots, slot)
+			}
+		}
+		if len(slots) == 0 {
+			continue
+		}
+
+		segments := bs.trackSegments(track)
+		if len(segments) == 0 {
+			fmt.Fprintf(bs.Out, "Track %d has no segments for %d template slots. Run 'split %d' first.\n", track, len(slots), track)
+			skipped += len(slots)
+			continue
+		}
+
+		target := otherTrack(track)
+		_, secondsPerBeat := bs.beatGrid(target)
+		gaps := bs.trackGaps(target)
+		targetDuration := bs.trackDuration(target)
+
+		for i := range segments {
+			segments[i].Active = false
+		}
+
+		sort.Slice(slots, func(i, j int) bool { return slots[i].Bar < slots[j].Bar })
+		used := make(map[int]bool)
+		for _, slot := range slots {
+			best := -1
+			bestScore := math.Inf(1)
+			for i, seg := range segments {
+				if used[i] {
+					continue
+				}
+				score := math.Abs(bs.placedDuration(track, seg)/secondsPerBeat - slot.Beats)
+				if slot.Energy != "" && seg.EnergyCategory != slot.Energy {
+					score += 1000 // Any segment beats an empty slot, but energy comes first
+				}
+				if score < bestScore {
+					best, bestScore = i, score
+				}
+			}
+			if best < 0 {
+				skipped++
+				continue
+			}
+
+			position := bs.beatToSeconds(target, slot.Bar*beatsPerBar)
+			if slot.Gap >= 0 && slot.Gap < len(gaps) {
+				position = bs.beatToSeconds(target, bs.secondsToBeat(target, gaps[slot.Gap].StartTime)+slot.GapBeats)
+			}
+			if position < 0 || (targetDuration > 0 && position >= targetDuration) {
+				skipped++
+				continue
+			}
+
+			seg := &segments[best]
+			seg.Placement = position
+			seg.Active = true
+			seg.FadeIn, seg.FadeOut = slot.FadeIn, slot.FadeOut
+			seg.Effects = append([]SegmentEffect{}, slot.Effects...)
+			used[best] = true
+			placed++
+
+			fmt.Fprintf(bs.Out, "  %d:%d -> %.2fs (bar %s)\n", track, best+1, position, bs.formatBarBeat(target, position))
+		}
+	}
+
+	fmt.Fprintf(bs.Out, "Applied template '%s' (from %s): %d segments placed", name, tmpl.From, placed)
+	if skipped > 0 {
+		fmt.Fprintf(bs.Out, ", %d slots left empty", skipped)
+	}
+	fmt.Fprintf(bs.Out, "\n")
+}
+
+// listTemplates prints the saved templates
+func (bs *Shell) listTemplates() {
+	entries, err := os.ReadDir(TemplatesDir)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(bs.Out, "Error reading %s: %v\n", TemplatesDir, err)
+		return
+	}
+
+	count := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		tmpl, err := loadTemplate(name)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(bs.Out, "  %-20s %d segments (from %s)\n", name, len(tmpl.Slots), tmpl.From)
+		count++
+	}
+
+	if count == 0 {
+		fmt.Fprintf(bs.Out, "No templates saved. Use 'template save <name>' to create one.\n")
+	}
+}
+
+// loadTemplate reads a template by name
+func loadTemplate(name string) (*Template, error) {
+	data, err := os.ReadFile(filepath.Join(TemplatesDir, name+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no template named %s", name)
+		}
+		return nil, err
+	}
+
+	var tmpl Template
+	if err := json.Unmarshal(data, &tmpl); err != nil {
+		return nil, fmt.Errorf("invalid template %s: %v", name, err)
+	}
+	return &tmpl, nil
+}
+
+// trackSegments returns a track's segments; changes to the elements are kept
+func (bs *Shell) trackSegments(track int) []VocalSegment {
+	if track == 2 {
+		return bs.Segments2
+	}
+	return bs.Segments1
+}
+
+// trackGaps returns the vocal gaps of a track, running the gap finder once per track
+func (bs *Shell) trackGaps(track int) []VocalGap {
+	if gaps, ok := bs.gaps[track]; ok {
+		return gaps
+	}
+
+	inputPath, duration := bs.InputPath1, bs.Duration1
+	if track == 2 {
+		inputPath, duration = bs.InputPath2, bs.Duration2
+	}
+	if inputPath == "" || duration <= 0 {
+		return nil
+	}
+
+	fmt.Fprintf(bs.Out, "Finding gaps in track %d...\n", track)
+	gaps := bs.findVocalGaps(inputPath, duration)
+	if bs.gaps == nil {
+		bs.gaps = make(map[int][]VocalGap)
+	}
+	bs.gaps[track] = gaps
+	return gaps
+}
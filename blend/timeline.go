@@ -0,0 +1,277 @@
+package blend
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Timebases for plain numbers in blend commands
+const (
+	TimebaseSeconds = "seconds"
+	TimebaseBars    = "bars"
+)
+
+// beatsPerBar assumes 4/4, which covers nearly everything we blend
+const beatsPerBar = 4
+
+// HandleTimelineCommand processes musical timeline commands (timebase, loop, timeline)
+func (bs *Shell) HandleTimelineCommand(cmd string, args []string) bool {
+	switch cmd {
+	case "timebase":
+		bs.handleTimebaseCommand(args)
+
+	case "timeline", "tl":
+		bs.handleTimelineViewCommand(args)
+
+	case "loop":
+		bs.handleLoopCommand(args)
+
+	default:
+		return false // Command not handled by this module
+	}
+
+	return true
+}
+
+// handleTimebaseCommand shows or sets how plain numbers are read
+func (bs *Shell) handleTimebaseCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(bs.Out, "Timebase: %s\n", bs.Timebase)
+		fmt.Fprintf(bs.Out, "Usage: timebase <seconds|bars>\n")
+		fmt.Fprintf(bs.Out, "  A suffix always wins: 45.2s is seconds, 17.1b is bar 17 beat 1\n")
+		return
+	}
+
+	switch args[0] {
+	case TimebaseSeconds, "s":
+		bs.Timebase = TimebaseSeconds
+	case TimebaseBars, "b":
+		bs.Timebase = TimebaseBars
+	default:
+		fmt.Fprintf(bs.Out, "Unknown timebase: %s (use seconds or bars)\n", args[0])
+		return
+	}
+	fmt.Fprintf(bs.Out, "Timebase set to %s\n", bs.Timebase)
+}
+
+// handleLoopCommand sets the loop region in bars: loop <start_bar> <end_bar>
+func (bs *Shell) handleLoopCommand(args []string) {
+	if len(args) == 0 {
+		if bs.LoopEnd <= bs.LoopStart {
+			fmt.Fprintf(bs.Out, "No loop region set\n")
+		} else {
+			grid := bs.gridTrack()
+			fmt.Fprintf(bs.Out, "Loop: bars %s - %s (%.2fs - %.2fs)\n",
+				bs.formatBarBeat(grid, bs.LoopStart), bs.formatBarBeat(grid, bs.LoopEnd), bs.LoopStart, bs.LoopEnd)
+		}
+		fmt.Fprintf(bs.Out, "Usage: loop <start_bar> <end_bar> | loop off\n")
+		fmt.Fprintf(bs.Out, "Example: loop 17 25 (bars 17 through 24, play then repeats them)\n")
+		return
+	}
+
+	if args[0] == "off" || args[0] == "clear" {
+		bs.LoopStart, bs.LoopEnd = 0, 0
+		fmt.Fprintf(bs.Out, "Loop cleared\n")
+		return
+	}
+
+	if len(args) < 2 {
+		fmt.Fprintf(bs.Out, "Usage: loop <start_bar> <end_bar>\n")
+		return
+	}
+
+	grid := bs.gridTrack()
+	start, err := bs.parseBarBeat(grid, args[0])
+	if err != nil {
+		fmt.Fprintf(bs.Out, "Invalid start bar: %s\n", args[0])
+		return
+	}
+	end, err := bs.parseBarBeat(grid, args[1])
+	if err != nil {
+		fmt.Fprintf(bs.Out, "Invalid end bar: %s\n", args[1])
+		return
+	}
+	if end <= start {
+		fmt.Fprintf(bs.Out, "Loop end must be after loop start\n")
+		return
+	}
+
+	bs.LoopStart, bs.LoopEnd = start, end
+	fmt.Fprintf(bs.Out, "Loop set to bars %s - %s (%.2fs - %.2fs)\n", args[0], args[1], start, end)
+}
+
+// gridTrack returns the track whose beats define the blend's bar grid: the backing
+// (non-vocal) track, or track 2 when that is ambiguous
+func (bs *Shell) gridTrack() int {
+	if !bs.isVocalTrack(1) && bs.isVocalTrack(2) {
+		return 1
+	}
+	return 2
+}
+
+// otherTrack returns the track a segment from track is placed onto
+func otherTrack(track int) int {
+	if track == 1 {
+		return 2
+	}
+	return 1
+}
+
+// trackBPM returns a track's analyzed BPM, or 0 if unknown
+func (bs *Shell) trackBPM(track int) float64 {
+	metadata := bs.Metadata1
+	if track == 2 {
+		metadata = bs.Metadata2
+	}
+	if metadata == nil || metadata.BPM == nil || *metadata.BPM <= 0 {
+		return 0
+	}
+	return *metadata.BPM
+}
+
+// beatGrid returns a track's detected beats and the beat length used beyond them.
+// Grid positions are in the track's original seconds, so bars stay attached to the
+// music when its tempo changes. Without detected beats the grid starts at 0.
+func (bs *Shell) beatGrid(track int) ([]float64, float64) {
+	detected := bs.Beats1
+	if track == 2 {
+		detected = bs.Beats2
+	}
+
+	beats := append([]float64{}, detected...)
+	sort.Float64s(beats)
+
+	secondsPerBeat := 0.5 // 120 BPM when nothing is known
+	if bpm := bs.trackBPM(track); bpm > 0 {
+		secondsPerBeat = 60.0 / bpm
+	} else if len(beats) > 1 {
+		secondsPerBeat = (beats[len(beats)-1] - beats[0]) / float64(len(beats)-1)
+	}
+
+	return beats, secondsPerBeat
+}
+
+// beatToSeconds converts a 0-based (fractional) beat index to seconds on a track's grid
+func (bs *Shell) beatToSeconds(track int, index float64) float64 {
+	beats, secondsPerBeat := bs.beatGrid(track)
+	if len(beats) == 0 {
+		return index * secondsPerBeat
+	}
+
+	last := float64(len(beats) - 1)
+	switch {
+	case index < 0:
+		return beats[0] + index*secondsPerBeat
+	case index >= last:
+		return beats[len(beats)-1] + (index-last)*secondsPerBeat
+	}
+
+	i := int(index)
+	frac := index - float64(i)
+	return beats[i] + frac*(beats[i+1]-beats[i])
+}
+
+// secondsToBeat converts seconds to a 0-based (fractional) beat index on a track's grid
+func (bs *Shell) secondsToBeat(track int, seconds float64) float64 {
+	beats, secondsPerBeat := bs.beatGrid(track)
+	if len(beats) == 0 {
+		return seconds / secondsPerBeat
+	}
+
+	switch {
+	case seconds < beats[0]:
+		return (seconds - beats[0]) / secondsPerBeat
+	case seconds >= beats[len(beats)-1]:
+		return float64(len(beats)-1) + (seconds-beats[len(beats)-1])/secondsPerBeat
+	}
+
+	i := sort.SearchFloat64s(beats, seconds)
+	if beats[i] == seconds {
+		return float64(i)
+	}
+	i--
+	return float64(i) + (seconds-beats[i])/(beats[i+1]-beats[i])
+}
+
+// barBeatToSeconds converts a 1-based bar and beat (beat may be fractional) to seconds
+func (bs *Shell) barBeatToSeconds(track, bar int, beat float64) float64 {
+	return bs.beatToSeconds(track, float64((bar-1)*beatsPerBar)+beat-1)
+}
+
+// secondsToBarBeat converts seconds to a 1-based bar and beat
+func (bs *Shell) secondsToBarBeat(track int, seconds float64) (int, float64) {
+	index := bs.secondsToBeat(track, seconds)
+	bar := int(math.Floor(index / beatsPerBar))
+	return bar + 1, index - float64(bar*beatsPerBar) + 1
+}
+
+// formatBarBeat renders seconds as bar.beat, with a beat fraction when off the grid
+func (bs *Shell) formatBarBeat(track int, seconds float64) string {
+	bar, beat := bs.secondsToBarBeat(track, seconds)
+	whole := math.Floor(beat + 1e-6)
+	if frac := beat - whole; frac > 0.01 {
+		return fmt.Sprintf("%d.%d+%.2f", bar, int(whole), frac)
+	}
+	return fmt.Sprintf("%d.%d", bar, int(whole))
+}
+
+// parseBarBeat parses "17", "17.3" or "17.2.5" (bar 17, beat 2.5) into seconds
+func (bs *Shell) parseBarBeat(track int, s string) (float64, error) {
+	parts := strings.SplitN(s, ".", 2)
+
+	bar, err := strconv.Atoi(parts[0])
+	if err != nil || bar < 1 {
+		return 0, fmt.Errorf("invalid bar: %s", s)
+	}
+
+	beat := 1.0
+	if len(parts) == 2 && parts[1] != "" {
+		beat, err = strconv.ParseFloat(parts[1], 64)
+		if err != nil || beat < 1 || beat >= beatsPerBar+1 {
+			return 0, fmt.Errorf("invalid beat: %s", s)
+		}
+	}
+
+	return bs.barBeatToSeconds(track, bar, beat), nil
+}
+
+// parseTime reads a position on a track's timeline: 45.2s is seconds, 17.1b is
+// bar.beat, and a plain number follows the shell's timebase
+func (bs *Shell) parseTime(track int, s string) (float64, error) {
+	switch {
+	case strings.HasSuffix(s, "s"):
+		return strconv.ParseFloat(strings.TrimSuffix(s, "s"), 64)
+	case strings.HasSuffix(s, "b"):
+		return bs.parseBarBeat(track, strings.TrimSuffix(s, "b"))
+	case bs.Timebase == TimebaseBars:
+		return bs.parseBarBeat(track, s)
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseShift reads a relative offset at position from on a track's timeline:
+// +2.5s is seconds, +2b is two beats, and a plain number follows the timebase
+// (beats when it is bars)
+func (bs *Shell) parseShift(track int, from float64, s string) (float64, error) {
+	inBeats := bs.Timebase == TimebaseBars
+	switch {
+	case strings.HasSuffix(s, "s"):
+		s, inBeats = strings.TrimSuffix(s, "s"), false
+	case strings.HasSuffix(s, "b"):
+		s, inBeats = strings.TrimSuffix(s, "b"), true
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if !inBeats {
+		return value, nil
+	}
+
+	index := bs.secondsToBeat(track, from)
+	return bs.beatToSeconds(track, index+value) - from, nil
+}
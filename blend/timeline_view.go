@@ -0,0 +1,218 @@
+package blend
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"starchive/audio"
+)
+
+// Timeline layout: a label column, then at least timelineMinColumns of track
+const (
+	timelineLabelWidth = 9
+	timelineMinColumns = 40
+)
+
+// handleTimelineViewCommand draws both tracks (or one) across the terminal: bar
+// numbers, energy envelope, beat ticks, gaps, and the active segments placed on each
+func (bs *Shell) handleTimelineViewCommand(args []string) {
+	tracks := []int{1, 2}
+	if len(args) > 0 {
+		switch args[0] {
+		case "1":
+			tracks = []int{1}
+		case "2":
+			tracks = []int{2}
+		default:
+			fmt.Fprintf(bs.Out, "Usage: timeline [1|2]\n")
+			return
+		}
+	}
+
+	columns := readline.GetScreenWidth() - timelineLabelWidth - 1
+	if columns < timelineMinColumns {
+		columns = timelineMinColumns
+	}
+
+	for _, track := range tracks {
+		bs.printTrackTimeline(track, columns)
+	}
+	fmt.Fprintf(bs.Out, "Beats: ┃ bar, ╵ beat. Gaps: ░. Segments: [track:seg] blocks from the other track\n")
+}
+
+// printTrackTimeline draws one track scaled so its whole length fits columns
+func (bs *Shell) printTrackTimeline(track, columns int) {
+	id, inputPath := bs.ID1, bs.InputPath1
+	if track == 2 {
+		id, inputPath = bs.ID2, bs.InputPath2
+	}
+	duration := bs.trackDuration(track)
+	if duration <= 0 {
+		fmt.Fprintf(bs.Out, "Track %d (%s): length unknown\n\n", track, id)
+		return
+	}
+
+	gaps := bs.trackGaps(track) // May analyze the track, so before anything is drawn
+
+	perColumn := duration / float64(columns)
+	column := func(seconds float64) int {
+		col := int(seconds / perColumn)
+		if col < 0 {
+			return 0
+		}
+		if col >= columns {
+			return columns - 1
+		}
+		return col
+	}
+	row := func(label, content string) {
+		fmt.Fprintf(bs.Out, "  %-*s%s\n", timelineLabelWidth-2, label, content)
+	}
+
+	fmt.Fprintf(bs.Out, "Track %d (%s %s): %.1fs, 1 column = %.2fs\n", track, id, bs.stemMixDescription(track), duration, perColumn)
+
+	row("bars", bs.barRuler(track, columns, perColumn))
+
+	if peaks, err := audio.EnsurePeaks(inputPath, false); err == nil {
+		row("energy", peaks.Sparkline(columns))
+	} else {
+		row("energy", fmt.Sprintf("(unavailable: %v)", strings.SplitN(err.Error(), "\n", 2)[0]))
+	}
+
+	beats := append([]float64{}, bs.Beats1...)
+	if track == 2 {
+		beats = append([]float64{}, bs.Beats2...)
+	}
+	if len(beats) == 0 {
+		row("beats", fmt.Sprintf("(none detected, run beat-detect %d)", track))
+	} else {
+		// Too dense to tell apart, beats are left out and bars follow the ruler's step
+		sort.Float64s(beats)
+		_, secondsPerBeat := bs.beatGrid(track)
+		step := bs.barStep(track, perColumn)
+		line := blankRow(columns)
+		for i, beat := range beats {
+			col := column(beat)
+			if i%beatsPerBar == 0 && (i/beatsPerBar)%step == 0 {
+				line[col] = '┃'
+			} else if secondsPerBeat >= perColumn && line[col] == ' ' {
+				line[col] = '╵'
+			}
+		}
+		row("beats", string(line))
+	}
+
+	if len(gaps) == 0 {
+		row("gaps", "(none found)")
+	} else {
+		line := blankRow(columns)
+		for _, gap := range gaps {
+			for col := column(gap.StartTime); col <= column(gap.StartTime+gap.Duration); col++ {
+				line[col] = '░'
+			}
+		}
+		row("gaps", string(line))
+	}
+
+	// Segments from the other track, placed on this one; overlapping blocks stack
+	source := otherTrack(track)
+	segments := bs.Segments1
+	if source == 2 {
+		segments = bs.Segments2
+	}
+
+	var lanes [][]rune
+	for i, seg := range segments {
+		if !seg.Active {
+			continue
+		}
+		length := bs.segmentLength(source, seg) * bs.trackSpeed(track) // In this track's seconds
+		from, to := column(seg.Placement), column(seg.Placement+length)
+		block := segmentBlock(fmt.Sprintf("%d:%d", source, i+1), to-from+1)
+
+		lane := -1
+		for l := range lanes {
+			if laneFree(lanes[l], from, to) {
+				lane = l
+				break
+			}
+		}
+		if lane < 0 {
+			lanes = append(lanes, blankRow(columns))
+			lane = len(lanes) - 1
+		}
+		copy(lanes[lane][from:], block)
+	}
+
+	label := "from " + strconv.Itoa(source)
+	if len(lanes) == 0 {
+		row(label, "(no active segments)")
+	}
+	for l, lane := range lanes {
+		if l > 0 {
+			label = ""
+		}
+		row(label, string(lane))
+	}
+	fmt.Fprintf(bs.Out, "\n")
+}
+
+// barRuler numbers bars on a track's grid, every 1, 2, 4, ... bars so labels fit
+func (bs *Shell) barRuler(track, columns int, perColumn float64) string {
+	line := blankRow(columns)
+	step := bs.barStep(track, perColumn)
+
+	next := 0 // First column free for a label
+	for bar := 1; ; bar += step {
+		col := int(bs.barBeatToSeconds(track, bar, 1) / perColumn)
+		if col >= columns {
+			break
+		}
+		label := []rune(strconv.Itoa(bar))
+		if col < next || col+len(label) > columns {
+			continue
+		}
+		copy(line[col:], label)
+		next = col + len(label) + 1
+	}
+	return string(line)
+}
+
+// barStep returns how many bars apart the ruler labels are, a power of two that
+// leaves room for the numbers
+func (bs *Shell) barStep(track int, perColumn float64) int {
+	secondsPerBar := bs.beatToSeconds(track, beatsPerBar) - bs.beatToSeconds(track, 0)
+	step := 1
+	for secondsPerBar > 0 && float64(step)*secondsPerBar/perColumn < 6 && step < 1024 {
+		step *= 2
+	}
+	return step
+}
+
+// segmentBlock draws a placed segment width columns wide: [1:3====] when the label
+// fits, the label alone or a solid bar when it does not
+func segmentBlock(label string, width int) []rune {
+	switch {
+	case width >= len(label)+2:
+		return []rune("[" + label + strings.Repeat("=", width-len(label)-2) + "]")
+	case width >= len(label):
+		return []rune(label + strings.Repeat("=", width-len(label)))
+	}
+	return []rune(strings.Repeat("█", width))
+}
+
+func laneFree(lane []rune, from, to int) bool {
+	for col := from; col <= to; col++ {
+		if lane[col] != ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+func blankRow(columns int) []rune {
+	return []rune(strings.Repeat(" ", columns))
+}
@@ -0,0 +1,332 @@
+package blend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/chzyer/readline"
+)
+
+const (
+	transportSeekStep   = 5.0 // Seconds moved by the arrow keys
+	transportVolumeStep = 5.0 // Percent changed by the volume keys
+)
+
+// transport drives the mixer during play: seeking, pausing, A/B loops and live
+// volume changes. Seeking rebuilds only the decoders; the audio sink and the
+// recording keep running.
+type transport struct {
+	bs                       *Shell
+	ctx                      context.Context
+	start1, start2, duration float64
+	origin                   float64 // Grid track position (its own seconds) at transport position 0
+
+	mu                 sync.Mutex
+	mixer              *Mixer
+	offset             float64 // Blend position where the current mixer starts
+	baseVol1, baseVol2 float64 // Track volumes baked into the current mixer's graph
+	paused, ended      bool
+	loopA, loopB       float64 // Loop points, -1 when unset
+}
+
+// filterInputRune hands keys to a running transport instead of the line editor
+func (bs *Shell) filterInputRune(r rune) (rune, bool) {
+	bs.keysMu.Lock()
+	keys := bs.keys
+	bs.keysMu.Unlock()
+
+	if keys == nil {
+		return r, true
+	}
+	select {
+	case keys <- r:
+	default:
+	}
+	return r, false
+}
+
+func (bs *Shell) setTransportKeys(keys chan rune) {
+	bs.keysMu.Lock()
+	bs.keys = keys
+	bs.keysMu.Unlock()
+}
+
+// runTransport plays the blend with interactive controls until the user stops it.
+// Every sample sent to the speakers is also written to recorder.
+func (bs *Shell) runTransport(startPosition1, startPosition2, maxAvailableDuration float64, recorder *wavRecorder) (float64, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t := &transport{
+		bs:       bs,
+		ctx:      ctx,
+		start1:   startPosition1,
+		start2:   startPosition2,
+		duration: maxAvailableDuration,
+		loopA:    -1,
+		loopB:    -1,
+	}
+	// Transport positions count from the play start; the loop region is on the grid track
+	grid := bs.gridTrack()
+	t.origin = startPosition1 - bs.Window1
+	if grid == 2 {
+		t.origin = startPosition2 - bs.Window2
+	}
+	if bs.LoopEnd > bs.LoopStart && bs.LoopEnd > t.origin {
+		t.loopA = math.Max(t.toMix(bs.LoopStart), 0)
+		t.loopB = t.toMix(bs.LoopEnd)
+	}
+
+	if err := t.seek(0); err != nil {
+		return 0, err
+	}
+	defer func() {
+		t.mu.Lock()
+		t.mixer.Close()
+		t.mu.Unlock()
+	}()
+
+	sink, sinkIn, err := startSink(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	keys := make(chan rune, 16)
+	bs.setTransportKeys(keys)
+	defer bs.setTransportKeys(nil)
+
+	bs.rl.Terminal.EnterRawMode()
+	defer bs.rl.Terminal.ExitRawMode()
+	bs.rl.Terminal.KickRead()
+
+	fmt.Fprintf(bs.Out, "space pause | ←/→ seek %.0fs | [/] seek 1 bar | a/b set loop, c clear | 1/2 vol1 -/+ | 9/0 vol2 -/+ | enter/x stop\r\n", transportSeekStep)
+
+	audioDone := make(chan error, 1)
+	go func() {
+		defer sink.Wait()
+		defer sinkIn.Close()
+		audioDone <- t.pump(sinkIn, recorder)
+	}()
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case r := <-keys:
+			if !t.handleKey(r) {
+				t.mu.Lock()
+				position := t.position()
+				t.mu.Unlock()
+				cancel()
+				err := <-audioDone
+				fmt.Fprintf(bs.Out, "\r\n")
+				return position, err
+			}
+			t.printStatus()
+		case err := <-audioDone:
+			fmt.Fprintf(bs.Out, "\r\n")
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			return t.position(), err
+		case <-ticker.C:
+			t.printStatus()
+		}
+	}
+}
+
+// pump moves mixed audio to the sink and the recording until ctx is cancelled
+func (t *transport) pump(sink io.Writer, recorder *wavRecorder) error {
+	for t.ctx.Err() == nil {
+		t.mu.Lock()
+		if t.paused || t.ended {
+			t.mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			continue
+		}
+
+		if t.looping() && t.position() >= t.loopB {
+			if err := t.seek(t.loopA); err != nil {
+				t.mu.Unlock()
+				return err
+			}
+		}
+
+		samples, err := t.mixer.Next(2048)
+		if err == io.EOF {
+			if t.looping() {
+				err = t.seek(t.loopA)
+			} else {
+				// Stay open at the end so the user can seek back
+				t.ended = true
+				err = nil
+			}
+			t.mu.Unlock()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		t.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		if err := writeSamples(sink, samples); err != nil {
+			return nil // Sink closed, e.g. the player was stopped
+		}
+		if recorder != nil {
+			if err := recorder.Write(samples); err != nil {
+				return fmt.Errorf("recording failed: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// seek restarts the decoders at pos seconds into the blend. Callers hold t.mu.
+func (t *transport) seek(pos float64) error {
+	if pos < 0 {
+		pos = 0
+	}
+	if pos > t.duration-0.1 {
+		pos = t.duration - 0.1
+	}
+
+	// Each track has moved through its own file at its own speed
+	graph := t.bs.blendGraph(t.start1+pos*t.bs.trackSpeed(1), t.start2+pos*t.bs.trackSpeed(2), t.duration-pos)
+	mixer, err := NewMixer(t.ctx, graph, t.duration-pos)
+	if err != nil {
+		return err
+	}
+
+	if t.mixer != nil {
+		t.mixer.Close()
+	}
+	t.mixer = mixer
+	t.offset = pos
+	t.baseVol1, t.baseVol2 = t.bs.Volume1, t.bs.Volume2
+	t.ended = false
+	return nil
+}
+
+// position returns the blend position in seconds. Callers hold t.mu.
+func (t *transport) position() float64 {
+	return t.offset + t.mixer.Position()
+}
+
+// moveBars returns the transport position bars away on the grid track's beat grid.
+// Callers hold t.mu.
+func (t *transport) moveBars(bars int) float64 {
+	grid := t.bs.gridTrack()
+	index := t.bs.secondsToBeat(grid, t.toGrid(t.position()))
+	return t.toMix(t.bs.beatToSeconds(grid, index+float64(bars*beatsPerBar)))
+}
+
+// toGrid converts a transport position to the grid track's own seconds
+func (t *transport) toGrid(pos float64) float64 {
+	return t.origin + pos*t.bs.trackSpeed(t.bs.gridTrack())
+}
+
+// toMix converts a position in the grid track's own seconds to a transport position
+func (t *transport) toMix(seconds float64) float64 {
+	return (seconds - t.origin) / t.bs.trackSpeed(t.bs.gridTrack())
+}
+
+func (t *transport) looping() bool {
+	return t.loopA >= 0 && t.loopB > t.loopA
+}
+
+// handleKey applies one key press; it returns false when playback should stop
+func (t *transport) handleKey(r rune) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var err error
+	switch r {
+	case readline.CharEnter, readline.CharCtrlJ, readline.CharInterrupt, 'x', 'q':
+		return false
+	case ' ':
+		t.paused = !t.paused
+	case readline.CharBackward, 'h':
+		err = t.seek(t.position() - transportSeekStep)
+	case readline.CharForward, 'l':
+		err = t.seek(t.position() + transportSeekStep)
+	case '[':
+		err = t.seek(t.moveBars(-1))
+	case ']':
+		err = t.seek(t.moveBars(1))
+	case 'a':
+		t.loopA = t.position()
+		if t.loopB <= t.loopA {
+			t.loopB = -1
+		}
+	case 'b':
+		if pos := t.position(); pos > t.loopA {
+			t.loopB = pos
+			if t.loopA < 0 {
+				t.loopA = 0
+			}
+			err = t.seek(t.loopA)
+		}
+	case 'c':
+		t.loopA, t.loopB = -1, -1
+	case '1':
+		err = t.nudgeVolume(1, -transportVolumeStep)
+	case '2':
+		err = t.nudgeVolume(1, transportVolumeStep)
+	case '9':
+		err = t.nudgeVolume(2, -transportVolumeStep)
+	case '0':
+		err = t.nudgeVolume(2, transportVolumeStep)
+	}
+
+	if err != nil {
+		fmt.Fprintf(t.bs.Out, "\r\nError: %v\r\n", err)
+	}
+	return true
+}
+
+// nudgeVolume changes a track's volume while playing. The mixer applies the change
+// as a gain; only a track coming back from 0% needs its decoders rebuilt.
+func (t *transport) nudgeVolume(track int, delta float64) error {
+	volume, base := &t.bs.Volume1, t.baseVol1
+	if track == 2 {
+		volume, base = &t.bs.Volume2, t.baseVol2
+	}
+
+	*volume = clampFloat(*volume+delta, 0.0, 200.0)
+	if base == 0 {
+		return t.seek(t.position())
+	}
+	t.mixer.SetTrackGain(track, *volume/base)
+	return nil
+}
+
+// printStatus redraws the position readout line
+func (t *transport) printStatus() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := "playing"
+	if t.paused {
+		state = "paused"
+	} else if t.ended {
+		state = "end"
+	}
+
+	loop := ""
+	if t.looping() {
+		loop = fmt.Sprintf("  loop %.1f-%.1f", t.loopA, t.loopB)
+	} else if t.loopA >= 0 {
+		loop = fmt.Sprintf("  loop A %.1f", t.loopA)
+	}
+
+	fmt.Fprintf(t.bs.Out, "\r\033[K[%s] %6.1fs / %.1fs  bar %s  vol %.0f%%/%.0f%%%s",
+		state, t.position(), t.duration, t.bs.formatBarBeat(t.bs.gridTrack(), t.toGrid(t.position())),
+		t.bs.Volume1, t.bs.Volume2, loop)
+}
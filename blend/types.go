@@ -1,6 +1,10 @@
 package blend
 
 import (
+	"io"
+	"sync"
+
+	"github.com/chzyer/readline"
 	"starchive/util"
 )
 
@@ -12,11 +16,38 @@ type VocalSegment struct {
 	Index     int     `json:"index"`
 	StartTime float64 `json:"start_time"`
 	Duration  float64 `json:"duration"`
-	Placement float64 `json:"placement"` // Where to place in target track
+	Placement float64 `json:"placement"` // Where to place in target track, in its original seconds
 	Active    bool    `json:"active"`    // Whether this segment is enabled
 	RMSEnergy float64 `json:"rms_energy"` // Root Mean Square energy level (0.0-1.0)
 	PeakLevel float64 `json:"peak_level"` // Peak amplitude level (0.0-1.0)
 	EnergyCategory string `json:"energy_category"` // "low", "medium", "high"
+	FadeIn    float64 `json:"fade_in,omitempty"`  // Fade-in length in seconds
+	FadeOut   float64 `json:"fade_out,omitempty"` // Fade-out length in seconds
+	Effects   []SegmentEffect `json:"effects,omitempty"` // Applied in order when the segment plays
+}
+
+// SegmentEffect is one creative effect on a segment. Timings are in beats of the
+// target track so effects stay in time with the music.
+type SegmentEffect struct {
+	Type      string `json:"type"`                // reverse, stutter, chop, echo or harmony
+	Count     int    `json:"count,omitempty"`     // Stutter repeats or echo copies
+	Division  int    `json:"division,omitempty"`  // Chop note value: 4 = 1/4, 8 = 1/8
+	Semitones []int  `json:"semitones,omitempty"` // Harmony layer pitch shifts
+}
+
+// AutomationPoint is one breakpoint of a volume envelope
+type AutomationPoint struct {
+	Time  float64 `json:"time"`  // Position in the track's original seconds
+	Value float64 `json:"value"` // Volume percentage, 0-200
+}
+
+// StemChannel is one stem file contributing to a track's mix
+type StemChannel struct {
+	Name   string  `json:"name"`   // Stem name, e.g. "vocals", "drums"
+	Path   string  `json:"path"`
+	Volume float64 `json:"volume"` // Percentage, 0-200
+	Mute   bool    `json:"mute"`
+	Solo   bool    `json:"solo"`
 }
 
 // Shell represents the blend shell for mixing two tracks
@@ -35,6 +66,22 @@ type Shell struct {
 	Segments1, Segments2 []VocalSegment // Vocal segments for each track
 	SegmentsDir1, SegmentsDir2 string   // Directories containing split files
 	Beats1, Beats2 []float64           // Beat positions in seconds for each track
+	Stems1, Stems2 []StemChannel       // Stems mixed together to form each track
+	Engine         string              // Pitch/tempo engine: fast, rubberband or prerender
+	Timebase       string              // How plain numbers are read: seconds or bars
+	LoopStart, LoopEnd float64         // Loop region in seconds on the grid track (end <= start means none)
+	Automation1, Automation2 []AutomationPoint // Volume envelopes; when set they replace Volume1/Volume2
+	AutoFade       bool                // Add short anti-click fades to every track and segment edge
+	History        []string            // Commands run this session; stochastic ones include their --seed
+	Headless       bool                // No terminal or speakers (the web editor): play renders instead
+	LastRender     string              // Most recent mix saved by play or render
+	Out            io.Writer           // Where commands report; os.Stdout unless Exec is capturing
+
+	rl     *readline.Instance // Line editor, also the key source for the play transport
+	gaps   map[int][]VocalGap // Gap-finder results per track, see trackGaps
+	loudness map[string]float64 // Mean level in dB per stem file, see stemLoudness
+	keys   chan rune          // Receives key presses while the transport is running
+	keysMu sync.Mutex
 }
 
 // InvertState stores the state for intelligent track matching
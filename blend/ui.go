@@ -11,6 +11,7 @@ import (
 func (bs *Shell) Completer() readline.AutoCompleter {
 	return readline.NewPrefixCompleter(
 		readline.PcItem("play"),
+		readline.PcItem("render"),
 		readline.PcItem("pitch1"),
 		readline.PcItem("pitch2"), 
 		readline.PcItem("tempo1"),
@@ -18,6 +19,31 @@ func (bs *Shell) Completer() readline.AutoCompleter {
 		readline.PcItem("volume1"),
 		readline.PcItem("volume2"),
 		readline.PcItem("window"),
+		readline.PcItem("engine",
+			readline.PcItem("fast"),
+			readline.PcItem("rubberband"),
+			readline.PcItem("prerender"),
+		),
+		readline.PcItem("timebase",
+			readline.PcItem("seconds"),
+			readline.PcItem("bars"),
+		),
+		readline.PcItem("timeline",
+			readline.PcItem("1"),
+			readline.PcItem("2"),
+		),
+		readline.PcItem("loop",
+			readline.PcItem("off"),
+		),
+		readline.PcItem("fade"),
+		readline.PcItem("automate",
+			readline.PcItem("volume1"),
+			readline.PcItem("volume2"),
+		),
+		readline.PcItem("crossfade-auto",
+			readline.PcItem("on"),
+			readline.PcItem("off"),
+		),
 		readline.PcItem("match",
 			readline.PcItem("bpm1to2"),
 			readline.PcItem("bpm2to1"),
@@ -27,11 +53,19 @@ func (bs *Shell) Completer() readline.AutoCompleter {
 		readline.PcItem("type1",
 			readline.PcItem("vocal"),
 			readline.PcItem("instrumental"),
+			readline.PcItem("drums"),
+			readline.PcItem("bass"),
+			readline.PcItem("other"),
 		),
 		readline.PcItem("type2",
 			readline.PcItem("vocal"),
 			readline.PcItem("instrumental"),
+			readline.PcItem("drums"),
+			readline.PcItem("bass"),
+			readline.PcItem("other"),
 		),
+		readline.PcItem("stems"),
+		readline.PcItem("stem"),
 		readline.PcItem("split",
 			readline.PcItem("1"),
 			readline.PcItem("2"),
@@ -60,7 +94,35 @@ func (bs *Shell) Completer() readline.AutoCompleter {
 		readline.PcItem("place"),
 		readline.PcItem("shift"),
 		readline.PcItem("toggle"),
+		readline.PcItem("reverse"),
+		readline.PcItem("stutter"),
+		readline.PcItem("vocal-chop"),
+		readline.PcItem("echo-place"),
+		readline.PcItem("harmony-stack"),
+		readline.PcItem("effects"),
+		readline.PcItem("score"),
+		readline.PcItem("auto-arrange",
+			readline.PcItem("minimal"),
+			readline.PcItem("dense"),
+			readline.PcItem("call-response"),
+			readline.PcItem("harmony-heavy"),
+		),
+		readline.PcItem("magic-blend",
+			readline.PcItem("minimal"),
+			readline.PcItem("dense"),
+			readline.PcItem("call-response"),
+			readline.PcItem("harmony-heavy"),
+		),
+		readline.PcItem("template",
+			readline.PcItem("save"),
+			readline.PcItem("apply"),
+			readline.PcItem("list"),
+		),
 		readline.PcItem("preview"),
+		readline.PcItem("call-response",
+			readline.PcItem("1"),
+			readline.PcItem("2"),
+		),
 		readline.PcItem("random",
 			readline.PcItem("1"),
 			readline.PcItem("2"),
@@ -68,6 +130,7 @@ func (bs *Shell) Completer() readline.AutoCompleter {
 		readline.PcItem("invert"),
 		readline.PcItem("reset"),
 		readline.PcItem("status"),
+		readline.PcItem("history"),
 		readline.PcItem("help"),
 		readline.PcItem("exit"),
 	)
@@ -75,22 +138,34 @@ func (bs *Shell) Completer() readline.AutoCompleter {
 
 // ShowStatus displays the current blend settings
 func (bs *Shell) ShowStatus() {
-	fmt.Printf("--- Current Settings ---\n")
-	fmt.Printf("Track 1 (%s %s): pitch %+d, tempo %+.1f%%, volume %.0f%%, window %+.1fs\n", 
-		bs.ID1, bs.getTrackTypeDesc(bs.Type1), bs.Pitch1, bs.Tempo1, bs.Volume1, bs.Window1)
-	fmt.Printf("Track 2 (%s %s): pitch %+d, tempo %+.1f%%, volume %.0f%%, window %+.1fs\n", 
-		bs.ID2, bs.getTrackTypeDesc(bs.Type2), bs.Pitch2, bs.Tempo2, bs.Volume2, bs.Window2)
+	fmt.Fprintf(bs.Out, "--- Current Settings ---\n")
+	fmt.Fprintf(bs.Out, "Track 1 (%s %s): pitch %+d, tempo %+.1f%%, volume %.0f%%, window %+.1fs\n", 
+		bs.ID1, bs.stemMixDescription(1), bs.Pitch1, bs.Tempo1, bs.Volume1, bs.Window1)
+	fmt.Fprintf(bs.Out, "Track 2 (%s %s): pitch %+d, tempo %+.1f%%, volume %.0f%%, window %+.1fs\n", 
+		bs.ID2, bs.stemMixDescription(2), bs.Pitch2, bs.Tempo2, bs.Volume2, bs.Window2)
+		
+	fmt.Fprintf(bs.Out, "Pitch/tempo engine: %s, timebase: %s\n", bs.Engine, bs.Timebase)
+	if bs.LoopEnd > bs.LoopStart {
+		grid := bs.gridTrack()
+		fmt.Fprintf(bs.Out, "Loop: bars %s - %s\n", bs.formatBarBeat(grid, bs.LoopStart), bs.formatBarBeat(grid, bs.LoopEnd))
+	}
+	if len(bs.Automation1) > 0 || len(bs.Automation2) > 0 {
+		fmt.Fprintf(bs.Out, "Volume automation: Track 1: %d points, Track 2: %d points\n", len(bs.Automation1), len(bs.Automation2))
+	}
+	if bs.AutoFade {
+		fmt.Fprintf(bs.Out, "Auto anti-click fades: on\n")
+	}
 		
 	if bs.Metadata1 != nil && bs.Metadata1.BPM != nil && bs.Metadata1.Key != nil {
 		effectiveBPM1 := audio.CalculateEffectiveBPM(*bs.Metadata1.BPM, bs.Tempo1)
 		effectiveKey1 := audio.CalculateEffectiveKey(*bs.Metadata1.Key, bs.Pitch1)
-		fmt.Printf("  Effective: %.1f BPM, %s (was %.1f BPM, %s)\n", 
+		fmt.Fprintf(bs.Out, "  Effective: %.1f BPM, %s (was %.1f BPM, %s)\n", 
 			effectiveBPM1, effectiveKey1, *bs.Metadata1.BPM, *bs.Metadata1.Key)
 	}
 	if bs.Metadata2 != nil && bs.Metadata2.BPM != nil && bs.Metadata2.Key != nil {
 		effectiveBPM2 := audio.CalculateEffectiveBPM(*bs.Metadata2.BPM, bs.Tempo2)
 		effectiveKey2 := audio.CalculateEffectiveKey(*bs.Metadata2.Key, bs.Pitch2)
-		fmt.Printf("  Effective: %.1f BPM, %s (was %.1f BPM, %s)\n", 
+		fmt.Fprintf(bs.Out, "  Effective: %.1f BPM, %s (was %.1f BPM, %s)\n", 
 			effectiveBPM2, effectiveKey2, *bs.Metadata2.BPM, *bs.Metadata2.Key)
 	}
 	
@@ -105,58 +180,96 @@ func (bs *Shell) ShowStatus() {
 	}
 	
 	if len(bs.Segments1) > 0 || len(bs.Segments2) > 0 {
-		fmt.Printf("Segments: Track 1: %d/%d active, Track 2: %d/%d active\n", 
+		fmt.Fprintf(bs.Out, "Segments: Track 1: %d/%d active, Track 2: %d/%d active\n", 
 			activeSegments1, len(bs.Segments1), activeSegments2, len(bs.Segments2))
 	}
 	
 	if len(bs.Beats1) > 0 || len(bs.Beats2) > 0 {
-		fmt.Printf("Beats: Track 1: %d detected, Track 2: %d detected\n",
+		fmt.Fprintf(bs.Out, "Beats: Track 1: %d detected, Track 2: %d detected\n",
 			len(bs.Beats1), len(bs.Beats2))
 	}
-	fmt.Printf("\n")
+	fmt.Fprintf(bs.Out, "\n")
 }
 
 // ShowHelp displays detailed help information
 func (bs *Shell) ShowHelp() {
-	fmt.Printf("--- Blend Shell Commands ---\n")
-	fmt.Printf("Playback:\n")
-	fmt.Printf("  play [start_pos]    Play current blend (press any key to stop)\n")
-	fmt.Printf("                      start_pos: seconds (default: middle, 0 = beginning)\n")
-	fmt.Printf("Adjustments:\n")
-	fmt.Printf("  pitch1 <n>          Adjust track 1 pitch (-12 to +12 semitones)\n")
-	fmt.Printf("  pitch2 <n>          Adjust track 2 pitch (-12 to +12 semitones)\n")
-	fmt.Printf("  tempo1 <n>          Adjust track 1 tempo (-50 to +100%%)\n")
-	fmt.Printf("  tempo2 <n>          Adjust track 2 tempo (-50 to +100%%)\n")
-	fmt.Printf("  volume1 <n>         Set track 1 volume (0 to 200)\n")
-	fmt.Printf("  volume2 <n>         Set track 2 volume (0 to 200)\n")
-	fmt.Printf("  window <n1> <n2>    Set start offsets from middle (seconds)\n")
-	fmt.Printf("Matching:\n")
-	fmt.Printf("  match bpm1to2       Match track 1 BPM to track 2\n")
-	fmt.Printf("  match bpm2to1       Match track 2 BPM to track 1\n")
-	fmt.Printf("  match key1to2       Match track 1 key to track 2\n")
-	fmt.Printf("  match key2to1       Match track 2 key to track 1\n")
-	fmt.Printf("  invert              Reset and intelligently match tracks\n")
-	fmt.Printf("Track Types:\n")
-	fmt.Printf("  type1 <type>        Set track 1 type (vocal/instrumental)\n")
-	fmt.Printf("  type2 <type>        Set track 2 type (vocal/instrumental)\n")
-	fmt.Printf("Vocal Segments:\n")
-	fmt.Printf("  split <1|2>         Split vocal track into segments by silence\n")
-	fmt.Printf("  segments [1|2]      List available segments\n")
-	fmt.Printf("  analyze-segments <1|2> Analyze energy levels of segments\n")
-	fmt.Printf("Beat Detection:\n")
-	fmt.Printf("  beat-detect <1|2|both> Detect beat positions in tracks\n")
-	fmt.Printf("  beats [1|2]         Show detected beat positions\n")
-	fmt.Printf("Gap Analysis:\n")
-	fmt.Printf("  gap-finder <1|2>    Find vocal gaps (low energy periods) for placement\n")
-	fmt.Printf("Segment Placement:\n")
-	fmt.Printf("  place <track:seg> at <time> Place segment (e.g. '1:3 at 45.2')\n")
-	fmt.Printf("  shift <track:seg> <+/-time> Adjust segment timing (e.g. '1:3 +2.5')\n")
-	fmt.Printf("  toggle <track:seg>  Enable/disable segment (e.g. '1:3')\n")
-	fmt.Printf("  preview <track:seg> Preview individual segment (e.g. '1:3')\n")
-	fmt.Printf("  random <1|2>        Randomly place all segments from track\n")
-	fmt.Printf("Utility:\n")
-	fmt.Printf("  reset               Reset all adjustments to zero\n")
-	fmt.Printf("  status              Show current settings\n")
-	fmt.Printf("  exit                Exit blend shell\n")
-	fmt.Printf("\n")
+	fmt.Fprintf(bs.Out, "--- Blend Shell Commands ---\n")
+	fmt.Fprintf(bs.Out, "Playback:\n")
+	fmt.Fprintf(bs.Out, "  play [start_pos]    Play current blend with transport controls (starts at the loop if set):\n")
+	fmt.Fprintf(bs.Out, "                        space pause/resume, ←/→ seek 5s, [/] seek one bar\n")
+	fmt.Fprintf(bs.Out, "                        a/b set loop start/end, c clear loop\n")
+	fmt.Fprintf(bs.Out, "                        1/2 track 1 volume -/+, 9/0 track 2 volume -/+\n")
+	fmt.Fprintf(bs.Out, "                        enter or x stops (the mix is saved to ./data)\n")
+	fmt.Fprintf(bs.Out, "                      start_pos: seconds (default: middle, 0 = beginning)\n")
+	fmt.Fprintf(bs.Out, "  render [start_pos] [seconds] Mix to ./data without playing (default: the loop, else from the middle)\n")
+	fmt.Fprintf(bs.Out, "Timeline:\n")
+	fmt.Fprintf(bs.Out, "  timebase <seconds|bars> How plain times are read (45.2s and 17.1b always work)\n")
+	fmt.Fprintf(bs.Out, "  loop <start> <end>  Loop bars, e.g. 'loop 17 25'; 'loop off' clears\n")
+	fmt.Fprintf(bs.Out, "  timeline [1|2]      Draw each track across the terminal: bars, energy, beat ticks,\n")
+	fmt.Fprintf(bs.Out, "                      gaps and the active segments placed on it\n")
+	fmt.Fprintf(bs.Out, "Adjustments:\n")
+	fmt.Fprintf(bs.Out, "  pitch1 <n>          Adjust track 1 pitch (-12 to +12 semitones)\n")
+	fmt.Fprintf(bs.Out, "  pitch2 <n>          Adjust track 2 pitch (-12 to +12 semitones)\n")
+	fmt.Fprintf(bs.Out, "  tempo1 <n>          Adjust track 1 tempo (-50 to +100%%)\n")
+	fmt.Fprintf(bs.Out, "  tempo2 <n>          Adjust track 2 tempo (-50 to +100%%)\n")
+	fmt.Fprintf(bs.Out, "  volume1 <n>         Set track 1 volume (0 to 200)\n")
+	fmt.Fprintf(bs.Out, "  volume2 <n>         Set track 2 volume (0 to 200)\n")
+	fmt.Fprintf(bs.Out, "  window <n1> <n2>    Set start offsets from middle (1.5s, 2b, or timebase)\n")
+	fmt.Fprintf(bs.Out, "  engine <name>       Pitch/tempo engine: fast (preview), rubberband (ffmpeg filter),\n")
+	fmt.Fprintf(bs.Out, "                      prerender (rubberband CLI, cached on disk)\n")
+	fmt.Fprintf(bs.Out, "Fades & Automation:\n")
+	fmt.Fprintf(bs.Out, "  fade <track:seg> <in> <out> Fade a segment in/out (seconds, e.g. 'fade 1:3 0.5 1.2')\n")
+	fmt.Fprintf(bs.Out, "  automate volume1 <time>=<value> ... Volume envelope (e.g. 'automate volume1 0=100 30=40')\n")
+	fmt.Fprintf(bs.Out, "                      'automate volume1 clear' returns to the volume1 setting\n")
+	fmt.Fprintf(bs.Out, "  crossfade-auto [on|off] Short anti-click fades on every track and segment edge\n")
+	fmt.Fprintf(bs.Out, "Matching:\n")
+	fmt.Fprintf(bs.Out, "  match bpm1to2       Match track 1 BPM to track 2\n")
+	fmt.Fprintf(bs.Out, "  match bpm2to1       Match track 2 BPM to track 1\n")
+	fmt.Fprintf(bs.Out, "  match key1to2       Match track 1 key to track 2\n")
+	fmt.Fprintf(bs.Out, "  match key2to1       Match track 2 key to track 1\n")
+	fmt.Fprintf(bs.Out, "  invert              Reset and intelligently match tracks\n")
+	fmt.Fprintf(bs.Out, "Track Types & Stems:\n")
+	fmt.Fprintf(bs.Out, "  type1 <type>        Set track 1 stems (vocal/instrumental/drums/bass/other, join with +)\n")
+	fmt.Fprintf(bs.Out, "  type2 <type>        Set track 2 stems (e.g. 'type2 drums+bass')\n")
+	fmt.Fprintf(bs.Out, "  stems               List available stems and the current stem mix\n")
+	fmt.Fprintf(bs.Out, "  stem <t:stem> <op>  Adjust one stem: volume <n>, mute, unmute, solo, unsolo\n")
+	fmt.Fprintf(bs.Out, "Vocal Segments:\n")
+	fmt.Fprintf(bs.Out, "  split <1|2>         Split vocal track into segments by silence\n")
+	fmt.Fprintf(bs.Out, "  segments [1|2]      List available segments\n")
+	fmt.Fprintf(bs.Out, "  analyze-segments <1|2> Analyze energy levels of segments\n")
+	fmt.Fprintf(bs.Out, "Beat Detection:\n")
+	fmt.Fprintf(bs.Out, "  beat-detect <1|2|both> Detect beat positions in tracks\n")
+	fmt.Fprintf(bs.Out, "  beats [1|2]         Show detected beat positions\n")
+	fmt.Fprintf(bs.Out, "Gap Analysis:\n")
+	fmt.Fprintf(bs.Out, "  gap-finder <1|2>    Find vocal gaps (low energy periods) for placement\n")
+	fmt.Fprintf(bs.Out, "Segment Placement:\n")
+	fmt.Fprintf(bs.Out, "  place <track:seg> at <time> Place segment on the other track (e.g. '1:3 at 45.2' or '1:3 at 17.1b')\n")
+	fmt.Fprintf(bs.Out, "  shift <track:seg> <+/-time> Adjust segment timing (e.g. '1:3 +2.5' or '1:3 +2b' beats)\n")
+	fmt.Fprintf(bs.Out, "  toggle <track:seg>  Enable/disable segment (e.g. '1:3')\n")
+	fmt.Fprintf(bs.Out, "  preview <track:seg> Preview individual segment (e.g. '1:3')\n")
+	fmt.Fprintf(bs.Out, "  random <1|2> [--seed N] Randomly place all segments from track (same seed, same placements)\n")
+	fmt.Fprintf(bs.Out, "  call-response <1|2> Place track's segments in the pauses between the other track's phrases\n")
+	fmt.Fprintf(bs.Out, "Segment Effects:\n")
+	fmt.Fprintf(bs.Out, "  reverse <track:seg> Toggle playing the segment backwards\n")
+	fmt.Fprintf(bs.Out, "  stutter <track:seg> <n> Repeat the first beat n times (0 removes)\n")
+	fmt.Fprintf(bs.Out, "  vocal-chop <track:seg> <1/4|1/8|1/16|off> Gate the segment in a rhythmic pattern\n")
+	fmt.Fprintf(bs.Out, "  echo-place <track:seg> [n] Add n echoes a beat apart, each quieter (default 3)\n")
+	fmt.Fprintf(bs.Out, "  harmony-stack <track:seg> [semitones...|off] Layer pitch-shifted copies (default 3rd+5th)\n")
+	fmt.Fprintf(bs.Out, "  effects <track:seg> [clear] Show or clear a segment's effects\n")
+	fmt.Fprintf(bs.Out, "Scoring:\n")
+	fmt.Fprintf(bs.Out, "  score               Rate the blend: key, BPM, stretch, overlap, beat alignment, gaps, loudness\n")
+	fmt.Fprintf(bs.Out, "Auto Arrangement:\n")
+	fmt.Fprintf(bs.Out, "  auto-arrange [style] [--seed N] Search for the best placements and preview them\n")
+	fmt.Fprintf(bs.Out, "                      styles: minimal (default), dense, call-response, harmony-heavy\n")
+	fmt.Fprintf(bs.Out, "  magic-blend [style] Split, analyze, detect beats, match, then auto-arrange\n")
+	fmt.Fprintf(bs.Out, "Templates:\n")
+	fmt.Fprintf(bs.Out, "  template save <name>  Save the arrangement as bar positions, gaps, energies, fades and effects\n")
+	fmt.Fprintf(bs.Out, "  template apply <name> Map a saved arrangement onto the current tracks\n")
+	fmt.Fprintf(bs.Out, "  template list       List saved templates\n")
+	fmt.Fprintf(bs.Out, "Utility:\n")
+	fmt.Fprintf(bs.Out, "  reset               Reset all adjustments to zero\n")
+	fmt.Fprintf(bs.Out, "  status              Show current settings\n")
+	fmt.Fprintf(bs.Out, "  history             Show this session's commands, with the seeds random ones used\n")
+	fmt.Fprintf(bs.Out, "  exit                Exit blend shell\n")
+	fmt.Fprintf(bs.Out, "\n")
 }
\ No newline at end of file
@@ -6,6 +6,22 @@ let storedTranscripts = {};
 // Store for current mode
 let currentMode = 'default';
 
+const SERVER_URL = 'http://localhost:3009';
+
+// Call the local server with the API token saved from the popup
+async function apiFetch(path, options = {}) {
+  const { apiToken } = await browser.storage.local.get('apiToken');
+  if (!apiToken) {
+    console.warn('[Starchive] No API token set. Paste the token printed by "starchive run" into the popup.');
+  }
+  const headers = Object.assign({}, options.headers, { 'Authorization': `Bearer ${apiToken || ''}` });
+  const res = await fetch(SERVER_URL + path, Object.assign({}, options, { headers }));
+  if (res.status === 401) {
+    console.error('[Starchive] Server rejected the API token. Check the token in the popup.');
+  }
+  return res;
+}
+
 // Function to collect all YouTube and Google authentication cookies
 async function collectAllYouTubeCookies() {
   console.log(`[Starchive] 🚀 Starting comprehensive cookie collection for YouTube authentication`);
@@ -75,7 +91,7 @@ async function collectAllYouTubeCookies() {
         const isSecure = cookie.name.startsWith('__Secure-') || cookie.name.startsWith('__Host-');
         const hasSession = cookie.name.includes('session') || cookie.name.includes('login') || cookie.name.includes('auth');
         
-        console.log(`[Starchive]   [${idx+1}] ${isCritical ? '🔑' : isSecure ? '🔒' : hasSession ? '👤' : '🍪'} ${cookie.name} = ${cookie.value.substring(0, 20)}... (domain: ${cookie.domain}, secure: ${cookie.secure}, httpOnly: ${cookie.httpOnly})`);
+        console.log(`[Starchive]   [${idx+1}] ${isCritical ? '🔑' : isSecure ? '🔒' : hasSession ? '👤' : '🍪'} ${cookie.name} = [redacted] (domain: ${cookie.domain}, secure: ${cookie.secure}, httpOnly: ${cookie.httpOnly})`);
       });
       
       // Filter to include all cookies, but log why each is included
@@ -124,7 +140,7 @@ async function collectAllYouTubeCookies() {
   const foundCritical = uniqueCookies.filter(c => criticalCookieNames.includes(c.name));
   console.log(`[Starchive] 🔑 Found ${foundCritical.length}/${criticalCookieNames.length} critical authentication cookies:`);
   foundCritical.forEach((cookie, idx) => {
-    console.log(`[Starchive]   [${idx+1}] ${cookie.name}@${cookie.domain} = ${cookie.value.substring(0, 30)}...`);
+    console.log(`[Starchive]   [${idx+1}] ${cookie.name}@${cookie.domain}`);
   });
   
   // Log missing critical cookies
@@ -149,7 +165,7 @@ browser.runtime.onMessage.addListener((msg, sender, sendResponse) => {
   
   if (msg.type === "fetchData") {
     console.log('[Starchive] Fetching data from /data endpoint');
-    fetch("http://localhost:3009/data")
+    apiFetch("/data")
       .then(res => {
         console.log('[Starchive] Response status:', res.status);
         return res.json();
@@ -170,7 +186,7 @@ browser.runtime.onMessage.addListener((msg, sender, sendResponse) => {
     console.log(`[Starchive] Requesting txt for video ID: ${msg.videoId}`);
     console.log(`[Starchive] sendResponse function available:`, typeof sendResponse);
     
-    fetch(`http://localhost:3009/get-txt?id=${msg.videoId}&mode=${currentMode}`)
+    apiFetch(`/get-txt?id=${msg.videoId}&mode=${currentMode}`)
       .then(res => {
         console.log(`[Starchive] Response status for ${msg.videoId}:`, res.status);
         return res.json();
@@ -285,8 +301,8 @@ browser.runtime.onMessage.addListener((msg, sender, sendResponse) => {
         const criticalInPayload = minimalCookies.filter(c => ['SAPISID', 'SID', 'HSID', '__Secure-3PAPISID', 'LOGIN_INFO', 'session_logininfo'].includes(c.name));
         console.log(`[Starchive] 🔑 Critical cookies in payload (${criticalInPayload.length}):`, criticalInPayload.map(c => `${c.name}@${c.domain}`));
 
-        console.log(`[Starchive] 🌐 Sending POST request to http://localhost:3009/youtube`);
-        fetch("http://localhost:3009/youtube", {
+        console.log(`[Starchive] 🌐 Sending POST request to ${SERVER_URL}/youtube`);
+        apiFetch("/youtube", {
           method: "POST",
           headers: {
             "Content-Type": "application/json"
@@ -323,7 +339,7 @@ browser.runtime.onMessage.addListener((msg, sender, sendResponse) => {
       }).catch(err => {
         console.error(`[Starchive] 💥 Cookie collection failed for ${msg.videoId}:`, err);
         console.log(`[Starchive] 🔄 Attempting fallback request without cookies...`);
-        fetch("http://localhost:3009/youtube", {
+        apiFetch("/youtube", {
           method: "POST",
           headers: { "Content-Type": "application/json" },
           body: JSON.stringify({ videoId: msg.videoId })
@@ -332,7 +348,7 @@ browser.runtime.onMessage.addListener((msg, sender, sendResponse) => {
     } catch (err) {
       console.error(`[Starchive] 💀 Critical error in YouTube handler for ${msg.videoId}:`, err);
       console.log(`[Starchive] 🔄 Attempting emergency fallback...`);
-      fetch("http://localhost:3009/youtube", {
+      apiFetch("/youtube", {
         method: "POST",
         headers: { "Content-Type": "application/json" },
         body: JSON.stringify({ videoId: msg.videoId })
@@ -359,7 +375,7 @@ browser.runtime.onMessage.addListener((msg, sender, sendResponse) => {
           httpOnly: !!c.httpOnly
         }));
 
-        fetch("http://localhost:3009/instagram", {
+        apiFetch("/instagram", {
           method: "POST",
           headers: {
             "Content-Type": "application/json"
@@ -381,7 +397,7 @@ browser.runtime.onMessage.addListener((msg, sender, sendResponse) => {
       });
     } catch (err) {
       console.error("Instagram cookie collection failed:", err);
-      fetch("http://localhost:3009/instagram", {
+      apiFetch("/instagram", {
         method: "POST",
         headers: { "Content-Type": "application/json" },
         body: JSON.stringify({ postId: msg.postId })
@@ -391,10 +407,10 @@ browser.runtime.onMessage.addListener((msg, sender, sendResponse) => {
   }
   
   if (msg.type === "sendPOToken") {
-    console.log('[Starchive] Received PO token from content script:', msg.poToken.substring(0, 20) + '...');
+    console.log(`[Starchive] Received PO token from content script ([redacted, ${msg.poToken.length} chars])`);
     
     // Send PO token to backend
-    fetch("http://localhost:3009/po-token", {
+    apiFetch("/po-token", {
       method: "POST",
       headers: {
         "Content-Type": "application/json"
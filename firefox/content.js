@@ -24,7 +24,7 @@ function extractAndSendPOToken() {
     if (script.textContent && script.textContent.includes('poToken')) {
       const poTokenMatch = script.textContent.match(/['""]poToken['""]:\s*['""]([^'""]+)['""][,}]/);
       if (poTokenMatch && poTokenMatch[1]) {
-        console.log('[Starchive] Found PO token in script:', poTokenMatch[1]);
+        console.log('[Starchive] Found PO token in script');
         sendPOTokenToBackend(poTokenMatch[1]);
         return;
       }
@@ -37,7 +37,7 @@ function extractAndSendPOToken() {
     const jsonStr = JSON.stringify(window.ytInitialData);
     const poTokenMatch = jsonStr.match(/['""]poToken['""]:\s*['""]([^'""]+)['""][,}]/);
     if (poTokenMatch && poTokenMatch[1]) {
-      console.log('[Starchive] Found PO token in ytInitialData:', poTokenMatch[1]);
+      console.log('[Starchive] Found PO token in ytInitialData');
       sendPOTokenToBackend(poTokenMatch[1]);
       return;
     }
@@ -47,7 +47,7 @@ function extractAndSendPOToken() {
 }
 
 function sendPOTokenToBackend(poToken) {
-  console.log('[Starchive] Sending PO token to backend:', poToken.substring(0, 20) + '...');
+  console.log(`[Starchive] Sending PO token to backend ([redacted, ${poToken.length} chars])`);
   
   browser.runtime.sendMessage({
     type: "sendPOToken",
@@ -149,8 +149,26 @@ document.getElementById('podpapyrusButton').addEventListener('click', () => {
   }
 });
 
+document.getElementById('saveTokenButton').addEventListener('click', () => {
+  const input = document.getElementById('apiTokenInput');
+  const resultDiv = document.getElementById('result');
+  const apiToken = input.value.trim();
+
+  browser.storage.local.set({ apiToken }).then(() => {
+    console.log('[Starchive] API token saved');
+    resultDiv.textContent = apiToken ? '✅ API token saved' : 'API token cleared';
+    resultDiv.style.background = '#d4edda';
+    resultDiv.style.color = '#155724';
+  });
+});
+
 // Initialize popup with current mode when it loads
 document.addEventListener('DOMContentLoaded', () => {
+  browser.storage.local.get('apiToken').then(({ apiToken }) => {
+    document.getElementById('apiTokenInput').value = apiToken || '';
+  });
+
+
   console.log('[Starchive] Popup loaded, getting current mode from background script');
   browser.runtime.sendMessage({ type: "getMode" }, (response) => {
     if (response && response.mode) {
@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"os/exec"
@@ -88,70 +88,63 @@ func HandleLs() {
 }
 
 func HandleVocal() {
-	if len(os.Args) < 3 {
-		fmt.Println("Usage: starchive vocal <id>")
-		fmt.Println("Example: starchive vocal abc123")
-		os.Exit(1)
-	}
+	vocalCmd := flag.NewFlagSet("vocal", flag.ExitOnError)
+	model := vocalCmd.String("model", audio.DefaultSeparator, "Separation model: "+strings.Join(audio.SeparatorNames(), ", "))
 
-	id := os.Args[2]
-	inputPath := fmt.Sprintf("./data/%s.wav", id)
+	args := os.Args[2:]
+	var id string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id = args[0]
+		args = args[1:]
+	}
+	if err := vocalCmd.Parse(args); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		os.Exit(2)
+	}
+	if id == "" && vocalCmd.NArg() > 0 {
+		id = vocalCmd.Arg(0)
+	}
 
-	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
-		fmt.Printf("Error: Input file %s does not exist\n", inputPath)
+	if id == "" {
+		fmt.Println("Usage: starchive vocal <id> [--model uvr|demucs]")
+		fmt.Println("Example: starchive vocal abc123")
+		fmt.Println("         starchive vocal abc123 --model demucs  (drums/bass/other/vocals)")
 		os.Exit(1)
 	}
 
-	cmd := exec.Command("audio-separator", inputPath,
-		"--output_dir", "./data/",
-		"--model_filename", "UVR_MDXNET_Main.onnx",
-		"--output_format", "wav")
-
-	fmt.Printf("Running: %s\n", cmd.String())
-	
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	
-	err := cmd.Run()
+	sep, err := audio.GetSeparator(*model)
 	if err != nil {
-		fmt.Printf("Error running audio-separator: %v\n", err)
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if strings.HasPrefix(id, "_") {
-		expectedVocalPath := audio.GetVocalFilePath(id)
-		expectedInstrumentalPath := audio.GetInstrumentalFilePath(id)
-		
-		strippedID := strings.TrimPrefix(id, "_")
-		actualVocalPath := audio.GetVocalFilePath(strippedID)
-		actualInstrumentalPath := audio.GetInstrumentalFilePath(strippedID)
-		
-		if _, err := os.Stat(actualVocalPath); err == nil {
-			if err := os.Rename(actualVocalPath, expectedVocalPath); err != nil {
-				fmt.Printf("Warning: Could not rename vocal file: %v\n", err)
-			} else {
-				fmt.Printf("Renamed: %s -> %s\n", filepath.Base(actualVocalPath), filepath.Base(expectedVocalPath))
-			}
-		}
-		
-		if _, err := os.Stat(actualInstrumentalPath); err == nil {
-			if err := os.Rename(actualInstrumentalPath, expectedInstrumentalPath); err != nil {
-				fmt.Printf("Warning: Could not rename instrumental file: %v\n", err)
-			} else {
-				fmt.Printf("Renamed: %s -> %s\n", filepath.Base(actualInstrumentalPath), filepath.Base(expectedInstrumentalPath))
-			}
-		}
+	produced, err := sep.Separate(id)
+	if err != nil {
+		fmt.Printf("Error separating stems: %v\n", err)
+		os.Exit(1)
 	}
 
-	fmt.Printf("Successfully separated vocals for %s\n", id)
+	fmt.Printf("Successfully separated %s into %d stems using %s\n", id, len(produced), sep.Name())
 
 	db, err := util.InitDatabase()
 	if err != nil {
-		fmt.Printf("Warning: Could not initialize database to mark vocal as done: %v\n", err)
+		fmt.Printf("Warning: Could not initialize database to record stems: %v\n", err)
 		return
 	}
 	defer db.Close()
 
+	stems, err := audio.RegisterStems(db, id)
+	if err != nil {
+		fmt.Printf("Warning: Could not record stems in database: %v\n", err)
+	}
+	for _, stem := range stems {
+		fmt.Printf("  %-12s %s\n", stem.Stem, filepath.Base(stem.Path))
+	}
+
+	if _, ok := produced[audio.StemVocals]; !ok {
+		return
+	}
+
 	if err := db.MarkVocalDone(id); err != nil {
 		fmt.Printf("Warning: Could not mark vocal as done in database: %v\n", err)
 	} else {
@@ -169,28 +162,18 @@ func HandleBpm() {
 	id := os.Args[2]
 	inputPath := fmt.Sprintf("./data/%s.wav", id)
 
-	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
-		fmt.Printf("Error: Input file %s does not exist\n", inputPath)
-		os.Exit(1)
-	}
-
 	fmt.Printf("Analyzing BPM for %s...\n", id)
-	
-	bpmCmd := exec.Command("python3", "bpm/beats_per_min.py", inputPath)
-	output, err := bpmCmd.CombinedOutput()
+
+	bpm, key, output, err := audio.AnalyzeBPM(inputPath)
 	if err != nil {
 		fmt.Printf("Error analyzing BPM: %v\n", err)
-		fmt.Printf("Output: %s\n", string(output))
+		if len(output) > 0 {
+			fmt.Printf("Output: %s\n", string(output))
+		}
 		os.Exit(1)
 	}
-	
+
 	fmt.Printf("%s\n", string(output))
-	
-	var bpmData map[string]interface{}
-	if err := json.Unmarshal(output, &bpmData); err != nil {
-		fmt.Printf("Error parsing BPM JSON: %v\n", err)
-		os.Exit(1)
-	}
 
 	db, err := util.InitDatabase()
 	if err != nil {
@@ -199,9 +182,6 @@ func HandleBpm() {
 	}
 	defer db.Close()
 
-	bpm := bpmData["bpm"].(float64)
-	key := bpmData["key"].(string)
-
 	if err := db.StoreBPMData(id, bpm, key); err != nil {
 		fmt.Printf("Warning: Could not store BPM data in database: %v\n", err)
 	} else {
@@ -219,28 +199,18 @@ func HandleHz() {
 	id := os.Args[2]
 	inputPath := fmt.Sprintf("./data/%s.wav", id)
 
-	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
-		fmt.Printf("Error: Input file %s does not exist\n", inputPath)
-		os.Exit(1)
-	}
-
 	fmt.Printf("Analyzing frequency for %s...\n", id)
-	
-	hzCmd := exec.Command("python3", "bpm/frequency_analysis.py", inputPath)
-	output, err := hzCmd.CombinedOutput()
+
+	freqData, output, err := audio.AnalyzeFrequency(inputPath)
 	if err != nil {
 		fmt.Printf("Error analyzing frequency: %v\n", err)
-		fmt.Printf("Output: %s\n", string(output))
+		if len(output) > 0 {
+			fmt.Printf("Output: %s\n", string(output))
+		}
 		os.Exit(1)
 	}
-	
+
 	fmt.Printf("%s\n", string(output))
-	
-	var freqData map[string]interface{}
-	if err := json.Unmarshal(output, &freqData); err != nil {
-		fmt.Printf("Error parsing frequency JSON: %v\n", err)
-		os.Exit(1)
-	}
 
 	db, err := util.InitDatabase()
 	if err != nil {
@@ -249,27 +219,7 @@ func HandleHz() {
 	}
 	defer db.Close()
 
-	var fundamentalFreq, peakFreq, spectralCentroid *float64
-	
-	if val, ok := freqData["fundamental_frequency"]; ok && val != nil {
-		if freq, ok := val.(float64); ok {
-			fundamentalFreq = &freq
-		}
-	}
-	
-	if val, ok := freqData["peak_frequency"]; ok && val != nil {
-		if freq, ok := val.(float64); ok {
-			peakFreq = &freq
-		}
-	}
-	
-	if val, ok := freqData["spectral_centroid"]; ok && val != nil {
-		if freq, ok := val.(float64); ok {
-			spectralCentroid = &freq
-		}
-	}
-
-	if err := db.StoreFrequencyData(id, fundamentalFreq, peakFreq, spectralCentroid); err != nil {
+	if err := db.StoreFrequencyData(id, freqData.FundamentalFreq, freqData.PeakFreq, freqData.SpectralCentroid); err != nil {
 		fmt.Printf("Warning: Could not store frequency data in database: %v\n", err)
 	} else {
 		fmt.Printf("\nFrequency data stored in database for %s\n", id)
@@ -336,15 +286,14 @@ func HandleSync() {
 	fmt.Printf("  %s inverse: %.3f (%.1f%%)\n", id1, invRatio1to2, (invRatio1to2-1)*100)
 	fmt.Printf("  %s inverse: %.3f (%.1f%%)\n", id2, invRatio2to1, (invRatio2to1-1)*100)
 
+	// Whichever separator made them; the registry knows each stem's file
+	vocals1 := filepath.Base(audio.GetStemFilePath(db, id1, audio.StemVocals))
+	instrumental1 := filepath.Base(audio.GetStemFilePath(db, id1, audio.StemInstrumental))
+	vocals2 := filepath.Base(audio.GetStemFilePath(db, id2, audio.StemVocals))
+	instrumental2 := filepath.Base(audio.GetStemFilePath(db, id2, audio.StemInstrumental))
 	files := []string{
-		audio.GetVocalFilename(id1),
-		audio.GetInstrumentalFilename(id1),
-		audio.GetVocalFilename(id2),
-		audio.GetInstrumentalFilename(id2),
-		audio.GetVocalFilename(id1),
-		audio.GetInstrumentalFilename(id1),
-		audio.GetVocalFilename(id2),
-		audio.GetInstrumentalFilename(id2),
+		vocals1, instrumental1, vocals2, instrumental2,
+		vocals1, instrumental1, vocals2, instrumental2,
 	}
 
 	ratios := []float64{ratio1to2, ratio1to2, ratio2to1, ratio2to1, invRatio1to2, invRatio1to2, invRatio2to1, invRatio2to1}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"starchive/pipeline"
+	"starchive/storage"
+	"starchive/util"
+)
+
+// retryComponents maps downloaded artifact kinds to the retry command's components
+var retryComponents = map[string]string{
+	"video":     "video",
+	"metadata":  "json",
+	"thumbnail": "thumbnail",
+	"subtitles": "vtt",
+}
+
+// repairStages maps generated artifact kinds to the ingest stage that makes them again
+var repairStages = map[string]string{
+	"audio":      "wav",
+	"stem":       "separate",
+	"transcript": "transcript",
+}
+
+func HandleFsck() {
+	fsckCmd := flag.NewFlagSet("fsck", flag.ExitOnError)
+	hash := fsckCmd.Bool("hash", false, "Compare SHA-256 checksums as well as sizes (reads every file)")
+	repair := fsckCmd.Bool("repair", false, "Download or regenerate missing, empty, unreadable and truncated artifacts")
+	forget := fsckCmd.Bool("forget", false, "Drop records of missing files and accept changed files as they are")
+
+	// Allow the ID to come before or after the flags
+	args := os.Args[2:]
+	var id string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id = args[0]
+		args = args[1:]
+	}
+	if err := fsckCmd.Parse(args); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		os.Exit(2)
+	}
+	if id == "" && fsckCmd.NArg() > 0 {
+		id = fsckCmd.Arg(0)
+	}
+	if *repair && *forget {
+		fmt.Println("Use either --repair or --forget")
+		os.Exit(2)
+	}
+
+	db, err := util.InitDatabase()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	opts := storage.CheckOptions{Hash: *hash}
+	report, err := storage.Check(db, id, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	printFsckReport(report)
+
+	switch {
+	case *repair:
+		repaired := repairArtifacts(db, report.Problems)
+		if len(repaired) == 0 {
+			break
+		}
+		fmt.Printf("\nChecking repaired IDs again...\n")
+		remaining := 0
+		for _, repairedID := range repaired {
+			again, err := storage.Check(db, repairedID, opts)
+			if err != nil {
+				fmt.Printf("%s: error: %v\n", repairedID, err)
+				continue
+			}
+			for _, problem := range again.Problems {
+				printProblem(problem)
+			}
+			remaining += len(again.Problems)
+		}
+		fmt.Printf("%d problems remain after repair\n", remaining)
+		if remaining > 0 {
+			os.Exit(1)
+		}
+		return
+	case *forget:
+		forgetArtifacts(db, report.Problems)
+		return
+	}
+
+	if len(report.Problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+func printFsckReport(report *storage.Report) {
+	for _, problem := range report.Problems {
+		printProblem(problem)
+	}
+	if report.Unprobed {
+		fmt.Println("Warning: ffprobe not found, media durations not checked")
+	}
+
+	fmt.Printf("\nChecked %d files, recorded %d new artifacts, found %d problems\n",
+		report.Checked, report.Recorded, len(report.Problems))
+
+	var orphans []string
+	for _, problem := range report.Problems {
+		if problem.Issue == storage.IssueOrphan {
+			orphans = appendOnce(orphans, problem.ID)
+		}
+	}
+	for _, orphanID := range orphans {
+		fmt.Printf("Orphaned files of %s can be removed with: starchive rm %s\n", orphanID, orphanID)
+	}
+}
+
+func printProblem(problem storage.Problem) {
+	line := fmt.Sprintf("%-10s %-10s %s", problem.Issue, problem.Kind, problem.Path)
+	if problem.Detail != "" {
+		line += " (" + problem.Detail + ")"
+	}
+	fmt.Println(line)
+}
+
+// repairArtifacts removes broken files and fetches or regenerates them with the retry
+// command and the ingest pipeline; it returns the IDs it worked on
+func repairArtifacts(db *util.Database, problems []storage.Problem) []string {
+	components := make(map[string][]string)
+	stages := make(map[string][]string)
+	for _, problem := range problems {
+		switch problem.Issue {
+		case storage.IssueEmpty, storage.IssueUnreadable, storage.IssueTruncated:
+			fmt.Printf("Removing broken %s\n", problem.Path)
+			if err := os.Remove(problem.Path); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+		case storage.IssueMissing:
+		default:
+			continue
+		}
+
+		if component, ok := retryComponents[problem.Kind]; ok {
+			components[problem.ID] = appendOnce(components[problem.ID], component)
+		} else if stage, ok := repairStages[problem.Kind]; ok {
+			stages[problem.ID] = appendOnce(stages[problem.ID], stage)
+		}
+	}
+
+	var ids []string
+	for id, list := range components {
+		fmt.Printf("\n[%s] Retrying %s\n", id, strings.Join(list, ", "))
+		util.HandleRetryCommand(append([]string{id}, list...))
+		if err := storage.RecordStageArtifacts(db, id, "download"); err != nil {
+			fmt.Printf("[%s] Warning: failed to record artifacts: %v\n", id, err)
+		}
+		ids = appendOnce(ids, id)
+	}
+	for id, list := range stages {
+		fmt.Printf("\n[%s] Re-running %s\n", id, strings.Join(list, ", "))
+		if err := pipeline.Run(id, list, pipeline.Options{Force: true}); err != nil {
+			fmt.Printf("[%s] Error: %v\n", id, err)
+		}
+		ids = appendOnce(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+// forgetArtifacts brings the inventory in line with the disk: missing files are
+// dropped and changed ones recorded as they are now
+func forgetArtifacts(db *util.Database, problems []storage.Problem) {
+	forgotten, updated := 0, 0
+	for _, problem := range problems {
+		switch problem.Issue {
+		case storage.IssueMissing:
+			if err := db.DeleteArtifact(problem.Path); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+			forgotten++
+		case storage.IssueChanged:
+			file := storage.ArtifactFile{Path: problem.Path, ID: problem.ID, Kind: problem.Kind}
+			if err := storage.RecordArtifact(db, file, "fsck"); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+			updated++
+		}
+	}
+	fmt.Printf("Forgot %d missing artifacts, updated %d changed ones\n", forgotten, updated)
+}
+
+func appendOnce(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
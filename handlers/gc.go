@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"starchive/storage"
+	"starchive/util"
+)
+
+func HandleGc() {
+	gcCmd := flag.NewFlagSet("gc", flag.ExitOnError)
+	dryRun := gcCmd.Bool("dry-run", false, "List what would be removed without deleting anything")
+	policy := storage.DefaultPolicy
+	policy.AddFlags(gcCmd, "")
+	if err := gcCmd.Parse(os.Args[2:]); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		os.Exit(2)
+	}
+
+	result, err := storage.Collect(policy, *dryRun)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	verb := "Removed"
+	if *dryRun {
+		verb = "Would remove"
+	}
+	for _, removal := range result.Removals {
+		fmt.Printf("%s %s (%s): %s\n", verb, removal.Path, util.Pretty(uint64(removal.Size)), removal.Reason)
+	}
+	for _, err := range result.Errors {
+		fmt.Printf("Error: %v\n", err)
+	}
+
+	fmt.Printf("\n%s %d files, %s of %s in %s\n", verb, len(result.Removals),
+		util.Pretty(uint64(result.Freed)), util.Pretty(uint64(result.Size)), storage.DataDir)
+	if policy.MaxSize > 0 && result.Size-result.Freed > policy.MaxSize {
+		fmt.Printf("Still over the %s limit; the rest is source media gc does not delete\n", util.Pretty(uint64(policy.MaxSize)))
+	}
+	if len(result.Errors) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"starchive/audio"
+	"starchive/pipeline"
+)
+
+func HandleIngest() {
+	ingestCmd := flag.NewFlagSet("ingest", flag.ExitOnError)
+	stagesFlag := ingestCmd.String("stages", "all", "Comma-separated stages to run: "+strings.Join(pipeline.DefaultStages, ","))
+	force := ingestCmd.Bool("force", false, "Re-run requested stages even if already done")
+	status := ingestCmd.Bool("status", false, "Show recorded stage status instead of running")
+	model := ingestCmd.String("model", audio.DefaultSeparator, "Stem separator for the separate stage: "+strings.Join(audio.SeparatorNames(), ", "))
+
+	// Allow the URL to come before or after the flags
+	args := os.Args[2:]
+	var input string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		input = args[0]
+		args = args[1:]
+	}
+	if err := ingestCmd.Parse(args); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		os.Exit(2)
+	}
+	if input == "" && ingestCmd.NArg() > 0 {
+		input = ingestCmd.Arg(0)
+	}
+
+	if input == "" {
+		fmt.Println("Usage: starchive ingest <id_or_url> [--stages s1,s2,...] [--model uvr|demucs] [--force] [--status]")
+		fmt.Println("Examples:")
+		fmt.Println("  starchive ingest https://www.youtube.com/watch?v=abc123")
+		fmt.Println("  starchive ingest abc123 --stages bpm,hz")
+		fmt.Println("  starchive ingest abc123 --status")
+		fmt.Printf("Stages: %s (dependencies are added automatically)\n", strings.Join(pipeline.DefaultStages, ", "))
+		os.Exit(1)
+	}
+
+	if *status {
+		if err := pipeline.PrintStatus(input); err != nil {
+			fmt.Printf("Error reading stage status: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	stages, err := pipeline.ParseStages(*stagesFlag)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := pipeline.Run(input, stages, pipeline.Options{Force: *force, Separator: *model}); err != nil {
+		fmt.Printf("Ingest incomplete: %v\n", err)
+		fmt.Println("Fix the problem and run the same command again to resume from the failed stage.")
+		os.Exit(1)
+	}
+
+	fmt.Println("Ingest complete.")
+}
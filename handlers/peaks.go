@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"starchive/audio"
+	"starchive/util"
+)
+
+func HandlePeaks() {
+	peaksCmd := flag.NewFlagSet("peaks", flag.ExitOnError)
+	spectrogram := peaksCmd.Bool("spectrogram", false, "Also render spectrogram PNGs")
+	force := peaksCmd.Bool("force", false, "Regenerate even if the cached files are up to date")
+
+	// Allow the ID to come before or after the flags
+	args := os.Args[2:]
+	var id string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id = args[0]
+		args = args[1:]
+	}
+	if err := peaksCmd.Parse(args); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		os.Exit(2)
+	}
+	if id == "" && peaksCmd.NArg() > 0 {
+		id = peaksCmd.Arg(0)
+	}
+
+	if id == "" {
+		fmt.Println("Usage: starchive peaks <id> [--spectrogram] [--force]")
+		fmt.Println("Example: starchive peaks Oa_RSwwpPaA --spectrogram")
+		fmt.Printf("Writes multi-resolution peak files for the WAV and every stem to %s\n", audio.PeaksCacheDir)
+		os.Exit(1)
+	}
+
+	db, err := util.InitDatabase()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	sources := audio.PeakSources(db, id)
+	if len(sources) == 0 {
+		fmt.Printf("No WAV or stems found for %s\n", id)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, path := range sources {
+		peaks, err := audio.EnsurePeaks(path, *force)
+		if err != nil {
+			fmt.Printf("%s: error: %v\n", filepath.Base(path), err)
+			failed = true
+			continue
+		}
+
+		var levels []string
+		for _, level := range peaks.Levels {
+			levels = append(levels, fmt.Sprintf("%d:%d", level.SamplesPerPixel, level.Length))
+		}
+		fmt.Printf("%s (%.1fs)\n", filepath.Base(path), peaks.Duration)
+		fmt.Printf("  %s\n", peaks.Sparkline(60))
+		fmt.Printf("  Levels (samples per pixel:points): %s\n", strings.Join(levels, " "))
+		fmt.Printf("  Peaks: %s\n", audio.PeaksPath(path))
+
+		if *spectrogram {
+			pngPath, err := audio.EnsureSpectrogram(path, *force)
+			if err != nil {
+				fmt.Printf("  Spectrogram error: %v\n", err)
+				failed = true
+				continue
+			}
+			fmt.Printf("  Spectrogram: %s\n", pngPath)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"starchive/media"
+)
+
+// authCookieNames are the cookies a platform login depends on; their expiry is the
+// one that matters
+var authCookieNames = map[string]bool{
+	"SAPISID": true, "__Secure-3PAPISID": true, "SID": true, "LOGIN_INFO": true, "sessionid": true,
+}
+
+func HandleVault() {
+	args := os.Args[2:]
+	if len(args) == 0 {
+		args = []string{"status"}
+	}
+
+	switch args[0] {
+	case "status":
+		showVaultStatus()
+	case "import":
+		if len(args) != 3 {
+			fmt.Println("Usage: starchive vault import <platform> <cookies.txt>")
+			os.Exit(1)
+		}
+		importVaultCookies(args[1], args[2])
+	case "clear":
+		if len(args) != 2 {
+			fmt.Println("Usage: starchive vault clear <platform>")
+			os.Exit(1)
+		}
+		if err := media.ClearCookies(args[1]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed %s cookies from the vault\n", args[1])
+	default:
+		fmt.Println("Usage: starchive vault [status|import <platform> <cookies.txt>|clear <platform>]")
+		fmt.Println("  starchive vault                            Show stored cookies, their expiry and the PO token")
+		fmt.Println("  starchive vault import youtube cookies.txt Encrypt a Netscape cookie file into the vault")
+		fmt.Println("  starchive vault clear instagram            Forget a platform's cookies")
+		os.Exit(1)
+	}
+}
+
+func showVaultStatus() {
+	creds, err := media.LoadCredentials()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Vault: %s (key: ", media.VaultFile)
+	if os.Getenv(media.VaultPassphraseEnv) != "" {
+		fmt.Printf("passphrase from %s)\n", media.VaultPassphraseEnv)
+	} else {
+		fmt.Printf("%s)\n", media.VaultKeyFile())
+	}
+
+	platforms := make([]string, 0, len(creds.Cookies))
+	for platform := range creds.Cookies {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+
+	if len(platforms) == 0 {
+		fmt.Println("No cookies stored. Visit YouTube with the extension installed, or use 'starchive vault import'.")
+	}
+
+	now := time.Now()
+	for _, platform := range platforms {
+		cookies := creds.Cookies[platform]
+		expired, session := 0, 0
+		var authExpiry time.Time
+		for _, cookie := range cookies {
+			switch {
+			case cookie.Expired(now):
+				expired++
+			case cookie.Expires == 0:
+				session++
+			}
+			if authCookieNames[cookie.Name] && cookie.Expires > 0 {
+				at := time.Unix(cookie.Expires, 0)
+				if authExpiry.IsZero() || at.Before(authExpiry) {
+					authExpiry = at
+				}
+			}
+		}
+
+		fmt.Printf("  %-10s %d cookies (%d expired, %d session), updated %s\n", platform, len(cookies),
+			expired, session, creds.CookiesUpdated[platform].Format("2006-01-02 15:04"))
+		switch {
+		case authExpiry.IsZero():
+		case authExpiry.Before(now):
+			fmt.Printf("             login expired %s; visit the site with the extension to refresh it\n", authExpiry.Format("2006-01-02"))
+		default:
+			fmt.Printf("             login expires %s (in %.0f days)\n", authExpiry.Format("2006-01-02"), authExpiry.Sub(now).Hours()/24)
+		}
+	}
+
+	if creds.POToken == "" {
+		fmt.Println("  PO token   none")
+	} else {
+		age := now.Sub(creds.POTokenTime).Round(time.Minute)
+		state := "fresh"
+		if age > media.POTokenMaxAge {
+			state = "stale, not used"
+		}
+		fmt.Printf("  PO token   %s, received %s ago (%s)\n", media.Redact(creds.POToken), age, state)
+	}
+}
+
+func importVaultCookies(platform, path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	cookies, err := media.ReadNetscapeCookies(file)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	if len(cookies) == 0 {
+		fmt.Printf("No cookies found in %s\n", path)
+		os.Exit(1)
+	}
+
+	if err := media.StoreCookies(platform, cookies); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Imported %d %s cookies into %s\n", len(cookies), platform, media.VaultFile)
+	fmt.Printf("The plaintext %s is no longer needed; delete it\n", path)
+}
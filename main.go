@@ -6,13 +6,43 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"strings"
 	
 	"starchive/audio"
 	"starchive/handlers"
+	"starchive/pipeline"
+	"starchive/storage"
 	"starchive/util"
 	"starchive/web"
 )
 
+const usage = `Usage: starchive <command> [args]
+
+Commands:
+  run         Start the server (default features)
+  ls          List files in ./data
+  dl          Download video with given ID
+  ingest      Run the ingest pipeline (download, wav, separate, peaks, bpm, hz, transcript) for an ID or URL
+  external    Import external audio file to data directory
+  vocal       Separate stems using audio-separator (--model uvr|demucs)
+  bpm         Analyze BPM and key of vocal and instrumental files
+  hz          Analyze frequency characteristics of audio files
+  peaks       Generate waveform peaks (and --spectrogram PNGs) for an ID's WAV and stems
+  sync        Synchronize two audio files for mashups using rubberband
+  split       Split audio file by silence detection
+  rm          Remove all files with specified id from ./data
+  fsck        Check recorded artifacts against ./data (--hash, --repair, --forget)
+  gc          Delete old renders, caches and temporary files by retention policy (--dry-run to list)
+  play        Play a wav file starting from the middle (press any key to stop)
+  demo        Create 30-second demo with +3 pitch shift from middle of track
+  blend       Interactive blend shell for mixing two tracks
+  blend-clear Clear blend metadata for track combinations
+  retry       Retry downloading specific components (vtt, json, thumbnail, video) for a given ID
+  ul          Upload mp4 to YouTube using the given ID
+  small       Create small optimized video from data/id.mp4
+  podpapyrus  Download thumbnail and VTT, create text file from given ID
+  vault       Show, import or clear the encrypted cookies and PO token`
+
 var downloadQueue *web.DownloadQueue
 var downloadVideos bool
 
@@ -32,7 +62,7 @@ func updateYtDlp() {
 func main() {
 	// Simple subcommand dispatch: first arg is the command
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: starchive <command> [args]\n\nCommands:\n  run         Start the server (default features)\n  ls          List files in ./data\n  dl          Download video with given ID\n  external    Import external audio file to data directory\n  vocal       Extract vocals from audio file using audio-separator\n  bpm         Analyze BPM and key of vocal and instrumental files\n  hz          Analyze frequency characteristics of audio files\n  sync        Synchronize two audio files for mashups using rubberband\n  split       Split audio file by silence detection\n  rm          Remove all files with specified id from ./data\n  play        Play a wav file starting from the middle (press any key to stop)\n  demo        Create 30-second demo with +3 pitch shift from middle of track\n  blend       Interactive blend shell for mixing two tracks\n  blend-clear Clear blend metadata for track combinations\n  retry       Retry downloading specific components (vtt, json, thumbnail, video) for a given ID\n  ul          Upload mp4 to YouTube using the given ID\n  small       Create small optimized video from data/id.mp4\n  podpapyrus  Download thumbnail and VTT, create text file from given ID")
+		fmt.Println(usage)
 		os.Exit(1)
 	}
 
@@ -41,19 +71,55 @@ func main() {
 	case "run":
 		runCmd := flag.NewFlagSet("run", flag.ExitOnError)
 		runCmd.BoolVar(&downloadVideos, "download-videos", true, "Download full videos; if false, only subtitles and thumbnails")
+		stagesFlag := runCmd.String("stages", "download", "Comma-separated ingest stages to run for queued videos")
+		addr := runCmd.String("addr", web.DefaultAddr, "Address to listen on; anything but loopback exposes the API to the network")
+		originsFlag := runCmd.String("allow-origin", strings.Join(web.DefaultOrigins, ","), "Comma-separated Origin prefixes allowed to call the API")
+		gcPolicy := storage.DefaultPolicy
+		gcPolicy.AddFlags(runCmd, "gc-")
+		gcInterval := runCmd.Duration("gc-interval", storage.DefaultSweepInterval, "How often to check free space when -gc-min-free is set")
 		// Parse flags after the subcommand
 		if err := runCmd.Parse(os.Args[2:]); err != nil {
 			fmt.Println("Error parsing flags:", err)
 			os.Exit(2)
 		}
 
+		stages, err := pipeline.ParseStages(*stagesFlag)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(2)
+		}
+
 		updateYtDlp()
 
-		downloadQueue = web.NewDownloadQueue()
-		web.SetupRoutes(downloadQueue)
+		token, created, err := web.LoadOrCreateToken()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if created {
+			fmt.Printf("Generated API token (saved to %s). Paste it into the extension popup:\n\n  %s\n\n", web.TokenFile, token)
+		} else {
+			fmt.Printf("Using API token from %s\n", web.TokenFile)
+		}
+
+		downloadQueue = web.NewDownloadQueue(stages)
+		web.SetupRoutes(downloadQueue, web.Policy{
+			Token:   token,
+			Origins: strings.Split(*originsFlag, ","),
+			MaxBody: web.MaxBodyBytes,
+			Addr:    *addr,
+		})
 
-		fmt.Println("Server starting on port 3009...")
-		if err := http.ListenAndServe(":3009", nil); err != nil {
+		if gcPolicy.MinFree > 0 {
+			fmt.Printf("Sweeping ./data when free space falls below %s\n", util.Pretty(uint64(gcPolicy.MinFree)))
+			go storage.Sweep(gcPolicy, *gcInterval)
+		}
+
+		if !web.IsLoopback(*addr) {
+			fmt.Printf("Warning: listening on %s makes the API reachable from other machines\n", *addr)
+		}
+		fmt.Printf("Server starting on %s...\n", *addr)
+		if err := http.ListenAndServe(*addr, nil); err != nil {
 			fmt.Printf("Server error: %v\n", err)
 			os.Exit(1)
 		}
@@ -62,6 +128,8 @@ func main() {
 	case "dl":
 		updateYtDlp()
 		handlers.HandleDl()
+	case "ingest":
+		handlers.HandleIngest()
 	case "external":
 		handlers.HandleExternal()
 	case "vocal", "vocals":
@@ -70,12 +138,18 @@ func main() {
 		handlers.HandleBpm()
 	case "hz":
 		handlers.HandleHz()
+	case "peaks":
+		handlers.HandlePeaks()
 	case "sync":
 		handlers.HandleSync()
 	case "split":
 		audio.HandleSplitCommand(os.Args[2:])
 	case "rm":
 		util.HandleRmCommand(os.Args[2:])
+	case "gc":
+		handlers.HandleGc()
+	case "fsck":
+		handlers.HandleFsck()
 	case "play":
 		audio.HandlePlayCommand(os.Args[2:])
 	case "demo":
@@ -92,9 +166,11 @@ func main() {
 		handlers.HandleSmall()
 	case "podpapyrus":
 		handlers.HandlePodpapyrus()
+	case "vault":
+		handlers.HandleVault()
 	default:
 		fmt.Printf("Unknown command: %s\n", cmd)
-		fmt.Println("Usage: starchive <command> [args]\n\nCommands:\n  run         Start the server (default features)\n  ls          List files in ./data\n  dl          Download video with given ID\n  external    Import external audio file to data directory\n  vocal       Extract vocals from audio file using audio-separator\n  bpm         Analyze BPM and key of vocal and instrumental files\n  hz          Analyze frequency characteristics of audio files\n  sync        Synchronize two audio files for mashups using rubberband\n  split       Split audio file by silence detection\n  rm          Remove all files with specified id from ./data\n  play        Play a wav file starting from the middle (press any key to stop)\n  demo        Create 30-second demo with +3 pitch shift from middle of track\n  blend       Interactive blend shell for mixing two tracks\n  blend-clear Clear blend metadata for track combinations\n  retry       Retry downloading specific components (vtt, json, thumbnail, video) for a given ID\n  ul          Upload mp4 to YouTube using the given ID\n  small       Create small optimized video from data/id.mp4\n  podpapyrus  Download thumbnail and VTT, create text file from given ID")
+		fmt.Println(usage)
 		os.Exit(1)
 	}
 }
\ No newline at end of file
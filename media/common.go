@@ -1,17 +1,12 @@
 package media
 
 import (
-	"bufio"
 	"crypto/sha1"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"regexp"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -64,17 +59,6 @@ func DownloadVideo(videoID, platform string) (string, error) {
 	}
 }
 
-func GetCookieFile(platform string) string {
-	switch platform {
-	case "youtube":
-		return "./cookies_youtube.txt"
-	case "instagram":
-		return "./cookies_instagram.txt"
-	default:
-		return fmt.Sprintf("./cookies_%s.txt", platform)
-	}
-}
-
 func EnsureWav(videoID string) error {
 	wavPath := fmt.Sprintf("./data/%s.wav", videoID)
 	if _, err := os.Stat(wavPath); err == nil {
@@ -102,35 +86,13 @@ func EnsureWav(videoID string) error {
 	return nil
 }
 
-func extractSAPISID(cookieFile string) (string, error) {
-	file, err := os.Open(cookieFile)
-	if err != nil {
-		return "", fmt.Errorf("failed to open cookie file: %v", err)
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix(line, "#") || line == "" {
-			continue
-		}
-		
-		parts := strings.Split(line, "\t")
-		if len(parts) >= 7 {
-			cookieName := parts[5]
-			cookieValue := parts[6]
-			
-			if cookieName == "SAPISID" || cookieName == "__Secure-3PAPISID" {
-				return cookieValue, nil
-			}
+// findSAPISID returns the SAPISID cookie that YouTube PO tokens are generated from
+func findSAPISID(cookies []Cookie) (string, error) {
+	for _, cookie := range cookies {
+		if cookie.Name == "SAPISID" || cookie.Name == "__Secure-3PAPISID" {
+			return cookie.Value, nil
 		}
 	}
-	
-	if err := scanner.Err(); err != nil {
-		return "", fmt.Errorf("error reading cookie file: %v", err)
-	}
-	
 	return "", fmt.Errorf("SAPISID not found in cookies")
 }
 
@@ -145,62 +107,32 @@ func generatePOToken(sapisid string) string {
 	return timestampStr + "_" + hashHex
 }
 
-func getStoredPOTokenFromServer() string {
-	resp, err := http.Get("http://localhost:3009/po-token")
-	if err != nil {
-		fmt.Printf("Debug: Failed to get PO token from server: %v\n", err)
-		return ""
+func getPOToken() string {
+	// First try the PO token the extension saved in the vault
+	if storedToken, _ := StoredPOToken(); storedToken != "" {
+		fmt.Printf("Debug: Using PO token from extension: %s\n", Redact(storedToken))
+		// Format the stored token for yt-dlp (assuming it's already base64url encoded)
+		return fmt.Sprintf("web.gvs+%s,web.subs+%s", storedToken, storedToken)
 	}
-	defer resp.Body.Close()
 	
-	if resp.StatusCode != http.StatusOK {
-		return ""
-	}
+	fmt.Printf("Debug: No stored PO token available, trying SAPISID method...\n")
 	
-	body, err := io.ReadAll(resp.Body)
+	creds, err := LoadCredentials()
 	if err != nil {
-		fmt.Printf("Debug: Failed to read PO token response: %v\n", err)
+		fmt.Printf("Debug: Failed to read cookies: %v\n", err)
 		return ""
 	}
-	
-	var response struct {
-		POToken string `json:"poToken"`
-	}
-	
-	if err := json.Unmarshal(body, &response); err != nil {
-		fmt.Printf("Debug: Failed to parse PO token response: %v\n", err)
-		return ""
-	}
-	
-	return response.POToken
-}
-
-func getPOToken(cookieFile string) string {
-	// First try to get PO token from extension via server
-	if storedToken := getStoredPOTokenFromServer(); storedToken != "" {
-		fmt.Printf("Debug: Using PO token from extension: %s...\n", storedToken[:20])
-		// Format the stored token for yt-dlp (assuming it's already base64url encoded)
-		formattedToken := fmt.Sprintf("web.gvs+%s,web.subs+%s", storedToken, storedToken)
-		fmt.Printf("Debug: Formatted stored PO token: %s...\n", formattedToken[:50])
-		return formattedToken
-	}
-	
-	fmt.Printf("Debug: No stored PO token available, trying SAPISID method...\n")
-	
-	sapisid, err := extractSAPISID(cookieFile)
+	sapisid, err := findSAPISID(creds.Cookies["youtube"])
 	if err != nil {
 		fmt.Printf("Debug: Failed to extract SAPISID: %v\n", err)
 		return ""
 	}
-	fmt.Printf("Debug: Extracted SAPISID: %s\n", sapisid)
+	fmt.Printf("Debug: Extracted SAPISID: %s\n", Redact(sapisid))
 	
 	poToken := generatePOToken(sapisid)
-	fmt.Printf("Debug: Generated raw PO token: %s\n", poToken)
+	fmt.Printf("Debug: Generated PO token: %s\n", Redact(poToken))
 	
 	// Format as CLIENT.CONTEXT+TOKEN for yt-dlp
 	// Use web.gvs for video downloads and web.subs for subtitles
-	formattedToken := fmt.Sprintf("web.gvs+%s,web.subs+%s", poToken, poToken)
-	fmt.Printf("Debug: Formatted generated PO token: %s\n", formattedToken)
-	
-	return formattedToken
+	return fmt.Sprintf("web.gvs+%s,web.subs+%s", poToken, poToken)
 }
\ No newline at end of file
@@ -40,27 +40,26 @@ func DownloadInstagram(videoID string) (string, error) {
 		return videoID, nil
 	}
 
-	// Get Instagram cookie file
-	cookieFile := "./cookies_instagram.txt"
-	
 	// Download metadata first
-	DownloadInstagramThumbnail(videoID, cookieFile)
-	DownloadInstagramJSON(videoID, cookieFile)
+	DownloadInstagramThumbnail(videoID)
+	DownloadInstagramJSON(videoID)
 
 	fmt.Printf("Downloading Instagram video %s...\n", videoID)
 
 	// Try reels first, fallback to posts
 	videoURL := "https://www.instagram.com/reels/" + videoID + "/"
 
-	cmd := exec.Command("yt-dlp",
-		"--cookies", cookieFile,
-		"-o", "./data/%(id)s.%(ext)s",
-		"-f", "best[ext=mp4]/best",
-		videoURL)
+	err := WithCookies("instagram", func(cookieArgs []string) error {
+		cmd := exec.Command("yt-dlp", append(cookieArgs,
+			"-o", "./data/%(id)s.%(ext)s",
+			"-f", "best[ext=mp4]/best",
+			videoURL)...)
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	})
+	if err != nil {
 		return "", fmt.Errorf("error downloading and converting Instagram video: %v", err)
 	}
 
@@ -71,7 +70,7 @@ func DownloadInstagram(videoID string) (string, error) {
 	return videoID, nil
 }
 
-func DownloadInstagramThumbnail(videoID, cookieFile string) error {
+func DownloadInstagramThumbnail(videoID string) error {
 	jpgPath := fmt.Sprintf("./data/%s.jpg", videoID)
 
 	if _, err := os.Stat(jpgPath); err == nil {
@@ -83,26 +82,27 @@ func DownloadInstagramThumbnail(videoID, cookieFile string) error {
 
 	videoURL := "https://www.instagram.com/reels/" + videoID + "/"
 
-	cmd := exec.Command(
-		"yt-dlp",
-		"--cookies", cookieFile,
-		"-o", "./data/"+videoID,
-		"--skip-download",
-		"--write-thumbnail",
-		"--convert-thumbnails", "jpg",
-		videoURL,
-	)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
+	err := WithCookies("instagram", func(cookieArgs []string) error {
+		cmd := exec.Command("yt-dlp", append(cookieArgs,
+			"-o", "./data/"+videoID,
+			"--skip-download",
+			"--write-thumbnail",
+			"--convert-thumbnails", "jpg",
+			videoURL,
+		)...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	})
+
+	if err != nil {
 		return fmt.Errorf("error downloading Instagram thumbnail: %v", err)
 	}
 
 	return nil
 }
 
-func DownloadInstagramJSON(videoID, cookieFile string) error {
+func DownloadInstagramJSON(videoID string) error {
 	jsonPath := fmt.Sprintf("./data/%s.json", videoID)
 
 	if _, err := os.Stat(jsonPath); err == nil {
@@ -114,15 +114,18 @@ func DownloadInstagramJSON(videoID, cookieFile string) error {
 
 	videoURL := "https://www.instagram.com/reels/" + videoID + "/"
 
-	cmd := exec.Command(
-		"yt-dlp",
-		"--cookies", cookieFile,
-		"-j",
-		"--no-warnings",
-		videoURL,
-	)
-
-	output, err := cmd.Output()
+	var output []byte
+	err := WithCookies("instagram", func(cookieArgs []string) error {
+		cmd := exec.Command("yt-dlp", append(cookieArgs,
+			"-j",
+			"--no-warnings",
+			videoURL,
+		)...)
+
+		var err error
+		output, err = cmd.Output()
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("error downloading Instagram JSON metadata: %v", err)
 	}
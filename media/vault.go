@@ -0,0 +1,437 @@
+package media
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultFile holds the platform cookies and the PO token, encrypted with AES-256-GCM
+const VaultFile = "./data/.vault"
+
+// The vault key comes from a passphrase when one is set, otherwise from a key file
+// kept outside the data directory
+const (
+	VaultPassphraseEnv = "STARCHIVE_VAULT_PASSPHRASE"
+	VaultKeyFileEnv    = "STARCHIVE_VAULT_KEY_FILE"
+)
+
+// POTokenMaxAge is how long a PO token from the extension is used
+const POTokenMaxAge = time.Hour
+
+const (
+	vaultVersion       = 1
+	vaultKDFKeyFile    = "keyfile"
+	vaultKDFPassphrase = "pbkdf2-sha256"
+	vaultIterations    = 600000
+)
+
+// Cookie is one browser cookie as the extension sends it
+type Cookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain"`
+	Path     string `json:"path"`
+	Expires  int64  `json:"expires"` // Unix seconds, 0 for a session cookie
+	Secure   bool   `json:"secure"`
+	HTTPOnly bool   `json:"httpOnly"`
+}
+
+// Expired reports whether the cookie has expired at now; session cookies never do
+func (c Cookie) Expired(now time.Time) bool {
+	return c.Expires > 0 && c.Expires <= now.Unix()
+}
+
+// Credentials is the decrypted content of the vault
+type Credentials struct {
+	Cookies        map[string][]Cookie  `json:"cookies"`
+	CookiesUpdated map[string]time.Time `json:"cookies_updated"`
+	POToken        string               `json:"po_token,omitempty"`
+	POTokenTime    time.Time            `json:"po_token_time,omitempty"`
+}
+
+// vaultEnvelope is the on-disk form of the vault
+type vaultEnvelope struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+var (
+	vaultMutex sync.Mutex
+	// Deriving a passphrase key is deliberately slow, so the last one is kept
+	derivedKey  []byte
+	derivedSalt []byte
+)
+
+// Redact stands in for a secret in log output
+func Redact(secret string) string {
+	if secret == "" {
+		return "(none)"
+	}
+	return fmt.Sprintf("[redacted, %d chars]", len(secret))
+}
+
+// LoadCredentials decrypts the vault. A missing vault is empty.
+func LoadCredentials() (*Credentials, error) {
+	vaultMutex.Lock()
+	defer vaultMutex.Unlock()
+
+	creds, _, err := readVault()
+	return creds, err
+}
+
+// StoreCookies replaces the saved cookies of a platform
+func StoreCookies(platform string, cookies []Cookie) error {
+	return updateVault(func(creds *Credentials) {
+		creds.Cookies[platform] = cookies
+		creds.CookiesUpdated[platform] = time.Now()
+	})
+}
+
+// ClearCookies removes the saved cookies of a platform
+func ClearCookies(platform string) error {
+	return updateVault(func(creds *Credentials) {
+		delete(creds.Cookies, platform)
+		delete(creds.CookiesUpdated, platform)
+	})
+}
+
+// StorePOToken saves the PO token from the extension
+func StorePOToken(token string) error {
+	return updateVault(func(creds *Credentials) {
+		creds.POToken = token
+		creds.POTokenTime = time.Now()
+	})
+}
+
+// StoredPOToken returns the saved PO token and when it arrived, or "" if there is
+// none younger than POTokenMaxAge
+func StoredPOToken() (string, time.Time) {
+	creds, err := LoadCredentials()
+	if err != nil {
+		fmt.Printf("Warning: can't read PO token: %v\n", err)
+		return "", time.Time{}
+	}
+	if creds.POToken == "" || time.Since(creds.POTokenTime) > POTokenMaxAge {
+		return "", time.Time{}
+	}
+	return creds.POToken, creds.POTokenTime
+}
+
+// WithCookies runs fn with the yt-dlp arguments that pass a platform's cookies: a
+// temporary Netscape cookie file written from the vault and removed when fn returns.
+// Without saved cookies the arguments are empty.
+func WithCookies(platform string, fn func(cookieArgs []string) error) error {
+	creds, err := LoadCredentials()
+	if err != nil {
+		fmt.Printf("Warning: can't read %s cookies, continuing without them: %v\n", platform, err)
+		return fn(nil)
+	}
+
+	cookies := creds.Cookies[platform]
+	if len(cookies) == 0 {
+		if _, err := os.Stat(LegacyCookieFile(platform)); err == nil {
+			fmt.Printf("Warning: %s is no longer read; move it into the vault with 'starchive vault import %s %s'\n",
+				LegacyCookieFile(platform), platform, LegacyCookieFile(platform))
+		}
+		return fn(nil)
+	}
+
+	now := time.Now()
+	var live []Cookie
+	for _, cookie := range cookies {
+		if !cookie.Expired(now) {
+			live = append(live, cookie)
+		}
+	}
+	if expired := len(cookies) - len(live); expired > 0 {
+		fmt.Printf("Warning: %d of %d %s cookies have expired; visit the site with the extension to refresh them\n",
+			expired, len(cookies), platform)
+	}
+
+	file, err := os.CreateTemp("", "starchive-cookies-*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to create cookie file: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	if err := writeNetscapeCookies(file, live); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write cookie file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write cookie file: %v", err)
+	}
+
+	return fn([]string{"--cookies", file.Name()})
+}
+
+// LegacyCookieFile is where cookies were kept in plaintext before the vault
+func LegacyCookieFile(platform string) string {
+	return fmt.Sprintf("./cookies_%s.txt", platform)
+}
+
+// ReadNetscapeCookies parses a Netscape cookie file such as a browser export
+func ReadNetscapeCookies(r io.Reader) ([]Cookie, error) {
+	var cookies []Cookie
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := false
+		if rest, ok := strings.CutPrefix(line, "#HttpOnly_"); ok {
+			line, httpOnly = rest, true
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, "\t")
+		if len(parts) < 7 {
+			continue
+		}
+		expires, _ := strconv.ParseInt(parts[4], 10, 64)
+		cookies = append(cookies, Cookie{
+			Domain:   parts[0],
+			Path:     parts[2],
+			Secure:   strings.EqualFold(parts[3], "TRUE"),
+			Expires:  expires,
+			Name:     parts[5],
+			Value:    parts[6],
+			HTTPOnly: httpOnly,
+		})
+	}
+	return cookies, scanner.Err()
+}
+
+// writeNetscapeCookies writes cookies in the Netscape format yt-dlp reads
+func writeNetscapeCookies(w io.Writer, cookies []Cookie) error {
+	if _, err := io.WriteString(w, "# Netscape HTTP Cookie File\n\n"); err != nil {
+		return err
+	}
+	for _, cookie := range cookies {
+		domainSpecified := "FALSE"
+		if strings.HasPrefix(cookie.Domain, ".") {
+			domainSpecified = "TRUE"
+		}
+		path := cookie.Path
+		if path == "" {
+			path = "/"
+		}
+		// Format: domain, domain_specified, path, secure, expiration, name, value
+		line := fmt.Sprintf("%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			cookie.Domain, domainSpecified, path, strings.ToUpper(strconv.FormatBool(cookie.Secure)),
+			cookie.Expires, cookie.Name, cookie.Value)
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateVault applies a change to the vault and saves it
+func updateVault(change func(*Credentials)) error {
+	vaultMutex.Lock()
+	defer vaultMutex.Unlock()
+
+	creds, envelope, err := readVault()
+	if err != nil {
+		return err
+	}
+	change(creds)
+	return writeVault(creds, envelope)
+}
+
+// readVault decrypts the vault file, returning the envelope for re-encryption
+func readVault() (*Credentials, *vaultEnvelope, error) {
+	creds := &Credentials{}
+	var envelope *vaultEnvelope
+
+	data, err := os.ReadFile(VaultFile)
+	switch {
+	case os.IsNotExist(err):
+		// A new vault; it is created on the first write
+	case err != nil:
+		return nil, nil, fmt.Errorf("failed to read vault: %v", err)
+	default:
+		envelope = &vaultEnvelope{}
+		if err := json.Unmarshal(data, envelope); err != nil {
+			return nil, nil, fmt.Errorf("invalid vault %s: %v", VaultFile, err)
+		}
+		if envelope.Version != vaultVersion {
+			return nil, nil, fmt.Errorf("unsupported vault version %d", envelope.Version)
+		}
+
+		gcm, err := vaultCipher(envelope)
+		if err != nil {
+			return nil, nil, err
+		}
+		plain, err := gcm.Open(nil, envelope.Nonce, envelope.Data, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't decrypt vault %s: wrong key or passphrase", VaultFile)
+		}
+		if err := json.Unmarshal(plain, creds); err != nil {
+			return nil, nil, fmt.Errorf("invalid vault content: %v", err)
+		}
+	}
+
+	if creds.Cookies == nil {
+		creds.Cookies = make(map[string][]Cookie)
+	}
+	if creds.CookiesUpdated == nil {
+		creds.CookiesUpdated = make(map[string]time.Time)
+	}
+	return creds, envelope, nil
+}
+
+// writeVault encrypts and saves the vault, keeping the existing key settings
+func writeVault(creds *Credentials, envelope *vaultEnvelope) error {
+	if envelope == nil {
+		envelope = &vaultEnvelope{Version: vaultVersion, KDF: vaultKDFKeyFile}
+		if os.Getenv(VaultPassphraseEnv) != "" {
+			envelope.KDF = vaultKDFPassphrase
+			envelope.Iterations = vaultIterations
+			envelope.Salt = make([]byte, 16)
+			if _, err := rand.Read(envelope.Salt); err != nil {
+				return fmt.Errorf("failed to generate vault salt: %v", err)
+			}
+		}
+	}
+
+	gcm, err := vaultCipher(envelope)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("failed to encode vault: %v", err)
+	}
+	envelope.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(envelope.Nonce); err != nil {
+		return fmt.Errorf("failed to generate vault nonce: %v", err)
+	}
+	envelope.Data = gcm.Seal(nil, envelope.Nonce, plain, nil)
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to encode vault: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(VaultFile), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(VaultFile), err)
+	}
+
+	// Write and rename so a crash never leaves half a vault
+	tmp := VaultFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write vault: %v", err)
+	}
+	if err := os.Rename(tmp, VaultFile); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write vault: %v", err)
+	}
+	return nil
+}
+
+// vaultCipher returns the AES-GCM cipher for the envelope's key settings
+func vaultCipher(envelope *vaultEnvelope) (cipher.AEAD, error) {
+	var key []byte
+	var err error
+	switch envelope.KDF {
+	case vaultKDFKeyFile:
+		key, err = vaultKeyFromFile()
+	case vaultKDFPassphrase:
+		key, err = vaultKeyFromPassphrase(envelope.Salt, envelope.Iterations)
+	default:
+		err = fmt.Errorf("unknown vault key type: %s", envelope.KDF)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// VaultKeyFile returns the path of the key file, STARCHIVE_VAULT_KEY_FILE or one in
+// the user's config directory
+func VaultKeyFile() string {
+	if path := os.Getenv(VaultKeyFileEnv); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "starchive", "vault.key")
+}
+
+// vaultKeyFromFile reads the 256-bit key file, creating it on first use
+func vaultKeyFromFile() ([]byte, error) {
+	path := VaultKeyFile()
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid vault key file %s", path)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read vault key file: %v", err)
+	}
+
+	if _, err := os.Stat(VaultFile); err == nil {
+		return nil, fmt.Errorf("vault key file %s is missing; the vault can't be decrypted without it", path)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate vault key: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write vault key file: %v", err)
+	}
+	fmt.Printf("Created vault key %s\n", path)
+	return key, nil
+}
+
+// vaultKeyFromPassphrase derives the key from STARCHIVE_VAULT_PASSPHRASE
+func vaultKeyFromPassphrase(salt []byte, iterations int) ([]byte, error) {
+	passphrase := os.Getenv(VaultPassphraseEnv)
+	if passphrase == "" {
+		return nil, fmt.Errorf("the vault is passphrase protected; set %s", VaultPassphraseEnv)
+	}
+
+	if derivedKey != nil && string(derivedSalt) == string(salt) {
+		return derivedKey, nil
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	derivedKey, derivedSalt = key, salt
+	return key, nil
+}
@@ -20,33 +20,32 @@ func DownloadYouTube(youtubeID string) (string, error) {
 		return youtubeID, nil
 	}
 
-	// Get YouTube cookie file
-	cookieFile := GetCookieFile("youtube")
-
 	// Download metadata first
-	DownloadYouTubeSubtitles(youtubeID, cookieFile)
-	DownloadYouTubeThumbnail(youtubeID, cookieFile)
-	DownloadYouTubeJSON(youtubeID, cookieFile)
+	DownloadYouTubeSubtitles(youtubeID)
+	DownloadYouTubeThumbnail(youtubeID)
+	DownloadYouTubeJSON(youtubeID)
 
 	fmt.Printf("Downloading YouTube video %s...\n", youtubeID)
 
-	args := []string{
-		"--cookies", cookieFile,
-		"-o", "./data/%(id)s.%(ext)s",
-		"-f", "bv*[vcodec^=avc1][ext=mp4]+ba[acodec^=mp4a][ext=m4a]/best[ext=mp4][vcodec^=avc1]",
-		"--merge-output-format", "mp4",
-	}
+	err := WithCookies("youtube", func(cookieArgs []string) error {
+		args := append(cookieArgs,
+			"-o", "./data/%(id)s.%(ext)s",
+			"-f", "bv*[vcodec^=avc1][ext=mp4]+ba[acodec^=mp4a][ext=m4a]/best[ext=mp4][vcodec^=avc1]",
+			"--merge-output-format", "mp4",
+		)
 
-	if poToken := getPOToken(cookieFile); poToken != "" {
-		args = append(args, "--extractor-args", "youtube:po_token="+poToken)
-	}
+		if poToken := getPOToken(); poToken != "" {
+			args = append(args, "--extractor-args", "youtube:po_token="+poToken)
+		}
 
-	args = append(args, "https://www.youtube.com/watch?v="+youtubeID)
-	cmd := exec.Command("yt-dlp", args...)
+		args = append(args, "https://www.youtube.com/watch?v="+youtubeID)
+		cmd := exec.Command("yt-dlp", args...)
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	})
+	if err != nil {
 		return "", fmt.Errorf("error downloading and converting YouTube video: %v", err)
 	}
 
@@ -57,7 +56,7 @@ func DownloadYouTube(youtubeID string) (string, error) {
 	return youtubeID, nil
 }
 
-func DownloadYouTubeSubtitles(youtubeID, cookieFile string) error {
+func DownloadYouTubeSubtitles(youtubeID string) error {
 	vttFile := youtubeID + ".en.vtt"
 
 	// Check if .en.vtt file already exists
@@ -72,18 +71,21 @@ func DownloadYouTubeSubtitles(youtubeID, cookieFile string) error {
 	// Retry with exponential backoff up to 50 times
 	var lastErr error
 	for attempt := 1; attempt <= 1; attempt++ {
-		subArgs := []string{"--cookies", cookieFile, "-o", "./data/" + youtubeID, "--skip-download", "--write-auto-sub", "--sub-lang", "en", "--convert-subs", "vtt"}
+		err := WithCookies("youtube", func(cookieArgs []string) error {
+			subArgs := append(cookieArgs, "-o", "./data/"+youtubeID, "--skip-download", "--write-auto-sub", "--sub-lang", "en", "--convert-subs", "vtt")
 
-		if poToken := getPOToken(cookieFile); poToken != "" {
-			subArgs = append(subArgs, "--extractor-args", "youtube:po_token="+poToken)
-		}
+			if poToken := getPOToken(); poToken != "" {
+				subArgs = append(subArgs, "--extractor-args", "youtube:po_token="+poToken)
+			}
 
-		subArgs = append(subArgs, youtubeURL)
-		subCmd := exec.Command("yt-dlp", subArgs...)
-		subCmd.Stdout = os.Stdout
-		subCmd.Stderr = os.Stderr
+			subArgs = append(subArgs, youtubeURL)
+			subCmd := exec.Command("yt-dlp", subArgs...)
+			subCmd.Stdout = os.Stdout
+			subCmd.Stderr = os.Stderr
+			return subCmd.Run()
+		})
 
-		if err := subCmd.Run(); err != nil {
+		if err != nil {
 			lastErr = err
 			if attempt < 50 {
 				// Exponential backoff: wait 2^(attempt-1) seconds, capped at 60 seconds
@@ -108,7 +110,7 @@ func DownloadYouTubeSubtitles(youtubeID, cookieFile string) error {
 	return fmt.Errorf("could not download subtitles after 50 attempts: %v", lastErr)
 }
 
-func DownloadYouTubeThumbnail(youtubeID, cookieFile string) error {
+func DownloadYouTubeThumbnail(youtubeID string) error {
 	jpgPath := fmt.Sprintf("./data/%s.jpg", youtubeID)
 
 	if _, err := os.Stat(jpgPath); err == nil {
@@ -118,31 +120,33 @@ func DownloadYouTubeThumbnail(youtubeID, cookieFile string) error {
 
 	fmt.Printf("Downloading YouTube thumbnail...\n")
 
-	thumbArgs := []string{
-		"--cookies", cookieFile,
-		"-o", "./data/" + youtubeID,
-		"--skip-download",
-		"--write-thumbnail",
-		"--convert-thumbnails", "jpg",
-	}
+	err := WithCookies("youtube", func(cookieArgs []string) error {
+		thumbArgs := append(cookieArgs,
+			"-o", "./data/"+youtubeID,
+			"--skip-download",
+			"--write-thumbnail",
+			"--convert-thumbnails", "jpg",
+		)
 
-	if poToken := getPOToken(cookieFile); poToken != "" {
-		thumbArgs = append(thumbArgs, "--extractor-args", "youtube:po_token="+poToken)
-	}
+		if poToken := getPOToken(); poToken != "" {
+			thumbArgs = append(thumbArgs, "--extractor-args", "youtube:po_token="+poToken)
+		}
 
-	thumbArgs = append(thumbArgs, "https://www.youtube.com/watch?v="+youtubeID)
-	cmd := exec.Command("yt-dlp", thumbArgs...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+		thumbArgs = append(thumbArgs, "https://www.youtube.com/watch?v="+youtubeID)
+		cmd := exec.Command("yt-dlp", thumbArgs...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	})
 
-	if err := cmd.Run(); err != nil {
+	if err != nil {
 		return fmt.Errorf("error downloading thumbnail: %v", err)
 	}
 
 	return nil
 }
 
-func DownloadYouTubeJSON(youtubeID, cookieFile string) error {
+func DownloadYouTubeJSON(youtubeID string) error {
 	jsonPath := fmt.Sprintf("./data/%s.json", youtubeID)
 
 	if _, err := os.Stat(jsonPath); err == nil {
@@ -152,20 +156,24 @@ func DownloadYouTubeJSON(youtubeID, cookieFile string) error {
 
 	fmt.Printf("Downloading YouTube JSON metadata...\n")
 
-	jsonArgs := []string{
-		"--cookies", cookieFile,
-		"-j",
-		"--no-warnings",
-	}
+	var output []byte
+	err := WithCookies("youtube", func(cookieArgs []string) error {
+		jsonArgs := append(cookieArgs,
+			"-j",
+			"--no-warnings",
+		)
 
-	if poToken := getPOToken(cookieFile); poToken != "" {
-		jsonArgs = append(jsonArgs, "--extractor-args", "youtube:po_token="+poToken)
-	}
+		if poToken := getPOToken(); poToken != "" {
+			jsonArgs = append(jsonArgs, "--extractor-args", "youtube:po_token="+poToken)
+		}
 
-	jsonArgs = append(jsonArgs, "https://www.youtube.com/watch?v="+youtubeID)
-	cmd := exec.Command("yt-dlp", jsonArgs...)
+		jsonArgs = append(jsonArgs, "https://www.youtube.com/watch?v="+youtubeID)
+		cmd := exec.Command("yt-dlp", jsonArgs...)
 
-	output, err := cmd.Output()
+		var err error
+		output, err = cmd.Output()
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("error downloading JSON metadata: %v", err)
 	}
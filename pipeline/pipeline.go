@@ -0,0 +1,300 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"starchive/audio"
+	"starchive/media"
+	"starchive/storage"
+	"starchive/util"
+)
+
+// Stage status values recorded in the pipeline_stages table
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Job describes a single track moving through the ingest pipeline
+type Job struct {
+	ID        string
+	Platform  string
+	Separator string
+	DB        *util.Database
+}
+
+// Stage is one step of the ingest pipeline
+type Stage struct {
+	Name      string
+	DependsOn []string
+	Run       func(job *Job) error
+}
+
+// Options controls how a pipeline run behaves
+type Options struct {
+	Force     bool   // Re-run stages even if they are already recorded as done
+	Separator string // Stem separator for the separate stage ("" uses the default)
+}
+
+// Stages lists every stage in execution order
+var Stages = []Stage{
+	{Name: "download", Run: runDownload},
+	{Name: "wav", DependsOn: []string{"download"}, Run: runWav},
+	{Name: "separate", DependsOn: []string{"wav"}, Run: runSeparate},
+	{Name: "peaks", DependsOn: []string{"separate"}, Run: runPeaks},
+	{Name: "spectrogram", DependsOn: []string{"wav"}, Run: runSpectrogram},
+	{Name: "bpm", DependsOn: []string{"wav"}, Run: runBpm},
+	{Name: "hz", DependsOn: []string{"wav"}, Run: runHz},
+	{Name: "transcript", DependsOn: []string{"download"}, Run: runTranscript},
+}
+
+// DefaultStages is the full ingest pipeline
+var DefaultStages = []string{"download", "wav", "separate", "peaks", "bpm", "hz", "transcript"}
+
+// ParseStages splits a comma-separated stage list and validates each name
+func ParseStages(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" || list == "all" {
+		return DefaultStages, nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if findStage(name) == nil {
+			return nil, fmt.Errorf("unknown stage: %s", name)
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+func findStage(name string) *Stage {
+	for i := range Stages {
+		if Stages[i].Name == name {
+			return &Stages[i]
+		}
+	}
+	return nil
+}
+
+// resolve expands the requested stages with their dependencies and returns them in execution order
+func resolve(names []string) []*Stage {
+	wanted := make(map[string]bool)
+
+	var visit func(name string)
+	visit = func(name string) {
+		if wanted[name] {
+			return
+		}
+		wanted[name] = true
+		if stage := findStage(name); stage != nil {
+			for _, dep := range stage.DependsOn {
+				visit(dep)
+			}
+		}
+	}
+
+	for _, name := range names {
+		visit(name)
+	}
+
+	var ordered []*Stage
+	for i := range Stages {
+		if wanted[Stages[i].Name] {
+			ordered = append(ordered, &Stages[i])
+		}
+	}
+
+	return ordered
+}
+
+// Run executes the requested stages (and their dependencies) for the given ID or URL.
+// Stages already recorded as done are skipped unless opts.Force is set, so a failed
+// run can be resumed by running the same command again.
+func Run(input string, stageNames []string, opts Options) error {
+	id, platform := media.ParseVideoInput(input)
+	if id == "" {
+		return fmt.Errorf("could not extract ID from input: %s", input)
+	}
+
+	db, err := util.InitDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	job := &Job{ID: id, Platform: platform, Separator: opts.Separator, DB: db}
+
+	statuses, err := db.GetStageStatuses(id)
+	if err != nil {
+		return fmt.Errorf("failed to load stage status: %v", err)
+	}
+
+	requested := make(map[string]bool)
+	for _, name := range stageNames {
+		requested[name] = true
+	}
+
+	failed := make(map[string]bool)
+	var firstErr error
+
+	for _, stage := range resolve(stageNames) {
+		blocked := ""
+		for _, dep := range stage.DependsOn {
+			if failed[dep] {
+				blocked = dep
+				break
+			}
+		}
+		if blocked != "" {
+			fmt.Printf("[%s] %s: skipped (dependency %s failed)\n", id, stage.Name, blocked)
+			failed[stage.Name] = true
+			db.SetStageStatus(id, stage.Name, StatusSkipped, "dependency "+blocked+" failed")
+			continue
+		}
+
+		// Dependencies pulled in implicitly are never forced, only the stages asked for
+		if st, ok := statuses[stage.Name]; ok && st.Status == StatusDone && !(opts.Force && requested[stage.Name]) {
+			fmt.Printf("[%s] %s: already done, skipping\n", id, stage.Name)
+			continue
+		}
+
+		fmt.Printf("[%s] %s: running\n", id, stage.Name)
+		db.SetStageStatus(id, stage.Name, StatusRunning, "")
+
+		if err := stage.Run(job); err != nil {
+			fmt.Printf("[%s] %s: failed: %v\n", id, stage.Name, err)
+			db.SetStageStatus(id, stage.Name, StatusFailed, err.Error())
+			failed[stage.Name] = true
+			if firstErr == nil {
+				firstErr = fmt.Errorf("stage %s failed: %v", stage.Name, err)
+			}
+			continue
+		}
+
+		db.SetStageStatus(id, stage.Name, StatusDone, "")
+		if err := storage.RecordStageArtifacts(db, id, stage.Name); err != nil {
+			fmt.Printf("[%s] %s: warning: failed to record artifacts: %v\n", id, stage.Name, err)
+		}
+		fmt.Printf("[%s] %s: done\n", id, stage.Name)
+	}
+
+	return firstErr
+}
+
+// PrintStatus shows the recorded stage status for an ID
+func PrintStatus(id string) error {
+	db, err := util.InitDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	statuses, err := db.GetStageStatuses(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-12s %-8s %s\n", "Stage", "Status", "Error")
+	for _, stage := range Stages {
+		st, ok := statuses[stage.Name]
+		if !ok {
+			fmt.Printf("%-12s %-8s\n", stage.Name, "-")
+			continue
+		}
+		fmt.Printf("%-12s %-8s %s\n", stage.Name, st.Status, st.Error)
+	}
+
+	return nil
+}
+
+func runDownload(job *Job) error {
+	_, err := media.DownloadVideo(job.ID, job.Platform)
+	return err
+}
+
+func runWav(job *Job) error {
+	return media.EnsureWav(job.ID)
+}
+
+func runSeparate(job *Job) error {
+	sep, err := audio.GetSeparator(job.Separator)
+	if err != nil {
+		return err
+	}
+
+	produced, err := sep.Separate(job.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := audio.RegisterStems(job.DB, job.ID); err != nil {
+		return fmt.Errorf("failed to register stems: %v", err)
+	}
+
+	// New stems need new peaks even when the peaks stage is already done
+	for _, path := range produced {
+		if _, err := audio.EnsurePeaks(path, false); err != nil {
+			fmt.Printf("[%s] separate: warning: no peaks for %s: %v\n", job.ID, path, err)
+		}
+	}
+
+	if _, ok := produced[audio.StemVocals]; ok {
+		return job.DB.MarkVocalDone(job.ID)
+	}
+	return nil
+}
+
+// runPeaks makes peak files for the WAV and every stem separated so far
+func runPeaks(job *Job) error {
+	for _, path := range audio.PeakSources(job.DB, job.ID) {
+		if _, err := audio.EnsurePeaks(path, false); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// runSpectrogram renders spectrograms for the WAV and every stem; it is not part of
+// the default pipeline
+func runSpectrogram(job *Job) error {
+	for _, path := range audio.PeakSources(job.DB, job.ID) {
+		if _, err := audio.EnsureSpectrogram(path, false); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+func runBpm(job *Job) error {
+	bpm, key, _, err := audio.AnalyzeBPM(fmt.Sprintf("./data/%s.wav", job.ID))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[%s] %.1f BPM, %s\n", job.ID, bpm, key)
+	return job.DB.StoreBPMData(job.ID, bpm, key)
+}
+
+func runHz(job *Job) error {
+	freqData, _, err := audio.AnalyzeFrequency(fmt.Sprintf("./data/%s.wav", job.ID))
+	if err != nil {
+		return err
+	}
+	return job.DB.StoreFrequencyData(job.ID, freqData.FundamentalFreq, freqData.PeakFreq, freqData.SpectralCentroid)
+}
+
+func runTranscript(job *Job) error {
+	vttPath := fmt.Sprintf("./data/%s.en.vtt", job.ID)
+	if _, err := os.Stat(vttPath); os.IsNotExist(err) {
+		return fmt.Errorf("no subtitles found at %s", vttPath)
+	}
+	return media.ParseVttFile(vttPath, job.ID)
+}
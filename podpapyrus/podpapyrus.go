@@ -274,20 +274,17 @@ func ProcessVideo(videoId string, basePath string) (*ProcessingResult, error) {
 		jpgExists = true
 	}
 	
-	// Get YouTube cookie file (needed for potential downloads)
-	cookieFile := media.GetCookieFile("youtube")
-	
 	if txtExists && jpgExists {
 		fmt.Printf("[Podpapyrus] Found local files %s.txt and %s.jpg, skipping downloads\n", videoId, videoId)
 	} else {
 		// Download thumbnail and subtitles
 		fmt.Printf("[Podpapyrus] Downloading thumbnail and subtitles for %s...\n", videoId)
 
-		if err := media.DownloadYouTubeThumbnail(videoId, cookieFile); err != nil {
+		if err := media.DownloadYouTubeThumbnail(videoId); err != nil {
 			return nil, fmt.Errorf("error downloading thumbnail: %v", err)
 		}
 
-		if err := media.DownloadYouTubeSubtitles(videoId, cookieFile); err != nil {
+		if err := media.DownloadYouTubeSubtitles(videoId); err != nil {
 			return nil, fmt.Errorf("error downloading subtitles: %v", err)
 		}
 
@@ -313,7 +310,7 @@ func ProcessVideo(videoId string, basePath string) (*ProcessingResult, error) {
 	
 	if _, err := os.Stat(jsonPath); err != nil {
 		// Download JSON metadata for YouTube videos
-		if err := media.DownloadYouTubeJSON(videoId, cookieFile); err != nil {
+		if err := media.DownloadYouTubeJSON(videoId); err != nil {
 			return nil, fmt.Errorf("error downloading JSON metadata: %v", err)
 		}
 	}
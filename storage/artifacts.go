@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"starchive/util"
+)
+
+// ArtifactFile is a file in ./data that belongs to a video
+type ArtifactFile struct {
+	Path string
+	ID   string
+	Kind string
+}
+
+// artifactSuffixes are the names of a video's files after its ID; longer first, so
+// .en.vtt is not taken for an ID ending in ".en"
+var artifactSuffixes = []string{".en.vtt", ".vtt", ".mp4", ".wav", ".jpg", ".txt", ".json"}
+
+// stemPattern matches separator output: <id>_(<Stem>)_<model>.wav
+var stemPattern = regexp.MustCompile(`^(.+)_\(([^)]+)\)_([^()]+)\.wav$`)
+
+// artifactStages is the pipeline stage that produces each kind of artifact
+var artifactStages = map[string]string{
+	"video":      "download",
+	"metadata":   "download",
+	"thumbnail":  "download",
+	"subtitles":  "download",
+	"audio":      "wav",
+	"stem":       "separate",
+	"transcript": "transcript",
+}
+
+// ArtifactKind names the kind of a video's file from what follows the ID in its name,
+// or returns "" for files that are not artifacts
+func ArtifactKind(suffix string) string {
+	switch {
+	case suffix == ".mp4":
+		return "video"
+	case suffix == ".wav":
+		return "audio"
+	case suffix == ".jpg":
+		return "thumbnail"
+	case strings.HasSuffix(suffix, ".vtt"):
+		return "subtitles"
+	case suffix == ".txt":
+		return "transcript"
+	case suffix == ".json":
+		return "metadata"
+	}
+	return ""
+}
+
+// parseArtifact splits a file name in ./data into the ID it belongs to and its kind.
+// Renders, sync outputs, demos, small copies and temp files are not artifacts.
+func parseArtifact(name string) (id, kind string, ok bool) {
+	if renderPattern.MatchString(name) || derivedPattern.MatchString(name) || isTemp(name) ||
+		strings.HasPrefix(name, ".") || strings.HasSuffix(name, "-small.mp4") {
+		return "", "", false
+	}
+	if match := stemPattern.FindStringSubmatch(name); match != nil {
+		return match[1], "stem", true
+	}
+	for _, suffix := range artifactSuffixes {
+		if id := strings.TrimSuffix(name, suffix); id != name && id != "" && !strings.Contains(id, ".") {
+			return id, ArtifactKind(suffix), true
+		}
+	}
+	return "", "", false
+}
+
+// ScanArtifacts lists the artifacts on disk for an ID, or for every ID when id is empty
+func ScanArtifacts(id string) ([]ArtifactFile, error) {
+	entries, err := os.ReadDir(DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", DataDir, err)
+	}
+
+	var files []ArtifactFile
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		fileID, kind, ok := parseArtifact(entry.Name())
+		if !ok || (id != "" && fileID != id) {
+			continue
+		}
+		files = append(files, ArtifactFile{Path: filepath.Join(DataDir, entry.Name()), ID: fileID, Kind: kind})
+	}
+	return files, nil
+}
+
+// RecordArtifact hashes a file and adds it to the artifact inventory as produced by stage
+func RecordArtifact(db *util.Database, file ArtifactFile, stage string) error {
+	info, err := os.Stat(file.Path)
+	if err != nil {
+		return err
+	}
+	sum, err := hashFile(file.Path)
+	if err != nil {
+		return err
+	}
+	return db.RecordArtifact(util.ArtifactRecord{
+		Path:      file.Path,
+		ID:        file.ID,
+		Kind:      file.Kind,
+		Size:      info.Size(),
+		SHA256:    sum,
+		Stage:     stage,
+		CreatedAt: time.Now(),
+	})
+}
+
+// RecordStageArtifacts records the files of an ID that a pipeline stage produces
+func RecordStageArtifacts(db *util.Database, id, stage string) error {
+	files, err := ScanArtifacts(id)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if artifactStages[file.Kind] != stage {
+			continue
+		}
+		if err := RecordArtifact(db, file, stage); err != nil {
+			return fmt.Errorf("%s: %v", file.Path, err)
+		}
+	}
+	return nil
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"starchive/audio"
+	"starchive/util"
+)
+
+// Problems fsck reports
+const (
+	IssueMissing    = "missing"    // Recorded but not on disk
+	IssueOrphan     = "orphan"     // On disk for an ID with no metadata
+	IssueEmpty      = "empty"      // Zero bytes
+	IssueUnreadable = "unreadable" // Media ffprobe cannot read
+	IssueTruncated  = "truncated"  // Media shorter than the video or its WAV
+	IssueChanged    = "changed"    // Size or checksum differs from the record
+)
+
+// truncationTolerance is how many seconds short of the expected duration media may be;
+// yt-dlp rounds durations to whole seconds
+const truncationTolerance = 2.0
+
+// Problem is one thing fsck found wrong with an artifact
+type Problem struct {
+	Path   string
+	ID     string
+	Kind   string
+	Issue  string
+	Detail string
+}
+
+// CheckOptions controls how thorough fsck is
+type CheckOptions struct {
+	Hash bool // Compare checksums as well as sizes; reads every file
+}
+
+// Report is what a check found
+type Report struct {
+	Problems []Problem
+	Checked  int  // Files on disk examined
+	Recorded int  // Healthy files found on disk and added to the inventory
+	Unprobed bool // ffprobe is not installed, so media durations were not checked
+}
+
+// Check reconciles the artifact inventory with ./data for an ID, or every ID when id
+// is empty. Healthy files missing from the inventory are recorded as found by fsck.
+func Check(db *util.Database, id string, opts CheckOptions) (*Report, error) {
+	records, err := db.GetArtifacts(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifacts: %v", err)
+	}
+	files, err := ScanArtifacts(id)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	problem := func(path, id, kind, issue, detail string) {
+		report.Problems = append(report.Problems, Problem{Path: path, ID: id, Kind: kind, Issue: issue, Detail: detail})
+	}
+
+	recorded := make(map[string]util.ArtifactRecord)
+	for _, rec := range records {
+		recorded[rec.Path] = rec
+		if _, err := os.Stat(rec.Path); os.IsNotExist(err) {
+			problem(rec.Path, rec.ID, rec.Kind, IssueMissing, "recorded by "+rec.Stage)
+		}
+	}
+
+	_, probeErr := exec.LookPath("ffprobe")
+	durations := newDurationCache()
+
+	for _, file := range files {
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			continue
+		}
+		report.Checked++
+
+		if _, err := os.Stat(filepath.Join(DataDir, file.ID+".json")); err != nil {
+			problem(file.Path, file.ID, file.Kind, IssueOrphan, "no metadata for "+file.ID)
+			continue
+		}
+		if info.Size() == 0 {
+			problem(file.Path, file.ID, file.Kind, IssueEmpty, "")
+			continue
+		}
+
+		if probeErr == nil && (file.Kind == "video" || file.Kind == "audio" || file.Kind == "stem") {
+			if issue, detail := durations.check(file); issue != "" {
+				problem(file.Path, file.ID, file.Kind, issue, detail)
+				continue
+			}
+		}
+
+		rec, ok := recorded[file.Path]
+		if !ok {
+			if err := RecordArtifact(db, file, "fsck"); err != nil {
+				return report, fmt.Errorf("failed to record %s: %v", file.Path, err)
+			}
+			report.Recorded++
+			continue
+		}
+		if rec.Size != info.Size() {
+			problem(file.Path, file.ID, file.Kind, IssueChanged, fmt.Sprintf("%d bytes, recorded %d", info.Size(), rec.Size))
+			continue
+		}
+		if opts.Hash {
+			sum, err := hashFile(file.Path)
+			if err != nil {
+				problem(file.Path, file.ID, file.Kind, IssueUnreadable, err.Error())
+			} else if sum != rec.SHA256 {
+				problem(file.Path, file.ID, file.Kind, IssueChanged, "checksum differs from the record")
+			}
+		}
+	}
+
+	report.Unprobed = probeErr != nil
+	return report, nil
+}
+
+// durationCache probes media once and knows what length each ID's media should be
+type durationCache struct {
+	probed   map[string]float64
+	expected map[string]float64 // From the metadata JSON, 0 when unknown
+}
+
+func newDurationCache() *durationCache {
+	return &durationCache{probed: make(map[string]float64), expected: make(map[string]float64)}
+}
+
+func (c *durationCache) probe(path string) (float64, error) {
+	if duration, ok := c.probed[path]; ok {
+		return duration, nil
+	}
+	duration, err := audio.GetAudioDuration(path)
+	if err != nil {
+		return 0, err
+	}
+	c.probed[path] = duration
+	return duration, nil
+}
+
+func (c *durationCache) metadataDuration(id string) float64 {
+	if duration, ok := c.expected[id]; ok {
+		return duration
+	}
+	var metadata struct {
+		Duration float64 `json:"duration"`
+	}
+	if data, err := os.ReadFile(filepath.Join(DataDir, id+".json")); err == nil {
+		json.Unmarshal(data, &metadata)
+	}
+	c.expected[id] = metadata.Duration
+	return metadata.Duration
+}
+
+// check probes a media file and compares its length with the video's: the metadata
+// duration for the MP4 and WAV, the WAV's for stems
+func (c *durationCache) check(file ArtifactFile) (issue, detail string) {
+	duration, err := c.probe(file.Path)
+	if err != nil {
+		return IssueUnreadable, "ffprobe could not read it"
+	}
+
+	expected, source := c.metadataDuration(file.ID), "metadata"
+	if file.Kind == "stem" {
+		expected, source = 0, "WAV"
+		if wav, err := c.probe(filepath.Join(DataDir, file.ID+".wav")); err == nil {
+			expected = wav
+		}
+	}
+	if expected > 0 && duration < expected-math.Max(truncationTolerance, expected*0.01) {
+		return IssueTruncated, fmt.Sprintf("%.1fs of %.1fs (%s)", duration, expected, source)
+	}
+	return "", ""
+}
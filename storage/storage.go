@@ -0,0 +1,327 @@
+package storage
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"starchive/audio"
+	"starchive/util"
+)
+
+// DataDir is the directory gc keeps in check
+const DataDir = "./data"
+
+// Policy says what gc may delete. Source media (metadata, WAVs, stems, subtitles,
+// thumbnails) is never touched; MP4s only with DropMP4.
+type Policy struct {
+	MaxSize     int64         // Bytes ./data may use; over it, caches, then sync outputs and demos, then renders go oldest first. 0 is no limit
+	MinFree     int64         // Bytes to keep free on the disk, enforced the same way as MaxSize. 0 is no minimum
+	KeepRenders int           // Newest blend renders to keep; older ones are deleted. Negative keeps all
+	TempAge     time.Duration // Caches, sync outputs, demos and leftover temp files older than this are deleted. 0 keeps them
+	DropMP4     bool          // Delete a video's MP4 once its WAV and stems exist
+}
+
+// DefaultPolicy keeps the last 20 renders and a week of temporary files, with no size limit
+var DefaultPolicy = Policy{KeepRenders: 20, TempAge: 7 * 24 * time.Hour}
+
+// AddFlags registers the policy's settings on a flag set, each name prefixed with
+// prefix, defaulting to the policy's current values
+func (p *Policy) AddFlags(fs *flag.FlagSet, prefix string) {
+	fs.Var((*sizeValue)(&p.MaxSize), prefix+"max-size", "Largest ./data may grow, e.g. 200G (0 for no limit)")
+	fs.Var((*sizeValue)(&p.MinFree), prefix+"min-free", "Free disk space to keep, e.g. 10G (0 for no minimum)")
+	fs.IntVar(&p.KeepRenders, prefix+"keep-renders", p.KeepRenders, "Newest blend renders to keep (-1 keeps all)")
+	fs.Var((*daysValue)(&p.TempAge), prefix+"temp-days", "Delete caches, sync outputs and demos older than this many days (0 keeps them)")
+	fs.BoolVar(&p.DropMP4, prefix+"drop-mp4", p.DropMP4, "Delete MP4s whose WAV and stems have been extracted")
+}
+
+// Removal is a file gc deleted, or would delete in a dry run
+type Removal struct {
+	Path   string
+	Size   int64
+	Reason string
+}
+
+// Result is what a gc run did
+type Result struct {
+	Removals []Removal
+	Freed    int64
+	Size     int64 // Size of ./data before the run
+	Errors   []error
+}
+
+// File classes, in the order they are given up when space is needed
+const (
+	classCache = iota
+	classDerived
+	classRender
+	classTemp
+	classVideo
+)
+
+type dataFile struct {
+	path     string
+	size     int64
+	modified time.Time
+	class    int
+}
+
+var (
+	renderPattern  = regexp.MustCompile(`^blend_.+_\d+\.wav$`)
+	derivedPattern = regexp.MustCompile(`_(inv_)?sync_to_.+\.wav$|_demo\.wav$`)
+	cacheDirs      = []string{audio.TranscodeCacheDir, audio.PeaksCacheDir, audio.RubberbandCacheDir}
+)
+
+// Collect applies a policy to ./data, deleting what it allows unless dryRun is set.
+// Age and count rules go first; then, if ./data is over MaxSize or the disk has less
+// than MinFree, the rest of the deletable files go oldest first until it is not.
+func Collect(policy Policy, dryRun bool) (*Result, error) {
+	db, err := util.InitDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	files, err := scanData(db)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := util.DirSize(DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure %s: %v", DataDir, err)
+	}
+	result := &Result{Size: size}
+
+	removed := make(map[string]bool)
+	remove := func(file dataFile, reason string) {
+		if removed[file.path] {
+			return
+		}
+		removed[file.path] = true
+		if !dryRun {
+			if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+				result.Errors = append(result.Errors, err)
+				return
+			}
+		}
+		result.Removals = append(result.Removals, Removal{Path: file.path, Size: file.size, Reason: reason})
+		result.Freed += file.size
+	}
+
+	// Newest first, so renders past KeepRenders are the old ones
+	sort.Slice(files, func(i, j int) bool { return files[i].modified.After(files[j].modified) })
+
+	renders := 0
+	for _, file := range files {
+		switch file.class {
+		case classRender:
+			renders++
+			if policy.KeepRenders >= 0 && renders > policy.KeepRenders {
+				remove(file, fmt.Sprintf("older than the newest %d renders", policy.KeepRenders))
+			}
+		case classCache, classDerived, classTemp:
+			if policy.TempAge > 0 && time.Since(file.modified) > policy.TempAge {
+				remove(file, fmt.Sprintf("unchanged for %d days", int(time.Since(file.modified).Hours()/24)))
+			}
+		case classVideo:
+			if policy.DropMP4 {
+				remove(file, "WAV and stems extracted")
+			}
+		}
+	}
+
+	needed := int64(0)
+	if policy.MaxSize > 0 {
+		needed = result.Size - policy.MaxSize
+	}
+	if policy.MinFree > 0 {
+		if _, _, free, err := util.Usage(DataDir); err == nil {
+			needed = max(needed, policy.MinFree-int64(free))
+		} else {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to read free space: %v", err))
+		}
+	}
+
+	// Oldest first within each class, cheapest to lose first
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].class != files[j].class {
+			return files[i].class < files[j].class
+		}
+		return files[i].modified.Before(files[j].modified)
+	})
+	for _, file := range files {
+		if result.Freed >= needed {
+			break
+		}
+		if file.class <= classRender {
+			remove(file, "over the storage limit")
+		}
+	}
+
+	// Dropped MP4s are gone on purpose, so fsck should not report them missing
+	if !dryRun {
+		forgetArtifacts(db, files, result)
+	}
+
+	return result, nil
+}
+
+// forgetArtifacts removes the inventory records of removed files that were artifacts
+func forgetArtifacts(db *util.Database, files []dataFile, result *Result) {
+	removed := make(map[string]bool)
+	for _, removal := range result.Removals {
+		removed[removal.Path] = true
+	}
+
+	for _, file := range files {
+		if file.class != classVideo || !removed[file.path] {
+			continue
+		}
+		if err := db.DeleteArtifact(file.path); err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+	}
+}
+
+// scanData lists the files gc may delete: blend renders, sync outputs and demos at
+// the top of ./data, cache entries, leftover temp files and MP4s that are no longer
+// needed for extraction
+func scanData(db *util.Database) ([]dataFile, error) {
+	entries, err := os.ReadDir(DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", DataDir, err)
+	}
+
+	var files []dataFile
+	add := func(path string, info os.FileInfo, class int) {
+		files = append(files, dataFile{path: path, size: info.Size(), modified: info.ModTime(), class: class})
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(DataDir, name)
+
+		switch {
+		case renderPattern.MatchString(name):
+			add(path, info, classRender)
+		case derivedPattern.MatchString(name):
+			add(path, info, classDerived)
+		case isTemp(name):
+			add(path, info, classTemp)
+		case strings.HasSuffix(name, ".mp4") && !strings.HasSuffix(name, "-small.mp4"):
+			if extracted(db, strings.TrimSuffix(name, ".mp4")) {
+				add(path, info, classVideo)
+			}
+		}
+	}
+
+	for _, dir := range cacheDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue // Not created yet
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			if isTemp(entry.Name()) {
+				add(filepath.Join(dir, entry.Name()), info, classTemp) // Possibly still being written
+			} else {
+				add(filepath.Join(dir, entry.Name()), info, classCache)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// isTemp reports whether a file is left over from an interrupted write or download
+func isTemp(name string) bool {
+	return strings.HasSuffix(name, ".tmp") || strings.HasSuffix(name, ".part") || strings.Contains(name, ".tmp.")
+}
+
+// extracted reports whether an ID's audio has been converted and separated, so its
+// MP4 is no longer needed by the pipeline
+func extracted(db *util.Database, id string) bool {
+	if _, err := os.Stat(filepath.Join(DataDir, id+".wav")); err != nil {
+		return false
+	}
+	return audio.HasStem(db, id, audio.StemVocals) && len(audio.ListStemNames(db, id)) >= 2
+}
+
+// ParseSize reads a byte count such as 500M, 20G or 1.5T (powers of 1024); a plain
+// number is bytes
+func ParseSize(s string) (int64, error) {
+	number := strings.ToUpper(strings.TrimSpace(s))
+	number = strings.TrimSuffix(strings.TrimSuffix(number, "B"), "I")
+
+	multiplier := int64(1)
+	if number != "" {
+		if i := strings.IndexByte("KMGT", number[len(number)-1]); i >= 0 {
+			multiplier = int64(1) << (10 * (i + 1))
+			number = number[:len(number)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// sizeValue is a flag.Value for sizes in ParseSize's format
+type sizeValue int64
+
+func (v *sizeValue) Set(s string) error {
+	size, err := ParseSize(s)
+	if err != nil {
+		return err
+	}
+	*v = sizeValue(size)
+	return nil
+}
+
+func (v *sizeValue) String() string {
+	if v == nil || *v == 0 {
+		return "0"
+	}
+	return util.Pretty(uint64(*v))
+}
+
+// daysValue is a flag.Value for durations given in whole days
+type daysValue time.Duration
+
+func (v *daysValue) Set(s string) error {
+	days, err := strconv.Atoi(s)
+	if err != nil || days < 0 {
+		return fmt.Errorf("invalid number of days: %q", s)
+	}
+	*v = daysValue(time.Duration(days) * 24 * time.Hour)
+	return nil
+}
+
+func (v *daysValue) String() string {
+	if v == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(time.Duration(*v).Hours() / 24))
+}
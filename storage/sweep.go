@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"starchive/util"
+)
+
+// DefaultSweepInterval is how often the sweeper checks free space
+const DefaultSweepInterval = 10 * time.Minute
+
+// Sweep checks free space on the data disk every interval and runs Collect with the
+// policy whenever it is below policy.MinFree. It never returns; run it in a goroutine.
+func Sweep(policy Policy, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		_, _, free, err := util.Usage(DataDir)
+		if err != nil {
+			fmt.Printf("[Starchive] Sweeper: error reading free space: %v\n", err)
+			continue
+		}
+		if int64(free) >= policy.MinFree {
+			continue
+		}
+
+		fmt.Printf("[Starchive] Sweeper: %s free, below %s; collecting\n", util.Pretty(free), util.Pretty(uint64(policy.MinFree)))
+		result, err := Collect(policy, false)
+		if err != nil {
+			fmt.Printf("[Starchive] Sweeper: %v\n", err)
+			continue
+		}
+		for _, err := range result.Errors {
+			fmt.Printf("[Starchive] Sweeper: %v\n", err)
+		}
+		fmt.Printf("[Starchive] Sweeper: removed %d files, freed %s\n", len(result.Removals), util.Pretty(uint64(result.Freed)))
+	}
+}
@@ -62,6 +62,33 @@ func InitDatabase() (*Database, error) {
 		key TEXT
 	);
 	CREATE INDEX IF NOT EXISTS idx_last_modified ON video_metadata(last_modified);
+	CREATE TABLE IF NOT EXISTS pipeline_stages (
+		id TEXT NOT NULL,
+		stage TEXT NOT NULL,
+		status TEXT NOT NULL,
+		error TEXT,
+		started_at INTEGER,
+		finished_at INTEGER,
+		PRIMARY KEY (id, stage)
+	);
+	CREATE TABLE IF NOT EXISTS stems (
+		id TEXT NOT NULL,
+		stem TEXT NOT NULL,
+		model TEXT NOT NULL,
+		path TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (id, stem, model)
+	);
+	CREATE TABLE IF NOT EXISTS artifacts (
+		path TEXT PRIMARY KEY,
+		id TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		sha256 TEXT NOT NULL,
+		stage TEXT,
+		created_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_artifacts_id ON artifacts(id);
 	`
 	
 	if _, err := db.Exec(createTableSQL); err != nil {
@@ -345,4 +372,169 @@ func (d *Database) StoreFrequencyData(id string, fundamentalFreq, peakFreq, spec
 	_, err = d.db.Exec(`UPDATE video_metadata SET fundamental_freq = ?, peak_freq = ?, spectral_centroid = ? WHERE id = ?`,
 		fundamentalFreq, peakFreq, spectralCentroid, id)
 	return err
-}
\ No newline at end of file
+}
+
+// StageStatus records the outcome of one ingest pipeline stage for a track
+type StageStatus struct {
+	ID         string
+	Stage      string
+	Status     string // "running", "done", "failed"
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// SetStageStatus records the status of a pipeline stage for a track
+func (d *Database) SetStageStatus(id, stage, status, errMsg string) error {
+	now := time.Now().Unix()
+	if status == "running" {
+		_, err := d.db.Exec(`INSERT OR REPLACE INTO pipeline_stages (id, stage, status, error, started_at, finished_at)
+			VALUES (?, ?, ?, '', ?, 0)`, id, stage, status, now)
+		return err
+	}
+
+	_, err := d.db.Exec(`INSERT INTO pipeline_stages (id, stage, status, error, started_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id, stage) DO UPDATE SET status = excluded.status, error = excluded.error, finished_at = excluded.finished_at`,
+		id, stage, status, errMsg, now, now)
+	return err
+}
+
+// GetStageStatuses returns the recorded pipeline stage statuses for a track keyed by stage name
+func (d *Database) GetStageStatuses(id string) (map[string]StageStatus, error) {
+	rows, err := d.db.Query(`SELECT id, stage, status, error, started_at, finished_at
+		FROM pipeline_stages WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := make(map[string]StageStatus)
+	for rows.Next() {
+		var st StageStatus
+		var errMsg sql.NullString
+		var startedAt, finishedAt sql.NullInt64
+
+		if err := rows.Scan(&st.ID, &st.Stage, &st.Status, &errMsg, &startedAt, &finishedAt); err != nil {
+			continue
+		}
+
+		st.Error = errMsg.String
+		st.StartedAt = time.Unix(startedAt.Int64, 0)
+		st.FinishedAt = time.Unix(finishedAt.Int64, 0)
+		statuses[st.Stage] = st
+	}
+
+	return statuses, nil
+}
+
+// DeleteStageStatuses forgets every recorded stage of a track, so ingest runs them again
+func (d *Database) DeleteStageStatuses(id string) error {
+	_, err := d.db.Exec("DELETE FROM pipeline_stages WHERE id = ?", id)
+	return err
+}
+
+// StemRecord describes one separated stem file for a track
+type StemRecord struct {
+	ID        string
+	Stem      string // e.g. "vocals", "instrumental", "drums", "bass", "other"
+	Model     string // separation model tag from the filename, e.g. "UVR_MDXNET_Main"
+	Path      string
+	CreatedAt time.Time
+}
+
+// RecordStem adds or updates a stem file in the stem registry
+func (d *Database) RecordStem(id, stem, model, path string) error {
+	_, err := d.db.Exec(`INSERT OR REPLACE INTO stems (id, stem, model, path, created_at) VALUES (?, ?, ?, ?, ?)`,
+		id, stem, model, path, time.Now().Unix())
+	return err
+}
+
+// GetStems returns all registered stems for a track
+func (d *Database) GetStems(id string) ([]StemRecord, error) {
+	rows, err := d.db.Query(`SELECT id, stem, model, path, created_at FROM stems WHERE id = ? ORDER BY stem, model`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []StemRecord
+	for rows.Next() {
+		var rec StemRecord
+		var createdAt int64
+		if err := rows.Scan(&rec.ID, &rec.Stem, &rec.Model, &rec.Path, &createdAt); err != nil {
+			continue
+		}
+		rec.CreatedAt = time.Unix(createdAt, 0)
+		results = append(results, rec)
+	}
+
+	return results, nil
+}
+
+// DeleteStems removes all registered stems for a track
+func (d *Database) DeleteStems(id string) error {
+	_, err := d.db.Exec("DELETE FROM stems WHERE id = ?", id)
+	return err
+}
+
+// ArtifactRecord is one file in the artifact inventory
+type ArtifactRecord struct {
+	Path      string
+	ID        string
+	Kind      string // "video", "audio", "stem", "metadata", "thumbnail", "subtitles", "transcript"
+	Size      int64
+	SHA256    string
+	Stage     string // Pipeline stage that produced the file, or "fsck" when found on disk
+	CreatedAt time.Time
+}
+
+// RecordArtifact adds or updates a file in the artifact inventory
+func (d *Database) RecordArtifact(rec ArtifactRecord) error {
+	_, err := d.db.Exec(`INSERT OR REPLACE INTO artifacts (path, id, kind, size, sha256, stage, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		rec.Path, rec.ID, rec.Kind, rec.Size, rec.SHA256, rec.Stage, rec.CreatedAt.Unix())
+	return err
+}
+
+// GetArtifacts returns the recorded artifacts of a track, or of every track when id is empty
+func (d *Database) GetArtifacts(id string) ([]ArtifactRecord, error) {
+	query := `SELECT path, id, kind, size, sha256, stage, created_at FROM artifacts`
+	var args []interface{}
+	if id != "" {
+		query += ` WHERE id = ?`
+		args = append(args, id)
+	}
+	rows, err := d.db.Query(query+` ORDER BY id, path`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []ArtifactRecord
+	for rows.Next() {
+		var rec ArtifactRecord
+		var stage sql.NullString
+		var createdAt int64
+		if err := rows.Scan(&rec.Path, &rec.ID, &rec.Kind, &rec.Size, &rec.SHA256, &stage, &createdAt); err != nil {
+			continue
+		}
+		rec.Stage = stage.String
+		rec.CreatedAt = time.Unix(createdAt, 0)
+		results = append(results, rec)
+	}
+
+	return results, nil
+}
+
+// DeleteArtifact removes one file from the artifact inventory
+func (d *Database) DeleteArtifact(path string) error {
+	_, err := d.db.Exec("DELETE FROM artifacts WHERE path = ?", path)
+	return err
+}
+
+// DeleteArtifacts removes every recorded artifact of a track
+func (d *Database) DeleteArtifacts(id string) error {
+	_, err := d.db.Exec("DELETE FROM artifacts WHERE id = ?", id)
+	return err
+}
@@ -50,6 +50,34 @@ func HandleRmCommand(args []string) {
 		}
 	}
 
+	// The artifact inventory and stem registry also know files the list above does not,
+	// such as other models' stems
+	db, err := InitDatabase()
+	if err != nil {
+		fmt.Printf("Warning: artifact inventory not available: %v\n", err)
+	} else {
+		defer db.Close()
+		var recorded []string
+		artifacts, _ := db.GetArtifacts(id)
+		for _, rec := range artifacts {
+			recorded = append(recorded, rec.Path)
+		}
+		stems, _ := db.GetStems(id)
+		for _, rec := range stems {
+			recorded = append(recorded, rec.Path)
+		}
+		for _, path := range recorded {
+			filename := filepath.Base(path)
+			listed := false
+			for _, f := range filesToRemove {
+				listed = listed || f == filename
+			}
+			if _, err := os.Stat(filepath.Join(dataDir, filename)); err == nil && !listed {
+				filesToRemove = append(filesToRemove, filename)
+			}
+		}
+	}
+
 	if len(filesToRemove) == 0 {
 		fmt.Printf("No files found matching ID: %s\n", id)
 		return
@@ -88,6 +116,18 @@ func HandleRmCommand(args []string) {
 		}
 	}
 
+	if db != nil {
+		if err := db.DeleteArtifacts(id); err != nil {
+			fmt.Printf("Warning: failed to clear artifact records: %v\n", err)
+		}
+		if err := db.DeleteStems(id); err != nil {
+			fmt.Printf("Warning: failed to clear stem records: %v\n", err)
+		}
+		// Without its files the stages are no longer done; a later ingest must redo them
+		if err := db.DeleteStageStatuses(id); err != nil {
+			fmt.Printf("Warning: failed to clear pipeline stages: %v\n", err)
+		}
+	}
 	fmt.Printf("Removed %d files\n", removedCount)
 }
 
@@ -0,0 +1,260 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TokenFile holds the per-install API token the extension sends as a bearer token
+const TokenFile = "./data/.api_token"
+
+// DefaultAddr is where the server listens unless told otherwise; only this machine can reach it
+const DefaultAddr = "127.0.0.1:3009"
+
+// MaxBodyBytes caps request bodies; a full cookie jar from the extension is a few tens of KB
+const MaxBodyBytes = 1 << 20
+
+// SessionCookie lets the library UI in a browser tab authenticate images and media
+// requests, which can't carry a bearer token
+const SessionCookie = "starchive_session"
+
+// Policy decides which requests reach the API handlers
+type Policy struct {
+	Token   string   // Required as "Authorization: Bearer <token>"
+	Origins []string // Allowed Origin prefixes; requests without an Origin are not from a page
+	MaxBody int64
+	Addr    string // Listen address; requests must name it, or a loopback name with its port, as their Host
+
+	hosts map[string]bool // Host values Addr can be reached by, see listenHosts
+}
+
+// DefaultOrigins lets the Firefox extension in, whatever UUID Firefox gave it
+var DefaultOrigins = []string{"moz-extension://"}
+
+// LoadOrCreateToken reads the API token, generating and saving one on first run.
+// created is true when the token is new and has to be pasted into the extension.
+func LoadOrCreateToken() (token string, created bool, err error) {
+	data, err := os.ReadFile(TokenFile)
+	if err == nil {
+		if token = strings.TrimSpace(string(data)); token != "" {
+			return token, false, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", false, fmt.Errorf("failed to read %s: %v", TokenFile, err)
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", false, fmt.Errorf("failed to generate API token: %v", err)
+	}
+	token = hex.EncodeToString(raw)
+
+	if err := os.MkdirAll(filepath.Dir(TokenFile), 0755); err != nil {
+		return "", false, fmt.Errorf("failed to create %s: %v", filepath.Dir(TokenFile), err)
+	}
+	if err := os.WriteFile(TokenFile, []byte(token+"\n"), 0600); err != nil {
+		return "", false, fmt.Errorf("failed to write %s: %v", TokenFile, err)
+	}
+	return token, true, nil
+}
+
+// Protect wraps an API handler with the policy: an allowed Origin (answering CORS
+// preflights for it), a valid bearer token or session cookie and a bounded body
+func (p Policy) Protect(next http.HandlerFunc) http.HandlerFunc {
+	return p.checkOrigin(func(w http.ResponseWriter, r *http.Request) {
+		if !p.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="starchive"`)
+			http.Error(w, "Missing or invalid API token", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// checkOrigin applies the Host check, the Origin allow-list and the body limit
+func (p Policy) checkOrigin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// A DNS-rebinding page reaches us under its own name, so the Host gives it away
+		if !p.hosts[strings.ToLower(r.Host)] {
+			fmt.Printf("[Starchive] Rejected %s %s for host %s\n", r.Method, r.URL.Path, r.Host)
+			http.Error(w, "Host not allowed", http.StatusForbidden)
+			return
+		}
+
+		origin := r.Header.Get("Origin")
+		if origin != "" {
+			if !p.allowedOrigin(origin) {
+				fmt.Printf("[Starchive] Rejected %s %s from origin %s\n", r.Method, r.URL.Path, origin)
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Vary", "Origin")
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		if r.ContentLength > p.MaxBody {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, p.MaxBody)
+
+		next(w, r)
+	}
+}
+
+// allowedOrigin reports whether a browser origin may call the API: an allowed prefix,
+// or the library UI served by this server at one of its listen hosts
+func (p Policy) allowedOrigin(origin string) bool {
+	if host, ok := strings.CutPrefix(origin, "http://"); ok && p.hosts[strings.ToLower(host)] {
+		return true
+	}
+	for _, allowed := range p.Origins {
+		if allowed != "" && strings.HasPrefix(origin, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// listenHosts returns the Host values the listen address answers to: localhost,
+// 127.0.0.1 and [::1] with its port, its own host when it names one, and this
+// machine's interface addresses when it listens on all of them
+func (p Policy) listenHosts() map[string]bool {
+	addr := p.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		fmt.Printf("[Starchive] Warning: invalid listen address %s: %v\n", addr, err)
+		return nil
+	}
+
+	names := []string{"localhost", "127.0.0.1", "::1"}
+	switch host {
+	case "", "0.0.0.0", "::":
+		if addrs, err := net.InterfaceAddrs(); err == nil {
+			for _, a := range addrs {
+				if ipNet, ok := a.(*net.IPNet); ok {
+					names = append(names, ipNet.IP.String())
+				}
+			}
+		}
+	default:
+		names = append(names, host)
+	}
+
+	hosts := make(map[string]bool)
+	for _, name := range names {
+		hosts[strings.ToLower(net.JoinHostPort(name, port))] = true
+		if port == "80" {
+			hosts[strings.ToLower(name)] = true // Browsers leave the default port out
+		}
+	}
+	return hosts
+}
+
+// authorized checks the bearer token or the session cookie in constant time
+func (p Policy) authorized(r *http.Request) bool {
+	if p.Token == "" {
+		return false
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(p.Token)) == 1
+	}
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(p.sessionValue())) == 1
+	}
+	return false
+}
+
+// sessionValue is the session cookie for the token; it is derived so the token itself
+// never sits in the browser's cookie jar
+func (p Policy) sessionValue() string {
+	mac := hmac.New(sha256.New, []byte(p.Token))
+	mac.Write([]byte("starchive session"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// handleSession exchanges the API token for a session cookie (POST) or clears it (DELETE)
+func (p Policy) handleSession(w http.ResponseWriter, r *http.Request) {
+	cookie := &http.Cookie{
+		Name:     SessionCookie,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		body, ok := readBody(w, r)
+		if !ok {
+			return
+		}
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if p.Token == "" || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(req.Token)), []byte(p.Token)) != 1 {
+			http.Error(w, "Invalid API token", http.StatusUnauthorized)
+			return
+		}
+		cookie.Value = p.sessionValue()
+	case http.MethodDelete:
+		cookie.MaxAge = -1
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	http.SetCookie(w, cookie)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readBody reads a request body within the policy's limit, answering the request
+// itself when it can't
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, "Error reading request body", http.StatusBadRequest)
+		}
+		return nil, false
+	}
+	return body, true
+}
+
+// IsLoopback reports whether a listen address only accepts local connections
+func IsLoopback(addr string) bool {
+	host := addr
+	if i := strings.LastIndex(addr, ":"); i >= 0 {
+		host = addr[:i]
+	}
+	host = strings.Trim(host, "[]")
+	return host == "localhost" || host == "::1" || strings.HasPrefix(host, "127.")
+}
@@ -0,0 +1,309 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"starchive/blend"
+	"starchive/util"
+)
+
+// BlendSessionIdle is how long an editor session lives without requests
+const BlendSessionIdle = 2 * time.Hour
+
+// blendSession is a blend shell driven by the browser editor instead of a terminal
+type blendSession struct {
+	mu    sync.Mutex // One command at a time; the shell is not safe for concurrent use
+	shell *blend.Shell
+	db    *util.Database
+	used  time.Time
+}
+
+var (
+	blendSessions      = make(map[string]*blendSession)
+	blendSessionsMutex sync.Mutex
+)
+
+// blendRequest is a change to a session: a shell command line as typed, or an action
+// the editor's controls send, which is turned into the same command
+type blendRequest struct {
+	Command string   `json:"command"`
+	Action  string   `json:"action"` // pitch, tempo, volume, window, split, segments, place, shift, toggle, beat-detect, gap-finder, render
+	Track   int      `json:"track"`
+	Segment int      `json:"segment"` // 1-based, as in track:segment references
+	Value   float64  `json:"value"`   // pitch, tempo, volume and window settings
+	At      float64  `json:"at"`      // place: seconds on the target track
+	By      float64  `json:"by"`      // shift: seconds
+	Start   *float64 `json:"start"`   // render: seconds, default the loop or the middle
+	Length  float64  `json:"length"`  // render: seconds, default as long as both tracks last
+}
+
+// handleBlend serves the blend editor API:
+//
+//	POST   /api/blend                         {id1, id2} opens a session
+//	GET    /api/blend/<session>               session state
+//	POST   /api/blend/<session>               {command} or {action, ...} runs a shell command
+//	DELETE /api/blend/<session>               closes the session
+//	GET    /api/blend/<session>/peaks/<track> waveform of a track's audio
+func handleBlend(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/blend"), "/"), "/")
+	if parts[0] == "" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		createBlendSession(w, r)
+		return
+	}
+
+	id := parts[0]
+	session := lookupBlendSession(id)
+	if session == nil {
+		http.Error(w, "Blend session not found", http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 3 && parts[1] == "peaks" && r.Method == http.MethodGet {
+		writeBlendPeaks(w, r, session, parts[2])
+		return
+	}
+	if len(parts) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		writeBlendState(w, http.StatusOK, id, session, "")
+
+	case http.MethodPost:
+		body, ok := readBody(w, r)
+		if !ok {
+			return
+		}
+		var req blendRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		session.mu.Lock()
+		defer session.mu.Unlock()
+
+		line, err := req.commandLine(session.shell)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		output, exit := session.shell.Exec(line)
+		if exit {
+			closeBlendSession(id)
+		}
+		writeBlendState(w, http.StatusOK, id, session, output)
+
+	case http.MethodDelete:
+		closeBlendSession(id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// createBlendSession opens a headless shell for two tracks
+func createBlendSession(w http.ResponseWriter, r *http.Request) {
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		ID1 string `json:"id1"`
+		ID2 string `json:"id2"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if !libraryIDPattern.MatchString(req.ID1) || !libraryIDPattern.MatchString(req.ID2) {
+		http.Error(w, "Two valid video IDs are required", http.StatusBadRequest)
+		return
+	}
+
+	db, err := util.InitDatabase()
+	if err != nil {
+		fmt.Printf("[Starchive] Error opening database: %v\n", err)
+		http.Error(w, "Database not available", http.StatusInternalServerError)
+		return
+	}
+	shell, err := blend.OpenShell(req.ID1, req.ID2, db)
+	if err != nil {
+		db.Close()
+		http.Error(w, fmt.Sprintf("Cannot blend these tracks: %v", err), http.StatusBadRequest)
+		return
+	}
+	shell.Headless = true
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		db.Close()
+		http.Error(w, "Error creating session", http.StatusInternalServerError)
+		return
+	}
+	id := hex.EncodeToString(raw)
+	session := &blendSession{shell: shell, db: db, used: time.Now()}
+
+	blendSessionsMutex.Lock()
+	expireBlendSessions()
+	blendSessions[id] = session
+	blendSessionsMutex.Unlock()
+
+	fmt.Printf("[Starchive] Blend session opened for %s + %s\n", req.ID1, req.ID2)
+	writeBlendState(w, http.StatusCreated, id, session, "")
+}
+
+// lookupBlendSession returns a live session and marks it used
+func lookupBlendSession(id string) *blendSession {
+	blendSessionsMutex.Lock()
+	defer blendSessionsMutex.Unlock()
+
+	expireBlendSessions()
+	session := blendSessions[id]
+	if session != nil {
+		session.used = time.Now()
+	}
+	return session
+}
+
+// expireBlendSessions closes sessions idle longer than BlendSessionIdle; the caller
+// holds blendSessionsMutex
+func expireBlendSessions() {
+	for id, session := range blendSessions {
+		if time.Since(session.used) > BlendSessionIdle {
+			delete(blendSessions, id)
+			go closeShell(session)
+		}
+	}
+}
+
+func closeBlendSession(id string) {
+	blendSessionsMutex.Lock()
+	session := blendSessions[id]
+	delete(blendSessions, id)
+	blendSessionsMutex.Unlock()
+
+	if session != nil {
+		go closeShell(session)
+	}
+}
+
+// closeShell releases a session's database once any running command has finished
+func closeShell(session *blendSession) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.db.Close()
+}
+
+// writeBlendState answers with the session's state and the output of the command
+// that produced it; the latest render is linked for streaming
+func writeBlendState(w http.ResponseWriter, status int, id string, session *blendSession, output string) {
+	state := session.shell.State()
+	render := ""
+	if state.LastRender != "" {
+		render = mediaURL(session.shell.ID1, filepath.Base(state.LastRender))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session": id,
+		"state":   state,
+		"output":  output,
+		"render":  render,
+	})
+}
+
+// writeBlendPeaks answers with the waveform of the audio a track currently plays
+// (its first stem), at the peak level for ?width=N pixels
+func writeBlendPeaks(w http.ResponseWriter, r *http.Request, session *blendSession, track string) {
+	session.mu.Lock()
+	path := session.shell.InputPath1
+	if track == "2" {
+		path = session.shell.InputPath2
+	} else if track != "1" {
+		session.mu.Unlock()
+		http.Error(w, "Invalid track (use 1 or 2)", http.StatusBadRequest)
+		return
+	}
+	session.mu.Unlock()
+
+	writePeakLevel(w, r, path)
+}
+
+// commandLine turns a request into the blend shell command it stands for
+func (req blendRequest) commandLine(shell *blend.Shell) (string, error) {
+	if req.Command != "" {
+		return req.Command, nil
+	}
+
+	trackAction := map[string]bool{"pitch": true, "tempo": true, "volume": true, "split": true,
+		"segments": true, "place": true, "shift": true, "toggle": true, "gap-finder": true}
+	if trackAction[req.Action] && req.Track != 1 && req.Track != 2 {
+		return "", fmt.Errorf("%s needs track 1 or 2", req.Action)
+	}
+	ref := fmt.Sprintf("%d:%d", req.Track, req.Segment)
+
+	switch req.Action {
+	case "pitch":
+		return fmt.Sprintf("pitch%d %d", req.Track, int(math.Round(req.Value))), nil
+	case "tempo", "volume":
+		return fmt.Sprintf("%s%d %g", req.Action, req.Track, req.Value), nil
+	case "window":
+		window1, window2 := shell.Window1, shell.Window2
+		switch req.Track {
+		case 1:
+			window1 = req.Value
+		case 2:
+			window2 = req.Value
+		default:
+			return "", fmt.Errorf("window needs track 1 or 2")
+		}
+		return fmt.Sprintf("window %gs %gs", window1, window2), nil
+	case "split", "segments", "gap-finder":
+		return fmt.Sprintf("%s %d", req.Action, req.Track), nil
+	case "beat-detect":
+		if req.Track == 1 || req.Track == 2 {
+			return fmt.Sprintf("beat-detect %d", req.Track), nil
+		}
+		return "beat-detect both", nil
+	case "place":
+		return fmt.Sprintf("place %s at %gs", ref, req.At), nil
+	case "shift":
+		return fmt.Sprintf("shift %s %+gs", ref, req.By), nil
+	case "toggle":
+		return "toggle " + ref, nil
+	case "render":
+		if req.Start == nil {
+			if req.Length > 0 {
+				return "", fmt.Errorf("render needs a start with a length")
+			}
+			return "render", nil
+		}
+		if req.Length > 0 {
+			return fmt.Sprintf("render %gs %g", *req.Start, req.Length), nil
+		}
+		return fmt.Sprintf("render %gs", *req.Start), nil
+	case "":
+		return "", fmt.Errorf("a command or action is required")
+	}
+	return "", fmt.Errorf("unknown action: %s", req.Action)
+}
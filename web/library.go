@@ -0,0 +1,253 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"starchive/audio"
+	"starchive/storage"
+	"starchive/util"
+)
+
+// LibraryEntry is one archived video as the library UI lists it
+type LibraryEntry struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Author     string   `json:"author"`
+	Duration   float64  `json:"duration"`
+	BPM        *float64 `json:"bpm"`
+	Key        *string  `json:"key"`
+	VocalDone  bool     `json:"vocalDone"`
+	Stems      []string `json:"stems"`
+	Thumbnail  string   `json:"thumbnail,omitempty"` // URL, empty without a thumbnail
+	Transcript bool     `json:"transcript"`
+	Modified   int64    `json:"modified"`
+}
+
+// Artifact is a file in ./data that belongs to a video; URL streams it from /media
+type Artifact struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"` // video, audio, stem, render, thumbnail, subtitles, transcript, metadata
+	Stem     string `json:"stem,omitempty"`
+	Size     int64  `json:"size"`
+	Modified int64  `json:"modified"`
+	URL      string `json:"url"`
+}
+
+var libraryIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// jsonDetails caches the uploader and duration read from yt-dlp JSON files, which
+// are large, keyed by path and invalidated by modification time
+var (
+	jsonDetails      = make(map[string]jsonDetail)
+	jsonDetailsMutex sync.Mutex
+)
+
+type jsonDetail struct {
+	modified time.Time
+	author   string
+	duration float64
+}
+
+// handleLibrary lists the archive (GET /api/library) or one video with its transcript,
+// artifacts and pipeline stages (GET /api/library/<id>)
+func handleLibrary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db, err := util.InitDatabase()
+	if err != nil {
+		fmt.Printf("[Starchive] Error opening database: %v\n", err)
+		http.Error(w, "Database not available", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/library"), "/")
+	w.Header().Set("Content-Type", "application/json")
+
+	if id == "" {
+		entries, err := libraryEntries(db)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error reading library: %v", err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"videos": entries})
+		return
+	}
+
+	if !libraryIDPattern.MatchString(id) {
+		http.Error(w, "Invalid video ID", http.StatusBadRequest)
+		return
+	}
+	entry, ok := libraryEntry(db, id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	transcript := ""
+	if content, err := os.ReadFile(filepath.Join("./data", id+".txt")); err == nil {
+		transcript = string(content)
+	}
+
+	stages := make(map[string]string)
+	if statuses, err := db.GetStageStatuses(id); err == nil {
+		for stage, status := range statuses {
+			stages[stage] = status.Status
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"video":      entry,
+		"transcript": transcript,
+		"artifacts":  findArtifacts(db, id),
+		"stages":     stages,
+	})
+}
+
+// libraryEntries lists every video with a metadata file, newest first, the way
+// `starchive ls` finds them
+func libraryEntries(db *util.Database) ([]LibraryEntry, error) {
+	files, err := os.ReadDir("./data")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []LibraryEntry{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		if entry, ok := libraryEntry(db, strings.TrimSuffix(file.Name(), ".json")); ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Modified > entries[j].Modified })
+	return entries, nil
+}
+
+// libraryEntry collects what the library shows about one video
+func libraryEntry(db *util.Database, id string) (LibraryEntry, bool) {
+	jsonPath := filepath.Join("./data", id+".json")
+	info, err := os.Stat(jsonPath)
+	if err != nil {
+		return LibraryEntry{}, false
+	}
+
+	entry := LibraryEntry{ID: id, Title: id, Modified: info.ModTime().Unix(), Stems: []string{}}
+	if metadata, found := db.GetCachedMetadata(id); found {
+		if metadata.Title != nil && *metadata.Title != "" {
+			entry.Title = *metadata.Title
+		}
+		entry.BPM = metadata.BPM
+		entry.Key = metadata.Key
+		entry.VocalDone = metadata.VocalDone
+	}
+
+	detail := readJSONDetail(jsonPath, info.ModTime())
+	entry.Author, entry.Duration = detail.author, detail.duration
+
+	if names := audio.ListStemNames(db, id); names != nil {
+		entry.Stems = names
+	}
+	if _, err := os.Stat(filepath.Join("./data", id+".jpg")); err == nil {
+		entry.Thumbnail = mediaURL(id, "thumbnail")
+	}
+	if _, err := os.Stat(filepath.Join("./data", id+".txt")); err == nil {
+		entry.Transcript = true
+	}
+	return entry, true
+}
+
+// readJSONDetail returns the uploader and duration from a yt-dlp JSON file
+func readJSONDetail(path string, modified time.Time) jsonDetail {
+	jsonDetailsMutex.Lock()
+	defer jsonDetailsMutex.Unlock()
+
+	if detail, ok := jsonDetails[path]; ok && detail.modified.Equal(modified) {
+		return detail
+	}
+
+	detail := jsonDetail{modified: modified}
+	if data, err := os.ReadFile(path); err == nil {
+		var parsed struct {
+			Uploader string  `json:"uploader"`
+			Channel  string  `json:"channel"`
+			Duration float64 `json:"duration"`
+		}
+		if json.Unmarshal(data, &parsed) == nil {
+			detail.author, detail.duration = parsed.Uploader, parsed.Duration
+			if detail.author == "" {
+				detail.author = parsed.Channel
+			}
+		}
+	}
+	jsonDetails[path] = detail
+	return detail
+}
+
+// findArtifacts lists the files in ./data that belong to a video, including blend
+// renders that used it
+func findArtifacts(db *util.Database, id string) []Artifact {
+	artifacts := []Artifact{}
+	files, err := os.ReadDir("./data")
+	if err != nil {
+		return artifacts
+	}
+
+	stems := make(map[string]string)
+	for _, stem := range audio.RegisteredStems(db, id) {
+		stems[filepath.Base(stem.Path)] = stem.Stem
+	}
+	names := artifactNames(db, id)
+
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() {
+			continue
+		}
+
+		kind := ""
+		switch {
+		case stems[name] != "":
+			kind = "stem"
+		case strings.HasPrefix(name, "blend_") && strings.Contains(name, "_"+id+"_"):
+			kind = "render"
+		case strings.HasPrefix(name, id+"."):
+			kind = storage.ArtifactKind(strings.TrimPrefix(name, id))
+		}
+		if kind == "" {
+			continue
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		streamName := name
+		if alias, ok := names[name]; ok {
+			streamName = alias
+		}
+		artifacts = append(artifacts, Artifact{
+			Name:     name,
+			Kind:     kind,
+			Stem:     stems[name],
+			Size:     info.Size(),
+			Modified: info.ModTime().Unix(),
+			URL:      mediaURL(id, streamName),
+		})
+	}
+	return artifacts
+}
@@ -0,0 +1,155 @@
+package web
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"starchive/audio"
+	"starchive/util"
+)
+
+// mediaAliases name a video's own files by what they are
+var mediaAliases = map[string]string{
+	"mp4":       ".mp4",
+	"video":     ".mp4",
+	"wav":       ".wav",
+	"audio":     ".wav",
+	"thumbnail": ".jpg",
+	"jpg":       ".jpg",
+}
+
+// mediaTypes are the content types of archived files; anything else goes by extension
+var mediaTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".wav":  "audio/wav",
+	".jpg":  "image/jpeg",
+	".vtt":  "text/vtt; charset=utf-8",
+	".txt":  "text/plain; charset=utf-8",
+	".json": "application/json",
+}
+
+// mediaURL is where a video's artifact is streamed from
+func mediaURL(id, name string) string {
+	return "/media/" + id + "/" + name
+}
+
+// artifactNames maps a video's files to the short artifact names they stream under:
+// mp4, wav, thumbnail, and the stem name for each stem's preferred file
+func artifactNames(db *util.Database, id string) map[string]string {
+	names := map[string]string{
+		id + ".mp4": "mp4",
+		id + ".wav": "wav",
+		id + ".jpg": "thumbnail",
+	}
+	for _, stem := range audio.ListStemNames(db, id) {
+		names[filepath.Base(audio.FindStemFile(db, id, stem))] = stem
+	}
+	return names
+}
+
+// resolveArtifact finds the file an artifact name refers to: an alias, a stem name,
+// or the name of any file findArtifacts lists for the video
+func resolveArtifact(db *util.Database, id, name string) (string, bool) {
+	if ext, ok := mediaAliases[name]; ok {
+		path := filepath.Join("./data", id+ext)
+		_, err := os.Stat(path)
+		return path, err == nil
+	}
+
+	stem := audio.NormalizeStemName(name)
+	for _, available := range audio.ListStemNames(db, id) {
+		if available == stem {
+			return audio.FindStemFile(db, id, stem), true
+		}
+	}
+
+	for _, artifact := range findArtifacts(db, id) {
+		if artifact.Name == name {
+			return filepath.Join("./data", name), true
+		}
+	}
+	return "", false
+}
+
+// handleMedia streams an artifact of a video (GET /media/<id>/<artifact>) with Range
+// and ETag support. ?format=opus or ?format=mp3 transcodes WAV audio, cached on disk;
+// ?download=1 asks the browser to save it.
+func handleMedia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, name, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/media/"), "/")
+	if !ok || !libraryIDPattern.MatchString(id) {
+		http.NotFound(w, r)
+		return
+	}
+	db, err := util.InitDatabase()
+	if err != nil {
+		fmt.Printf("[Starchive] Error opening database: %v\n", err)
+		http.Error(w, "Database not available", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	path, ok := resolveArtifact(db, id, name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	contentType := mediaTypes[filepath.Ext(path)]
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(path))
+	}
+
+	if formatName := r.URL.Query().Get("format"); formatName != "" {
+		format, ok := audio.TranscodeFormats[formatName]
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown format %s (use opus or mp3)", formatName), http.StatusBadRequest)
+			return
+		}
+		if filepath.Ext(path) != ".wav" {
+			http.Error(w, "Only WAV audio can be transcoded", http.StatusBadRequest)
+			return
+		}
+
+		transcoded, err := audio.Transcode(path, format)
+		if err != nil {
+			fmt.Printf("[Starchive] Error transcoding %s: %v\n", path, err)
+			http.Error(w, "Error transcoding audio", http.StatusInternalServerError)
+			return
+		}
+		path, contentType = transcoded, format.ContentType
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "Error reading file", http.StatusInternalServerError)
+		return
+	}
+
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	// The file's identity is its size and modification time; a re-render changes both
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if r.URL.Query().Get("download") != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(path)))
+	}
+
+	http.ServeContent(w, r, filepath.Base(path), info.ModTime(), file)
+}
@@ -0,0 +1,112 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"starchive/audio"
+	"starchive/util"
+)
+
+// handlePeaks serves waveform data for a video's audio, generated on first request
+// and cached in audio.PeaksCacheDir:
+//
+//	GET /api/peaks/<id>/<artifact>[?width=N]  peak file, or the one level for N pixels
+//	GET /api/peaks/<id>/<artifact>/spectrogram  spectrogram PNG
+//
+// The artifact is named as for /media: wav, a stem name, or a WAV file name.
+func handlePeaks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/peaks"), "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || !libraryIDPattern.MatchString(parts[0]) {
+		http.NotFound(w, r)
+		return
+	}
+	db, err := util.InitDatabase()
+	if err != nil {
+		fmt.Printf("[Starchive] Error opening database: %v\n", err)
+		http.Error(w, "Database not available", http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	path, ok := resolveArtifact(db, parts[0], parts[1])
+	if !ok || filepath.Ext(path) != ".wav" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 2:
+		writePeakLevel(w, r, path)
+	case parts[2] == "spectrogram":
+		writeSpectrogram(w, r, path)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// writePeakLevel answers with the peaks of an audio file: the whole peak file, or
+// with ?width=N the level that best fills N pixels
+func writePeakLevel(w http.ResponseWriter, r *http.Request, path string) {
+	peaks, err := audio.EnsurePeaks(path, false)
+	if err != nil {
+		fmt.Printf("[Starchive] Error reading peaks of %s: %v\n", path, err)
+		http.Error(w, "Error reading waveform", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	width, err := strconv.Atoi(r.URL.Query().Get("width"))
+	if err != nil || width <= 0 {
+		json.NewEncoder(w).Encode(peaks)
+		return
+	}
+
+	level := peaks.Level(width)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"source":            peaks.Source,
+		"sample_rate":       peaks.SampleRate,
+		"duration":          peaks.Duration,
+		"samples_per_pixel": level.SamplesPerPixel,
+		"length":            level.Length,
+		"data":              level.Data,
+	})
+}
+
+// writeSpectrogram serves the spectrogram PNG of an audio file
+func writeSpectrogram(w http.ResponseWriter, r *http.Request, path string) {
+	pngPath, err := audio.EnsureSpectrogram(path, false)
+	if err != nil {
+		fmt.Printf("[Starchive] Error rendering spectrogram of %s: %v\n", path, err)
+		http.Error(w, "Error rendering spectrogram", http.StatusInternalServerError)
+		return
+	}
+
+	file, err := os.Open(pngPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "Error reading file", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	w.Header().Set("Cache-Control", "private, no-cache")
+	http.ServeContent(w, r, filepath.Base(pngPath), info.ModTime(), file)
+}
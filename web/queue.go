@@ -3,21 +3,26 @@ package web
 import (
 	"fmt"
 	"sync"
-	"starchive/media"
+	"starchive/pipeline"
 )
 
 // DownloadQueue manages a queue of video downloads
 type DownloadQueue struct {
 	queue     []string
 	isRunning bool
+	stages    []string
 	mutex     sync.Mutex
 }
 
-// NewDownloadQueue creates a new download queue
-func NewDownloadQueue() *DownloadQueue {
+// NewDownloadQueue creates a new download queue that runs the given ingest stages for each video
+func NewDownloadQueue(stages []string) *DownloadQueue {
+	if len(stages) == 0 {
+		stages = []string{"download"}
+	}
 	return &DownloadQueue{
 		queue:     make([]string, 0),
 		isRunning: false,
+		stages:    stages,
 	}
 }
 
@@ -60,19 +65,11 @@ func (dq *DownloadQueue) processQueue() {
 		fmt.Printf("Processing video %s. Remaining in queue: %d\n", videoId, len(dq.queue))
 		dq.mutex.Unlock()
 
-		// Auto-detect platform based on ID format
-		id, platform := media.ParseVideoInput(videoId)
-		if id == "" {
-			fmt.Printf("Could not determine platform for video %s, assuming YouTube\n", videoId)
-			platform = "youtube"
-			id = videoId
-		}
-		
-		_, err := media.DownloadVideo(id, platform)
-		if err != nil {
-			fmt.Printf("Error downloading %s video %s: %v\n", platform, id, err)
+		// The pipeline auto-detects the platform from the ID format
+		if err := pipeline.Run(videoId, dq.stages, pipeline.Options{}); err != nil {
+			fmt.Printf("Error ingesting video %s: %v\n", videoId, err)
 		} else {
-			fmt.Printf("Successfully downloaded %s video %s\n", platform, id)
+			fmt.Printf("Successfully ingested video %s\n", videoId)
 		}
 	}
 }
@@ -82,4 +79,4 @@ func (dq *DownloadQueue) GetQueueStatus() (int, bool) {
 	dq.mutex.Lock()
 	defer dq.mutex.Unlock()
 	return len(dq.queue), dq.isRunning
-}
\ No newline at end of file
+}
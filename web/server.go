@@ -3,54 +3,48 @@ package web
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"strings"
-	"sync"
 	"time"
 
+	"starchive/media"
 	"starchive/podpapyrus"
 	"starchive/util"
 )
 
-var (
-	storedPOToken string
-	poTokenMutex  sync.RWMutex
-	poTokenTime   time.Time
-)
-
-
-// WriteCookiesFile creates a Netscape format cookies file for a specific platform
-func WriteCookiesFile(cookiesData interface{}, platform string) error {
-	filename := fmt.Sprintf("./cookies_%s.txt", platform)
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create cookies file: %v", err)
-	}
-	defer file.Close()
-
-	// Write Netscape HTTP Cookie File header
-	file.WriteString("# Netscape HTTP Cookie File\n")
-	file.WriteString("# This file is generated by yt-dlp.  Do not edit.\n\n")
+// StoreCookies saves cookies from the extension in the vault for a platform
+func StoreCookies(cookiesData interface{}, platform string) error {
+	var cookies []media.Cookie
 
 	// Handle different input formats
 	switch data := cookiesData.(type) {
 	case string:
 		// Legacy string format - simple cookie string
-		return writeCookiesFromString(file, data, platform)
+		cookies = cookiesFromString(data, platform)
 	case []interface{}:
 		// Array format from Firefox extension
-		return writeCookiesFromArray(file, data)
+		cookies = cookiesFromArray(data)
 	default:
 		return fmt.Errorf("unsupported cookies data format")
 	}
+
+	if len(cookies) == 0 {
+		return fmt.Errorf("no valid cookies")
+	}
+	if err := media.StoreCookies(platform, cookies); err != nil {
+		return err
+	}
+
+	fmt.Printf("Stored %d %s cookies in the vault\n", len(cookies), platform)
+	return nil
 }
 
-// writeCookiesFromArray processes cookie objects from Firefox extension
-func writeCookiesFromArray(file *os.File, cookiesArray []interface{}) error {
+// cookiesFromArray processes cookie objects from Firefox extension
+func cookiesFromArray(cookiesArray []interface{}) []media.Cookie {
 	fmt.Printf("Processing %d cookies from extension\n", len(cookiesArray))
 
+	var cookies []media.Cookie
 	for i, cookieData := range cookiesArray {
 		cookie, ok := cookieData.(map[string]interface{})
 		if !ok {
@@ -61,89 +55,68 @@ func writeCookiesFromArray(file *os.File, cookiesArray []interface{}) error {
 		name, nameOk := cookie["name"].(string)
 		value, valueOk := cookie["value"].(string)
 		domain, domainOk := cookie["domain"].(string)
-		path, pathOk := cookie["path"].(string)
+		path, _ := cookie["path"].(string)
 
 		if !nameOk || !valueOk || !domainOk || name == "" || value == "" || domain == "" {
 			fmt.Printf("Skipping incomplete cookie: name=%v, value=%v, domain=%v\n", nameOk, valueOk, domainOk)
 			continue
 		}
 
-		if !pathOk || path == "" {
-			path = "/"
-		}
-
-		// Extract other attributes with defaults
-		secure := false
-		if s, ok := cookie["secure"].(bool); ok {
-			secure = s
-		}
-
-		expires := int64(0)
+		parsed := media.Cookie{Name: name, Value: value, Domain: domain, Path: path}
+		parsed.Secure, _ = cookie["secure"].(bool)
+		parsed.HTTPOnly, _ = cookie["httpOnly"].(bool)
 		if exp, ok := cookie["expires"].(float64); ok && exp > 0 {
-			expires = int64(exp)
-		}
-
-		// Determine if domain should be treated as subdomain
-		domainSpecified := "TRUE"
-		if !strings.HasPrefix(domain, ".") {
-			domainSpecified = "FALSE"
-		}
-
-		// Write in Netscape format: domain, domain_specified, path, secure, expiration, name, value
-		line := fmt.Sprintf("%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
-			domain,
-			domainSpecified,
-			path,
-			strings.ToUpper(fmt.Sprintf("%t", secure)),
-			expires,
-			name,
-			value)
-
-		if _, err := file.WriteString(line); err != nil {
-			return fmt.Errorf("failed to write cookie %s: %v", name, err)
+			parsed.Expires = int64(exp)
 		}
+		cookies = append(cookies, parsed)
 	}
 
-	fmt.Printf("Successfully wrote cookies to file\n")
-	return nil
+	return cookies
 }
 
-// writeCookiesFromString handles legacy string format
-func writeCookiesFromString(file *os.File, cookiesStr, platform string) error {
-	// Parse and write cookies in legacy format
-	cookies := strings.Split(cookiesStr, "; ")
+// cookiesFromString handles legacy string format
+func cookiesFromString(cookiesStr, platform string) []media.Cookie {
 	domain := fmt.Sprintf(".%s.com", platform)
 
-	for _, cookie := range cookies {
+	var cookies []media.Cookie
+	for _, cookie := range strings.Split(cookiesStr, "; ") {
 		parts := strings.SplitN(cookie, "=", 2)
-		if len(parts) == 2 {
-			// Format: domain, domain_specified, path, secure, expiration, name, value
-			line := fmt.Sprintf("%s\tTRUE\t/\tFALSE\t0\t%s\t%s\n", domain, parts[0], parts[1])
-			file.WriteString(line)
+		if len(parts) == 2 && parts[0] != "" {
+			cookies = append(cookies, media.Cookie{Name: parts[0], Value: parts[1], Domain: domain, Path: "/"})
 		}
 	}
 
-	return nil
+	return cookies
 }
 
-// SetupRoutes configures HTTP routes for the web server
-func SetupRoutes(downloadQueue interface{}) {
-	http.HandleFunc("/api/download", func(w http.ResponseWriter, r *http.Request) {
+// SetupRoutes configures HTTP routes for the web server. Every API route goes
+// through the policy; only the library UI's static files are open.
+func SetupRoutes(downloadQueue interface{}, policy Policy) {
+	policy.hosts = policy.listenHosts()
+
+	http.HandleFunc("/api/download", policy.Protect(func(w http.ResponseWriter, r *http.Request) {
 		handleDownload(w, r, downloadQueue)
-	})
-	http.HandleFunc("/api/cookies", handleSetCookies)
-	http.HandleFunc("/youtube", func(w http.ResponseWriter, r *http.Request) {
+	}))
+	http.HandleFunc("/api/cookies", policy.Protect(handleSetCookies))
+	http.HandleFunc("/youtube", policy.Protect(func(w http.ResponseWriter, r *http.Request) {
 		handleYouTube(w, r, downloadQueue)
-	})
-	http.HandleFunc("/instagram", func(w http.ResponseWriter, r *http.Request) {
+	}))
+	http.HandleFunc("/instagram", policy.Protect(func(w http.ResponseWriter, r *http.Request) {
 		handleInstagram(w, r, downloadQueue)
-	})
-	http.HandleFunc("/get-txt", func(w http.ResponseWriter, r *http.Request) {
+	}))
+	http.HandleFunc("/get-txt", policy.Protect(func(w http.ResponseWriter, r *http.Request) {
 		handleGetTxt(w, r, downloadQueue)
-	})
-	http.HandleFunc("/po-token", handlePOToken)
-	http.HandleFunc("/data", handleData)
-	http.HandleFunc("/", handleStatic)
+	}))
+	http.HandleFunc("/po-token", policy.Protect(handlePOToken))
+	http.HandleFunc("/data", policy.Protect(handleData))
+	http.HandleFunc("/api/session", policy.checkOrigin(policy.handleSession))
+	http.HandleFunc("/api/library", policy.Protect(handleLibrary))
+	http.HandleFunc("/api/library/", policy.Protect(handleLibrary))
+	http.HandleFunc("/media/", policy.Protect(handleMedia))
+	http.HandleFunc("/api/blend", policy.Protect(handleBlend))
+	http.HandleFunc("/api/blend/", policy.Protect(handleBlend))
+	http.HandleFunc("/api/peaks/", policy.Protect(handlePeaks))
+	http.Handle("/", uiHandler())
 }
 
 func handleDownload(w http.ResponseWriter, r *http.Request, downloadQueue interface{}) {
@@ -182,9 +155,8 @@ func handleSetCookies(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+	body, ok := readBody(w, r)
+	if !ok {
 		return
 	}
 
@@ -202,13 +174,11 @@ func handleSetCookies(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := WriteCookiesFile(req.Cookies, "youtube"); err != nil {
-		http.Error(w, fmt.Sprintf("Error writing cookies file: %v", err), http.StatusInternalServerError)
+	if err := StoreCookies(req.Cookies, "youtube"); err != nil {
+		http.Error(w, fmt.Sprintf("Error storing cookies: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	fmt.Println("Cookies file updated successfully")
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"status":  "success",
@@ -222,39 +192,36 @@ func handleYouTube(w http.ResponseWriter, r *http.Request, downloadQueue interfa
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Error reading request body", http.StatusBadRequest)
+	body, ok := readBody(w, r)
+	if !ok {
 		return
 	}
 	defer r.Body.Close()
 
 	var jsonData map[string]interface{}
 	if err := json.Unmarshal(body, &jsonData); err != nil {
-		fmt.Printf("Invalid JSON received: %s\n", string(body))
+		fmt.Printf("Invalid JSON received (%d bytes)\n", len(body))
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	fmt.Printf("JSON received: %+v\n", jsonData)
-
 	id, ok := jsonData["videoId"].(string)
 	if !ok {
 		http.Error(w, "Missing or invalid 'videoId' field", http.StatusBadRequest)
 		return
 	}
+	fmt.Printf("YouTube video received: %s\n", id)
 
 	// Handle cookies if provided - support both string and array formats
 	if cookies, ok := jsonData["cookies"].(string); ok && cookies != "" {
 		fmt.Printf("Processing string format cookies (%d chars)\n", len(cookies))
-		if err := WriteCookiesFile(cookies, "youtube"); err != nil {
-			fmt.Printf("Warning: failed to write YouTube cookies: %v\n", err)
+		if err := StoreCookies(cookies, "youtube"); err != nil {
+			fmt.Printf("Warning: failed to store YouTube cookies: %v\n", err)
 		}
 	} else if cookiesArray, ok := jsonData["cookies"].([]interface{}); ok && len(cookiesArray) > 0 {
 		fmt.Printf("Processing array format cookies (%d items)\n", len(cookiesArray))
-		// Pass the cookie array directly to WriteCookiesFile
-		if err := WriteCookiesFile(cookiesArray, "youtube"); err != nil {
-			fmt.Printf("Warning: failed to write YouTube cookies: %v\n", err)
+		if err := StoreCookies(cookiesArray, "youtube"); err != nil {
+			fmt.Printf("Warning: failed to store YouTube cookies: %v\n", err)
 		}
 	} else {
 		fmt.Printf("No cookies provided or invalid cookie format\n")
@@ -281,52 +248,34 @@ func handleInstagram(w http.ResponseWriter, r *http.Request, downloadQueue inter
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Error reading request body", http.StatusBadRequest)
+	body, ok := readBody(w, r)
+	if !ok {
 		return
 	}
 	defer r.Body.Close()
 
 	var jsonData map[string]interface{}
 	if err := json.Unmarshal(body, &jsonData); err != nil {
-		fmt.Printf("Invalid JSON received: %s\n", string(body))
+		fmt.Printf("Invalid JSON received (%d bytes)\n", len(body))
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	fmt.Printf("Instagram JSON received: %+v\n", jsonData)
-
 	id, ok := jsonData["postId"].(string)
 	if !ok {
 		http.Error(w, "Missing or invalid 'postId' field", http.StatusBadRequest)
 		return
 	}
+	fmt.Printf("Instagram post received: %s\n", id)
 
 	// Handle cookies if provided - support both string and array formats
 	if cookies, ok := jsonData["cookies"].(string); ok && cookies != "" {
-		if err := WriteCookiesFile(cookies, "instagram"); err != nil {
-			fmt.Printf("Warning: failed to write Instagram cookies: %v\n", err)
+		if err := StoreCookies(cookies, "instagram"); err != nil {
+			fmt.Printf("Warning: failed to store Instagram cookies: %v\n", err)
 		}
 	} else if cookiesArray, ok := jsonData["cookies"].([]interface{}); ok && len(cookiesArray) > 0 {
-		// Convert array format to string format
-		cookiesStr := ""
-		for _, cookie := range cookiesArray {
-			if c, ok := cookie.(map[string]interface{}); ok {
-				name, nameOk := c["name"].(string)
-				value, valueOk := c["value"].(string)
-				if nameOk && valueOk && name != "" && value != "" {
-					if cookiesStr != "" {
-						cookiesStr += "; "
-					}
-					cookiesStr += name + "=" + value
-				}
-			}
-		}
-		if cookiesStr != "" {
-			if err := WriteCookiesFile(cookiesStr, "instagram"); err != nil {
-				fmt.Printf("Warning: failed to write Instagram cookies: %v\n", err)
-			}
+		if err := StoreCookies(cookiesArray, "instagram"); err != nil {
+			fmt.Printf("Warning: failed to store Instagram cookies: %v\n", err)
 		}
 	}
 
@@ -414,33 +363,6 @@ func handleData(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func handleStatic(w http.ResponseWriter, r *http.Request) {
-	// Simple static file serving - in production, use proper static file server
-	if r.URL.Path == "/" {
-		w.Header().Set("Content-Type", "text/html")
-		w.Write([]byte(`
-<!DOCTYPE html>
-<html>
-<head>
-    <title>Starchive</title>
-</head>
-<body>
-    <h1>Starchive Download Server</h1>
-    <p>Use the API endpoints to download videos and set cookies.</p>
-    <h2>API Endpoints:</h2>
-    <ul>
-        <li><strong>POST /api/download</strong> - Queue a download</li>
-        <li><strong>POST /api/cookies</strong> - Set cookies</li>
-    </ul>
-</body>
-</html>
-		`))
-		return
-	}
-
-	http.NotFound(w, r)
-}
-
 func handleGetTxt(w http.ResponseWriter, r *http.Request, downloadQueue interface{}) {
 	fmt.Printf("[Starchive] GET /get-txt request received\n")
 
@@ -532,15 +454,12 @@ func handleGetTxt(w http.ResponseWriter, r *http.Request, downloadQueue interfac
 func handlePOToken(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
 		// Handle GET request to retrieve current PO token
-		poTokenMutex.RLock()
-		token := storedPOToken
-		tokenTime := poTokenTime
-		poTokenMutex.RUnlock()
+		token, tokenTime := media.StoredPOToken()
 
 		w.Header().Set("Content-Type", "application/json")
 
-		// Return empty if token is older than 1 hour
-		if time.Since(tokenTime) > time.Hour {
+		// Empty if there is none younger than media.POTokenMaxAge
+		if token == "" {
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"poToken": "",
 				"message": "No valid PO token available",
@@ -560,9 +479,8 @@ func handlePOToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Error reading request body", http.StatusBadRequest)
+	body, ok := readBody(w, r)
+	if !ok {
 		return
 	}
 	defer r.Body.Close()
@@ -583,14 +501,14 @@ func handlePOToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Store the PO token
-	poTokenMutex.Lock()
-	storedPOToken = req.POToken
-	poTokenTime = time.Now()
-	poTokenMutex.Unlock()
+	if err := media.StorePOToken(req.POToken); err != nil {
+		fmt.Printf("Error storing PO token: %v\n", err)
+		http.Error(w, "Error storing PO token", http.StatusInternalServerError)
+		return
+	}
 
-	fmt.Printf("PO token received from %s: %s... (timestamp: %d)\n",
-		req.Source, req.POToken[:20], req.Timestamp)
+	fmt.Printf("PO token received from %s: %s (timestamp: %d)\n",
+		req.Source, media.Redact(req.POToken), req.Timestamp)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -602,14 +520,6 @@ func handlePOToken(w http.ResponseWriter, r *http.Request) {
 
 // GetStoredPOToken returns the most recently stored PO token if it's fresh (less than 1 hour old)
 func GetStoredPOToken() string {
-	poTokenMutex.RLock()
-	defer poTokenMutex.RUnlock()
-
-	// Return empty if token is older than 1 hour
-	if time.Since(poTokenTime) > time.Hour {
-		return ""
-	}
-
-	return storedPOToken
+	token, _ := media.StoredPOToken()
+	return token
 }
-
@@ -0,0 +1,290 @@
+'use strict';
+
+// Library browser for the Starchive archive: the list view mirrors `starchive ls`,
+// the detail view adds the transcript and every downloadable artifact.
+
+let videos = [];
+
+const $ = (id) => document.getElementById(id);
+
+// el builds a DOM element; strings become text nodes so titles are never parsed as HTML
+function el(tag, attrs = {}, ...children) {
+  const node = document.createElement(tag);
+  for (const [name, value] of Object.entries(attrs)) {
+    if (name === 'onclick') {
+      node.addEventListener('click', value);
+    } else if (value !== undefined && value !== null && value !== false) {
+      node.setAttribute(name, value);
+    }
+  }
+  for (const child of children.flat()) {
+    if (child !== undefined && child !== null) {
+      node.append(child instanceof Node ? child : String(child));
+    }
+  }
+  return node;
+}
+
+// api calls the server with the session cookie; a 401 asks for the token again
+async function api(path, options = {}) {
+  const res = await fetch(path, Object.assign({ credentials: 'same-origin' }, options));
+  if (res.status === 401) {
+    show('login');
+    throw new Error('unauthorized');
+  }
+  if (!res.ok) {
+    throw new Error(`${res.status} ${await res.text()}`);
+  }
+  return res.status === 204 ? null : res.json();
+}
+
+function show(section) {
+  for (const name of ['login', 'library', 'detail', 'blend']) {
+    $(name).hidden = name !== section;
+  }
+  $('logoutButton').hidden = section === 'login';
+}
+
+function formatDuration(seconds) {
+  if (!seconds) {
+    return '';
+  }
+  const s = Math.round(seconds);
+  const h = Math.floor(s / 3600);
+  const m = Math.floor((s % 3600) / 60);
+  const rest = String(s % 60).padStart(2, '0');
+  return h > 0 ? `${h}:${String(m).padStart(2, '0')}:${rest}` : `${m}:${rest}`;
+}
+
+function formatSize(bytes) {
+  const units = ['B', 'KB', 'MB', 'GB'];
+  let size = bytes;
+  let unit = 0;
+  while (size >= 1024 && unit < units.length - 1) {
+    size /= 1024;
+    unit++;
+  }
+  return `${size.toFixed(unit === 0 ? 0 : 1)} ${units[unit]}`;
+}
+
+function bpmKey(video) {
+  const parts = [];
+  if (video.bpm) {
+    parts.push(video.bpm.toFixed(1));
+  }
+  if (video.key) {
+    parts.push(video.key);
+  }
+  return parts.join(' / ');
+}
+
+function thumbnail(video) {
+  return video.thumbnail ? el('img', { src: video.thumbnail, alt: '', loading: 'lazy' }) : el('div');
+}
+
+// fillSelect replaces the generated options of a filter, keeping its first "any" option
+function fillSelect(select, values) {
+  const current = select.value;
+  while (select.options.length > 1) {
+    select.remove(1);
+  }
+  for (const value of values) {
+    select.append(el('option', { value }, value));
+  }
+  select.value = values.includes(current) ? current : '';
+}
+
+async function loadLibrary() {
+  const data = await api('/api/library');
+  videos = data.videos || [];
+
+  const stems = new Set();
+  const keys = new Set();
+  for (const video of videos) {
+    video.stems.forEach((stem) => stems.add(stem));
+    if (video.key) {
+      keys.add(video.key);
+    }
+  }
+  fillSelect($('stemFilter'), [...stems].sort());
+  fillSelect($('keyFilter'), [...keys].sort());
+
+  const separated = videos.filter((video) => video.vocalDone).length;
+  $('summary').textContent = `${videos.length} videos, ${separated} separated`;
+  renderLibrary();
+}
+
+const sorters = {
+  modified: (a, b) => b.modified - a.modified,
+  title: (a, b) => a.title.localeCompare(b.title),
+  author: (a, b) => a.author.localeCompare(b.author),
+  duration: (a, b) => b.duration - a.duration,
+  bpm: (a, b) => (a.bpm || Infinity) - (b.bpm || Infinity),
+  key: (a, b) => (a.key || '~').localeCompare(b.key || '~'),
+};
+
+function renderLibrary() {
+  const query = $('search').value.trim().toLowerCase();
+  const vocal = $('vocalFilter').value;
+  const stem = $('stemFilter').value;
+  const key = $('keyFilter').value;
+
+  const shown = videos.filter((video) => {
+    if (query && ![video.id, video.title, video.author].some((text) => text.toLowerCase().includes(query))) {
+      return false;
+    }
+    if ((vocal === 'done' && !video.vocalDone) || (vocal === 'pending' && video.vocalDone)) {
+      return false;
+    }
+    if (stem && !video.stems.includes(stem)) {
+      return false;
+    }
+    return !key || video.key === key;
+  });
+  shown.sort(sorters[$('sort').value]);
+
+  $('rows').replaceChildren(...shown.map((video) => el('tr', { onclick: () => { location.hash = `#/v/${video.id}`; } },
+    el('td', { class: 'thumb' }, thumbnail(video)),
+    el('td', {}, video.title, el('div', { class: 'muted' }, video.id)),
+    el('td', {}, video.author),
+    el('td', {}, formatDuration(video.duration)),
+    el('td', {}, bpmKey(video)),
+    el('td', { class: video.vocalDone ? 'yes' : 'muted' }, video.vocalDone ? 'Y' : 'N'),
+    el('td', {}, video.stems.map((name) => el('span', { class: 'chip' }, name))),
+  )));
+  $('empty').hidden = shown.length > 0;
+  show('library');
+}
+
+// player streams an artifact in the page; WAV audio is offered as Opus with an MP3
+// fallback for browsers (Safari) without Ogg support
+function player(artifact) {
+  if (artifact.kind === 'video') {
+    return el('video', { src: artifact.url, controls: '', preload: 'none' });
+  }
+  if (['audio', 'stem', 'render'].includes(artifact.kind)) {
+    return el('audio', { controls: '', preload: 'none' },
+      el('source', { src: `${artifact.url}?format=opus`, type: 'audio/ogg; codecs=opus' }),
+      el('source', { src: `${artifact.url}?format=mp3`, type: 'audio/mpeg' }),
+    );
+  }
+  return null;
+}
+
+function isWav(artifact) {
+  return artifact.name.endsWith('.wav');
+}
+
+async function showDetail(id) {
+  const data = await api(`/api/library/${encodeURIComponent(id)}`);
+  const video = data.video;
+
+  const stages = Object.entries(data.stages || {}).map(([stage, status]) => `${stage}: ${status}`).join(', ');
+  const facts = [
+    ['ID', video.id],
+    ['Author', video.author],
+    ['Duration', formatDuration(video.duration)],
+    ['BPM / Key', bpmKey(video)],
+    ['Vocals separated', video.vocalDone ? 'yes' : 'no'],
+    ['Stems', video.stems.join(', ')],
+    ['Pipeline', stages],
+  ].filter(([, value]) => value);
+
+  const artifacts = el('table', {},
+    el('thead', {}, el('tr', {}, el('th', {}, 'File'), el('th', {}, 'Kind'), el('th', {}, 'Size'), el('th', {}, ''), el('th', {}, ''))),
+    el('tbody', {}, data.artifacts.map((artifact) => el('tr', {},
+      el('td', {}, el('a', { href: artifact.url, target: '_blank' }, artifact.name)),
+      el('td', {}, artifact.stem ? `${artifact.kind} (${artifact.stem})` : artifact.kind),
+      el('td', {}, formatSize(artifact.size)),
+      el('td', { class: 'player' }, player(artifact)),
+      el('td', {},
+        el('a', { href: `${artifact.url}?download=1` }, 'Download'),
+        isWav(artifact) ? [' · ', el('a', { href: `${artifact.url}?format=mp3&download=1` }, 'MP3')] : null,
+      ),
+    ))),
+  );
+
+  const partner = el('input', { type: 'text', list: 'blendPartners', placeholder: 'Video ID to blend with', required: '' });
+  const blendForm = el('form', { class: 'toolbar' },
+    partner,
+    el('datalist', { id: 'blendPartners' }, videos.filter((other) => other.id !== video.id)
+      .map((other) => el('option', { value: other.id }, other.title))),
+    el('button', { type: 'submit' }, 'Open blend editor'),
+  );
+  blendForm.addEventListener('submit', (event) => {
+    event.preventDefault();
+    location.hash = `#/blend/${video.id}/${partner.value.trim()}`;
+  });
+
+  $('detail').replaceChildren(
+    el('p', {}, el('a', { href: '#/' }, '← Library')),
+    el('div', { class: 'detail-header' },
+      video.thumbnail ? el('img', { src: video.thumbnail, alt: '' }) : null,
+      el('div', {},
+        el('h2', {}, video.title),
+        el('dl', {}, facts.map(([name, value]) => [el('dt', {}, name), el('dd', {}, value)])),
+      ),
+    ),
+    el('h3', {}, 'Blend'),
+    blendForm,
+    el('h3', {}, 'Artifacts'),
+    artifacts,
+    el('h3', {}, 'Transcript'),
+    data.transcript ? el('div', { class: 'transcript' }, data.transcript) : el('p', { class: 'muted' }, 'No transcript yet.'),
+  );
+  document.title = `${video.title} - Starchive`;
+  show('detail');
+}
+
+async function route() {
+  const match = location.hash.match(/^#\/v\/([A-Za-z0-9_-]+)$/);
+  const blendMatch = location.hash.match(/^#\/blend\/([A-Za-z0-9_-]+)\/([A-Za-z0-9_-]+)$/);
+  try {
+    if (match) {
+      await showDetail(match[1]);
+    } else if (blendMatch) {
+      await openBlend(blendMatch[1], blendMatch[2]);
+    } else {
+      document.title = 'Starchive';
+      if (videos.length === 0) {
+        await loadLibrary();
+      } else {
+        renderLibrary();
+      }
+    }
+  } catch (err) {
+    if (err.message !== 'unauthorized') {
+      $('summary').textContent = `Error: ${err.message}`;
+    }
+  }
+}
+
+$('loginForm').addEventListener('submit', async (event) => {
+  event.preventDefault();
+  $('loginError').textContent = '';
+  try {
+    await api('/api/session', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ token: $('tokenInput').value }),
+    });
+    $('tokenInput').value = '';
+    await route();
+  } catch (err) {
+    $('loginError').textContent = 'That token was not accepted.';
+  }
+});
+
+$('logoutButton').addEventListener('click', async () => {
+  await fetch('/api/session', { method: 'DELETE', credentials: 'same-origin' });
+  videos = [];
+  $('summary').textContent = '';
+  show('login');
+});
+
+for (const id of ['search', 'vocalFilter', 'stemFilter', 'keyFilter', 'sort']) {
+  $(id).addEventListener('input', renderLibrary);
+}
+
+window.addEventListener('hashchange', route);
+route();
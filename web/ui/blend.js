@@ -0,0 +1,284 @@
+'use strict';
+
+// Blend editor: both tracks' waveforms with beats and gaps, and the segments placed
+// on each as blocks that can be dragged. Every change is sent as a blend shell
+// command, so the editor and `starchive blend` share the same logic.
+
+let blend = null; // { pair, session, state, peaks, render }
+
+const BLEND_PEAK_WIDTH = 1600;
+
+// loadPeaks fetches the waveform of the audio a track plays, again whenever a type
+// command swaps its stems
+function loadPeaks(number) {
+  const stems = trackOf(number).stems;
+  const source = stems.length > 0 ? stems[0].path : '';
+  if (blend.peakSources[number] === source) {
+    return;
+  }
+  blend.peakSources[number] = source;
+  delete blend.peaks[number];
+  api(`/api/blend/${blend.session}/peaks/${number}?width=${BLEND_PEAK_WIDTH}`)
+    .then((peaks) => {
+      blend.peaks[number] = peaks;
+      drawLane(number);
+    })
+    .catch(() => {});
+}
+
+async function blendCall(body) {
+  const data = await api(`/api/blend/${blend.session}`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(body),
+  });
+  blend.state = data.state;
+  blend.render = data.render;
+  if (data.output) {
+    blendLog(data.output);
+  }
+  renderBlend();
+}
+
+// blendRun sends a request, showing errors in the log instead of losing them
+async function blendRun(body, button) {
+  if (button) {
+    button.disabled = true;
+  }
+  try {
+    await blendCall(body);
+  } catch (err) {
+    if (err.message !== 'unauthorized') {
+      blendLog(`Error: ${err.message}\n`);
+    }
+  } finally {
+    if (button) {
+      button.disabled = false;
+    }
+  }
+}
+
+function blendLog(text) {
+  const log = $('blendLog');
+  if (log) {
+    log.textContent += text;
+    log.scrollTop = log.scrollHeight;
+  }
+}
+
+async function openBlend(id1, id2) {
+  const pair = `${id1}/${id2}`;
+  if (!blend || blend.pair !== pair) {
+    if (blend) {
+      fetch(`/api/blend/${blend.session}`, { method: 'DELETE', credentials: 'same-origin' });
+    }
+    const data = await api('/api/blend', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ id1, id2 }),
+    });
+    blend = { pair, session: data.session, state: data.state, peaks: {}, peakSources: {}, render: data.render };
+  }
+  document.title = `Blend ${id1} + ${id2} - Starchive`;
+  renderBlend();
+  show('blend');
+}
+
+function trackOf(number) {
+  return blend.state.tracks[number - 1];
+}
+
+function otherTrackOf(number) {
+  return number === 1 ? 2 : 1;
+}
+
+// nearestBeat snaps seconds to the closest detected beat of a track, if it has any
+function nearestBeat(track, seconds) {
+  let best = seconds;
+  let distance = Infinity;
+  for (const beat of track.beats) {
+    if (Math.abs(beat - seconds) < distance) {
+      best = beat;
+      distance = Math.abs(beat - seconds);
+    }
+  }
+  return best;
+}
+
+function renderBlend() {
+  const state = blend.state;
+  const lanes = [1, 2].map((number) => {
+    const track = trackOf(number);
+    const setting = (action, label, step, value) => {
+      const input = el('input', { type: 'number', step, value, title: label });
+      input.addEventListener('change', () => blendRun({ action, track: number, value: Number(input.value) }));
+      return el('label', {}, label, input);
+    };
+    const button = (label, body) => {
+      const node = el('button', {}, label);
+      node.addEventListener('click', () => blendRun(body, node));
+      return node;
+    };
+
+    const facts = [track.type, track.effective_bpm ? `${track.effective_bpm.toFixed(1)} BPM` : '', track.effective_key,
+      `${track.beats.length} beats`, `${track.segments.length} segments`].filter(Boolean).join(' · ');
+
+    return el('div', { class: 'lane' },
+      el('div', { class: 'lane-title' }, el('strong', {}, `Track ${number}: `), track.title, el('span', { class: 'muted' }, ` ${facts}`)),
+      el('div', { class: 'wave', id: `wave${number}` }, el('canvas', {}), el('div', { class: 'blocks' })),
+      el('div', { class: 'lane-controls' },
+        setting('pitch', 'Pitch', 1, track.pitch),
+        setting('tempo', 'Tempo %', 0.1, track.tempo),
+        setting('volume', 'Volume', 1, track.volume),
+        setting('window', 'Window s', 0.5, track.window),
+        button('Split', { action: 'split', track: number }),
+        button('Detect beats', { action: 'beat-detect', track: number }),
+        button('Find gaps', { action: 'gap-finder', track: number }),
+      ),
+    );
+  });
+
+  const render = el('button', {}, 'Render');
+  render.addEventListener('click', () => blendRun({ action: 'render' }, render));
+
+  const command = el('input', { type: 'text', placeholder: 'Blend shell command, e.g. match bpm1to2 or auto-arrange dense' });
+  const form = el('form', { class: 'toolbar' }, command, el('button', { type: 'submit' }, 'Run'));
+  form.addEventListener('submit', (event) => {
+    event.preventDefault();
+    if (command.value.trim()) {
+      blendLog(`blend> ${command.value}\n`);
+      blendRun({ command: command.value });
+      command.value = '';
+    }
+  });
+
+  const snap = el('input', { type: 'checkbox', id: 'blendSnap', checked: blend.snap !== false ? '' : null });
+  snap.addEventListener('change', () => { blend.snap = snap.checked; });
+
+  const log = $('blendLog') ? $('blendLog').textContent : '';
+  $('blend').replaceChildren(
+    el('p', {}, el('a', { href: '#/' }, '← Library')),
+    el('div', { class: 'toolbar' },
+      el('span', { class: 'muted' }, `Engine ${state.engine}, timebase ${state.timebase}`),
+      el('label', {}, snap, ' Snap to beats'),
+      render,
+      blend.render ? el('audio', { controls: '', preload: 'none' },
+        el('source', { src: `${blend.render}?format=opus`, type: 'audio/ogg; codecs=opus' }),
+        el('source', { src: `${blend.render}?format=mp3`, type: 'audio/mpeg' }),
+      ) : null,
+      blend.render ? el('a', { href: `${blend.render}?download=1` }, 'Download') : null,
+    ),
+    el('p', { class: 'muted' }, 'Blocks are the other track\'s segments placed on this one: drag to move, click to enable or disable.'),
+    lanes,
+    form,
+    el('pre', { id: 'blendLog', class: 'log' }, log),
+  );
+
+  for (const number of [1, 2]) {
+    loadPeaks(number);
+    drawLane(number);
+  }
+}
+
+// drawLane paints a track's waveform, gaps, loop and beat grid, and lays out the
+// blocks of the segments placed on it
+function drawLane(number) {
+  const wave = $(`wave${number}`);
+  if (!wave || !blend) {
+    return;
+  }
+  const track = trackOf(number);
+  const duration = track.duration || 1;
+  const canvas = wave.querySelector('canvas');
+  const width = wave.clientWidth;
+  const height = wave.clientHeight;
+  const scale = window.devicePixelRatio || 1;
+  canvas.width = width * scale;
+  canvas.height = height * scale;
+
+  const ctx = canvas.getContext('2d');
+  ctx.scale(scale, scale);
+  ctx.clearRect(0, 0, width, height);
+  const x = (seconds) => (seconds / duration) * width;
+
+  ctx.fillStyle = 'rgba(76, 175, 80, 0.15)';
+  for (const gap of track.gaps) {
+    ctx.fillRect(x(gap.start_time), 0, x(gap.duration), height);
+  }
+  if (blend.state.grid_track === number && blend.state.loop_end > blend.state.loop_start) {
+    ctx.fillStyle = 'rgba(255, 193, 7, 0.15)';
+    ctx.fillRect(x(blend.state.loop_start), 0, x(blend.state.loop_end - blend.state.loop_start), height);
+  }
+
+  // Peaks are min/max pairs; the level spans the audio's own length
+  const peaks = blend.peaks[number];
+  if (peaks && peaks.length > 0) {
+    ctx.fillStyle = '#00BCD4';
+    const mid = height / 2;
+    const span = x(peaks.duration) / peaks.length;
+    for (let i = 0; i < peaks.length; i++) {
+      const top = mid - (peaks.data[2 * i + 1] / 32768) * mid;
+      const bottom = mid - (peaks.data[2 * i] / 32768) * mid;
+      ctx.fillRect(i * span, top, Math.max(span, 1), Math.max(bottom - top, 1));
+    }
+  }
+
+  track.beats.forEach((beat, i) => {
+    const downbeat = i % blend.state.beats_per_bar === 0;
+    ctx.fillStyle = downbeat ? 'rgba(255, 255, 255, 0.45)' : 'rgba(255, 255, 255, 0.15)';
+    ctx.fillRect(Math.round(x(beat)), 0, 1, downbeat ? height : height / 4);
+  });
+
+  const source = otherTrackOf(number);
+  const blocks = wave.querySelector('.blocks');
+  blocks.replaceChildren(...trackOf(source).segments.map((segment, i) => segmentBlock(number, source, segment, i + 1, width)));
+}
+
+// segmentBlock is one placed segment; dragging it places it, a click toggles it
+function segmentBlock(target, source, segment, position, width) {
+  const duration = trackOf(target).duration || 1;
+  const block = el('div', {
+    class: segment.active ? 'block' : 'block inactive',
+    title: `${source}:${position} ${segment.duration.toFixed(1)}s at ${segment.placement.toFixed(2)}s${segment.energy_category ? `, ${segment.energy_category} energy` : ''}`,
+  }, `${source}:${position}`);
+  block.style.left = `${(segment.placement / duration) * 100}%`;
+  block.style.width = `${Math.max((segment.duration / duration) * 100, 0.3)}%`;
+
+  block.addEventListener('pointerdown', (event) => {
+    event.preventDefault();
+    block.setPointerCapture(event.pointerId);
+    const startX = event.clientX;
+    const startLeft = (segment.placement / duration) * width;
+    let moved = false;
+
+    const move = (e) => {
+      if (Math.abs(e.clientX - startX) > 3) {
+        moved = true;
+      }
+      block.style.left = `${startLeft + e.clientX - startX}px`;
+    };
+    const up = (e) => {
+      block.removeEventListener('pointermove', move);
+      block.removeEventListener('pointerup', up);
+      if (!moved) {
+        blendRun({ action: 'toggle', track: source, segment: position });
+        return;
+      }
+      let at = Math.max(((startLeft + e.clientX - startX) / width) * duration, 0);
+      if (blend.snap !== false) {
+        at = nearestBeat(trackOf(target), at);
+      }
+      blendRun({ action: 'place', track: source, segment: position, at: Number(at.toFixed(3)) });
+    };
+    block.addEventListener('pointermove', move);
+    block.addEventListener('pointerup', up);
+  });
+  return block;
+}
+
+window.addEventListener('resize', () => {
+  if (blend && !$('blend').hidden) {
+    drawLane(1);
+    drawLane(2);
+  }
+});